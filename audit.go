@@ -0,0 +1,68 @@
+package spake2plus
+
+import (
+	"time"
+)
+
+// AuditSink receives a record of every login attempt a Server sees, for an audit trail; see
+// WithAuditSink and the audit package. Unlike an Observer's events, AuditEvents name the identity
+// and the source, but they never tell whether the identity is registered: an attempt on an unknown
+// identity is recorded like a failed one on a registered identity.
+//
+// Audit is called synchronously, from whichever goroutine runs the handshake, so it must be safe
+// for concurrent use. The slices of an AuditEvent are only valid during the call.
+type AuditSink interface {
+	Audit(e AuditEvent)
+}
+
+// AuditKind is the step of a login attempt an AuditEvent records.
+type AuditKind int
+
+const (
+	// AuditHandshake is a client's share processed, or rejected.
+	AuditHandshake AuditKind = iota
+	// AuditConfirmation is a Verify of the client's confirmation.
+	AuditConfirmation
+	// AuditAbandoned is a SharedSecret destroyed before its confirmation was verified, e.g. by a
+	// ServerSession Reset or Destroyed midway.
+	AuditAbandoned
+)
+
+func (k AuditKind) String() string {
+	switch k {
+	case AuditHandshake:
+		return "handshake"
+	case AuditConfirmation:
+		return "confirmation"
+	case AuditAbandoned:
+		return "abandoned"
+	}
+	return "unknown"
+}
+
+// AuditEvent is a step of a login attempt.
+type AuditEvent struct {
+	Kind     AuditKind
+	Time     time.Time
+	Suite    string // the CipherSuite's ID
+	Identity []byte // as prepared with WithPRECIS, or as received when it couldn't be
+	Source   []byte // as passed to HandshakeFrom, nil otherwise
+	Failed   bool
+	Reason   Reason // why it failed, when it did
+}
+
+// auditTrail is what a SharedSecret needs to record the end of its login attempt.
+type auditTrail struct {
+	sink      AuditSink
+	event     AuditEvent // the handshake's
+	confirmed bool       // whether Verify was called
+}
+
+func (a *auditTrail) record(kind AuditKind, err error) {
+	e := a.event
+	e.Kind, e.Time = kind, time.Now()
+	if err != nil {
+		e.Failed, e.Reason = true, reasonOf(err)
+	}
+	a.sink.Audit(e)
+}
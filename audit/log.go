@@ -0,0 +1,333 @@
+// Package audit keeps a tamper-evident log of the login attempts a spake2plus Server sees.
+//
+// A Log is a spake2plus.AuditSink writing one record per line to an append-only file. Each line is
+// a JSON record, a tab and the base64 Ed25519 signature of the record, and each record carries the
+// SHA-256 of the one before it, starting from zeros. Editing, reordering or removing a record
+// breaks the signatures or the chain. Removing records from the end doesn't, so Verify also takes
+// a Head saved from an earlier run, and reports whether the log ends with the record Close writes.
+//
+// The signing key is derived from a long-term server key with HKDF, see NewKey; verifying only
+// takes its public half.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jtejido/spake2plus"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record kinds besides those of spake2plus.AuditKind.
+const (
+	KindOpen  = "open"  // the Log was opened
+	KindClose = "close" // the Log was closed
+)
+
+// Record is a line of the log.
+type Record struct {
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	Suite    string    `json:"suite,omitempty"`
+	Identity []byte    `json:"identity,omitempty"`
+	Source   []byte    `json:"source,omitempty"`
+	Result   string    `json:"result,omitempty"` // "ok", or why it failed
+	Prev     string    `json:"prev"`             // hex SHA-256 of the previous record
+}
+
+// Head identifies the last record of a log, as a checkpoint for Verify.
+type Head struct {
+	Seq  uint64
+	Hash [sha256.Size]byte
+}
+
+func (h Head) String() string {
+	return strconv.FormatUint(h.Seq, 10) + ":" + hex.EncodeToString(h.Hash[:])
+}
+
+// ParseHead parses the output of Head.String.
+func ParseHead(s string) (Head, error) {
+	var h Head
+	seq, hash, ok := strings.Cut(s, ":")
+	if !ok {
+		return h, errors.New("audit: head isn't seq:hash")
+	}
+	var err error
+	if h.Seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+		return h, fmt.Errorf("audit: head: %w", err)
+	}
+	b, err := hex.DecodeString(hash)
+	if err != nil || len(b) != sha256.Size {
+		return h, errors.New("audit: head hash isn't a hex SHA-256")
+	}
+	copy(h.Hash[:], b)
+	return h, nil
+}
+
+var keyInfo = []byte("spake2plus audit log signing key v1")
+
+// NewKey derives the log's Ed25519 signing key from a long-term server key, which should have at
+// least 32 bytes of entropy.
+func NewKey(serverKey []byte) ed25519.PrivateKey {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, serverKey, nil, keyInfo), seed); err != nil {
+		panic(err) // HKDF-SHA256 gives up to 8160 bytes
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// Log appends signed, chained records to a file. It is safe for concurrent use.
+type Log struct {
+	mu   sync.Mutex
+	f    *os.File
+	key  ed25519.PrivateKey
+	head Head
+	next uint64
+	err  error
+	now  func() time.Time
+}
+
+// Open opens the log at path for appending, creating it if needed, and appends an open record.
+// An existing log must end with a whole record signed by key; Open checks no further, which is
+// Verify's job.
+//
+// Records are written with one write each, but not synced; see Sync.
+func Open(path string, key ed25519.PrivateKey) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{f: f, key: key, now: time.Now}
+	if err := l.resume(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	l.append(&Record{Kind: KindOpen})
+	if l.err != nil {
+		f.Close()
+		return nil, l.err
+	}
+	return l, nil
+}
+
+// resume reads the last record of the file, if any, to carry on its chain.
+func (l *Log) resume() error {
+	st, err := l.f.Stat()
+	if err != nil || st.Size() == 0 {
+		return err
+	}
+	// records are short, so the last one is within the last few kilobytes
+	off := st.Size() - 64<<10
+	if off < 0 {
+		off = 0
+	}
+	buf := make([]byte, st.Size()-off)
+	if _, err := l.f.ReadAt(buf, off); err != nil {
+		return err
+	}
+	if buf[len(buf)-1] != '\n' {
+		return errors.New("audit: the log ends with a partial record")
+	}
+	line := buf[:len(buf)-1]
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	} else if off > 0 {
+		return errors.New("audit: the last record is too long")
+	}
+	r, hash, err := parseLine(line, l.key.Public().(ed25519.PublicKey))
+	if err != nil {
+		return fmt.Errorf("audit: last record: %w", err)
+	}
+	l.head = Head{r.Seq, hash}
+	l.next = r.Seq + 1
+	return nil
+}
+
+// Audit appends a record of e. A record that can't be written is lost, and Err returns why.
+func (l *Log) Audit(e spake2plus.AuditEvent) {
+	r := &Record{
+		Time:     e.Time,
+		Kind:     e.Kind.String(),
+		Suite:    e.Suite,
+		Identity: e.Identity,
+		Source:   e.Source,
+		Result:   "ok",
+	}
+	if e.Failed {
+		r.Result = e.Reason.String()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.append(r)
+}
+
+// append chains, signs and writes r. l.mu must be held, or l not shared yet.
+func (l *Log) append(r *Record) {
+	if l.err != nil {
+		return
+	}
+	r.Seq = l.next
+	r.Prev = hex.EncodeToString(l.head.Hash[:])
+	if r.Time.IsZero() {
+		r.Time = l.now()
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		l.err = err
+		return
+	}
+	line := append(append(body, '\t'), base64.StdEncoding.EncodeToString(ed25519.Sign(l.key, body))...)
+	line = append(line, '\n')
+	if _, err := l.f.Write(line); err != nil {
+		l.err = err
+		return
+	}
+	l.head = Head{r.Seq, sha256.Sum256(body)}
+	l.next++
+}
+
+// Head returns the last record written, to be kept somewhere safe and passed to Verify later.
+func (l *Log) Head() Head {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.head
+}
+
+// Err returns the error that stopped the log, if any.
+func (l *Log) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Sync commits the records written so far to stable storage.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return l.err
+	}
+	return l.f.Sync()
+}
+
+// Close appends a close record, syncs and closes the file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.append(&Record{Kind: KindClose})
+	err := l.err
+	if err == nil {
+		err = l.f.Sync()
+	}
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	if l.err == nil {
+		l.err = errors.New("audit: log closed")
+	}
+	return err
+}
+
+// parseLine checks the signature of a line, without its newline, and returns its record and hash.
+func parseLine(line []byte, pub ed25519.PublicKey) (*Record, [sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	body, sig, ok := bytes.Cut(line, []byte{'\t'})
+	if !ok {
+		return nil, hash, errors.New("no signature")
+	}
+	s, err := base64.StdEncoding.DecodeString(string(sig))
+	if err != nil || !ed25519.Verify(pub, body, s) {
+		return nil, hash, errors.New("bad signature")
+	}
+	r := new(Record)
+	if err := json.Unmarshal(body, r); err != nil {
+		return nil, hash, err
+	}
+	return r, sha256.Sum256(body), nil
+}
+
+// Result is what Verify found.
+type Result struct {
+	Head    Head // the last record
+	Records int
+	Closed  bool // whether the last record is a close record, as Close writes
+}
+
+// VerifyError locates the first problem Verify found.
+type VerifyError struct {
+	Line int // 1-based
+	Err  error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("audit: line %d: %v", e.Line, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Errors that Verify wraps in a VerifyError.
+var (
+	ErrBrokenChain = errors.New("record doesn't follow the previous one")
+	ErrTruncated   = errors.New("log truncated")
+	ErrCheckpoint  = errors.New("log doesn't contain the checkpoint")
+)
+
+// Verify reads a whole log and checks that every record is signed with the key of pub, and
+// chained to the one before it from the first. With a checkpoint, such as an earlier Head, the
+// log must also go through it, which catches records removed from the end since. Removals that
+// leave no such trace show as a Result that isn't Closed, which is otherwise a crash.
+func Verify(r io.Reader, pub ed25519.PublicKey, checkpoint *Head) (Result, error) {
+	var res Result
+	var prev [sha256.Size]byte
+	seen := checkpoint == nil
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err == io.EOF {
+			return res, &VerifyError{n, ErrTruncated}
+		}
+		if err != nil {
+			return res, err
+		}
+
+		rec, hash, err := parseLine(line[:len(line)-1], pub)
+		if err != nil {
+			return res, &VerifyError{n, err}
+		}
+		if rec.Seq != uint64(res.Records) || rec.Prev != hex.EncodeToString(prev[:]) {
+			return res, &VerifyError{n, ErrBrokenChain}
+		}
+		if !seen && rec.Seq == checkpoint.Seq {
+			if hash != checkpoint.Hash {
+				return res, &VerifyError{n, ErrCheckpoint}
+			}
+			seen = true
+		}
+		prev = hash
+		res.Head = Head{rec.Seq, hash}
+		res.Records++
+		res.Closed = rec.Kind == KindClose
+	}
+	if !seen {
+		return res, &VerifyError{res.Records + 1, ErrCheckpoint}
+	}
+	return res, nil
+}
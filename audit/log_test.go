@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"github.com/jtejido/spake2plus"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLog runs a successful login of alice and a failed one of mallory through a server auditing
+// to a new log, and returns the log's path and head.
+func writeLog(t *testing.T, key ed25519.PrivateKey) (string, Head) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	cs := spake2plus.Ed25519Sha256HkdfHmac(spake2plus.Scrypt(16, 1, 1))
+	db := spake2plus.NewMapLookup()
+	server, err := spake2plus.NewServer(cs, db, []byte("server"), spake2plus.WithAuditSink(l))
+	assert.NoError(t, err)
+	for _, identity := range []string{"alice", "mallory"} {
+		client, err := spake2plus.NewClient(cs, []byte(identity), []byte("server"), []byte("password"), []byte("NaCl"))
+		assert.NoError(t, err)
+		if identity == "alice" {
+			v, err := client.Verifier()
+			assert.NoError(t, err)
+			db.Add([]byte(identity), v)
+		}
+		A, err := client.EphemeralPublic()
+		assert.NoError(t, err)
+		m, sB, err := server.HandshakeFrom([]byte("10.0.0.1"), []byte(identity), A)
+		assert.NoError(t, err)
+		sA, err := client.CompleteHandshake(m)
+		assert.NoError(t, err)
+		sB.Verify(sA.Confirmation())
+	}
+
+	head := l.Head()
+	assert.NoError(t, l.Close())
+	return path, head
+}
+
+func TestLog(t *testing.T) {
+	key := NewKey([]byte("a long-term server key of 32 bytes"))
+	pub := key.Public().(ed25519.PublicKey)
+	path, head := writeLog(t, key)
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.SplitAfter(string(b), "\n")
+	lines = lines[:len(lines)-1]
+	var kinds, results []string
+	for _, line := range lines {
+		r, _, err := parseLine([]byte(strings.TrimSuffix(line, "\n")), pub)
+		assert.NoError(t, err)
+		kinds = append(kinds, r.Kind)
+		results = append(results, r.Result)
+	}
+	assert.Equal(t, []string{"open", "handshake", "confirmation", "handshake", "confirmation", "close"}, kinds)
+	assert.Equal(t, []string{"", "ok", "ok", "ok", "confirmation mismatch", ""}, results)
+	assert.Contains(t, lines[1], `"identity":"YWxpY2U="`) // base64 of alice
+
+	res, err := Verify(strings.NewReader(string(b)), pub, &head)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Head: res.Head, Records: 6, Closed: true}, res)
+	h, err := ParseHead(head.String())
+	assert.NoError(t, err)
+	assert.Equal(t, head, h)
+
+	// reopening carries on the chain
+	l, err := Open(path, key)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), l.Head().Seq)
+	assert.NoError(t, l.Close())
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	res, err = Verify(f, pub, &head)
+	f.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, 8, res.Records)
+	assert.True(t, res.Closed)
+
+	_, err = Open(path, NewKey([]byte("another key")))
+	assert.Error(t, err)
+}
+
+func TestVerifyTampering(t *testing.T) {
+	key := NewKey([]byte("a long-term server key of 32 bytes"))
+	pub := key.Public().(ed25519.PublicKey)
+	path, head := writeLog(t, key)
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.SplitAfter(string(b), "\n")
+	lines = lines[:len(lines)-1]
+
+	verify := func(log string, checkpoint *Head) (Result, error) {
+		return Verify(strings.NewReader(log), pub, checkpoint)
+	}
+	line := func(err error) int {
+		var e *VerifyError
+		if errors.As(err, &e) {
+			return e.Line
+		}
+		return 0
+	}
+
+	// an edited record
+	edited := strings.Replace(string(b), `"result":"confirmation mismatch"`, `"result":"ok"`, 1)
+	_, err = verify(edited, nil)
+	assert.Equal(t, 5, line(err))
+
+	// a record removed, or two swapped
+	_, err = verify(strings.Join(append(append([]string(nil), lines[:2]...), lines[3:]...), ""), nil)
+	assert.True(t, errors.Is(err, ErrBrokenChain))
+	assert.Equal(t, 3, line(err))
+	_, err = verify(lines[0]+lines[2]+lines[1]+strings.Join(lines[3:], ""), nil)
+	assert.True(t, errors.Is(err, ErrBrokenChain))
+
+	// records removed from the end: not closed, and short of the checkpoint
+	short := strings.Join(lines[:3], "")
+	res, err := verify(short, nil)
+	assert.NoError(t, err)
+	assert.False(t, res.Closed)
+	_, err = verify(short, &head)
+	assert.True(t, errors.Is(err, ErrCheckpoint))
+	assert.Equal(t, 4, line(err))
+	_, err = verify(string(b), &Head{Seq: head.Seq})
+	assert.True(t, errors.Is(err, ErrCheckpoint))
+
+	// a partial record
+	_, err = verify(string(b[:len(b)-10]), nil)
+	assert.True(t, errors.Is(err, ErrTruncated))
+	assert.Equal(t, 6, line(err))
+
+	// signed with another key
+	_, err = Verify(bytes.NewReader(b), NewKey([]byte("another key")).Public().(ed25519.PublicKey), nil)
+	assert.Equal(t, 1, line(err))
+}
@@ -0,0 +1,67 @@
+package spake2plus
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+func (r *auditRecorder) Audit(e AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Identity = append([]byte(nil), e.Identity...)
+	r.events = append(r.events, e)
+}
+
+func TestAuditSink(t *testing.T) {
+	cs := Ed25519Sha256HkdfHmac(mhfScrypt)
+	sink := new(auditRecorder)
+	server, err := NewServer(cs, NewMapLookup(), []byte("server"), WithAuditSink(sink))
+	assert.NoError(t, err)
+	client, err := NewClient(cs, []byte("mallory"), []byte("server"), []byte("guess"), []byte("NaCl"))
+	assert.NoError(t, err)
+	A, err := client.EphemeralPublic()
+	assert.NoError(t, err)
+
+	ss, err := server.NewSession()
+	assert.NoError(t, err)
+	identity := []byte("mallory")
+	_, err = ss.HandshakeFrom([]byte("10.0.0.1"), identity, A)
+	assert.NoError(t, err)
+	identity[0] = 'M' // the sink keeps its own copy
+	assert.NoError(t, ss.Reset())
+	_, err = ss.Handshake([]byte("mallory"), A[1:])
+	assert.Error(t, err)
+	ss.Destroy()
+
+	_, secret, err := server.Handshake([]byte("mallory"), A)
+	assert.NoError(t, err)
+	assert.Error(t, secret.Verify(make([]byte, 32)))
+	secret.Destroy()
+
+	type event struct {
+		kind     AuditKind
+		identity string
+		source   string
+		failed   bool
+		reason   Reason
+	}
+	var got []event
+	for _, e := range sink.events {
+		assert.Equal(t, cs.ID(), e.Suite)
+		assert.False(t, e.Time.IsZero())
+		got = append(got, event{e.Kind, string(e.Identity), string(e.Source), e.Failed, e.Reason})
+	}
+	assert.Equal(t, []event{
+		{AuditHandshake, "mallory", "10.0.0.1", false, ReasonUnknown},
+		{AuditAbandoned, "mallory", "10.0.0.1", false, ReasonUnknown},
+		{AuditHandshake, "mallory", "", true, BadLength},
+		{AuditHandshake, "mallory", "", false, ReasonUnknown},
+		{AuditConfirmation, "mallory", "", true, ConfirmationMismatch},
+	}, got)
+}
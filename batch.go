@@ -0,0 +1,91 @@
+package spake2plus
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+	"runtime"
+	"sync"
+)
+
+// HandshakeRequest is one client's identity and share A, as passed to Server.Handshake, and its
+// source if known, as passed to Server.HandshakeFrom.
+type HandshakeRequest struct {
+	Identity, A []byte
+	Source      []byte
+}
+
+// HandshakeResult is what Server.Handshake returns for one HandshakeRequest.
+type HandshakeResult struct {
+	Material *ServerMaterial
+	Secret   *SharedSecret
+	Err      error
+}
+
+// HandshakeBatch runs HandshakeFrom on every request and returns the results in the same order; a
+// request that fails only fails its own result. The requests are split between up to GOMAXPROCS
+// goroutines, so the Lookup must be safe for concurrent use. Each goroutine encodes its Y, Z and V
+// elements in one batch, sharing a single field inversion on Ed25519.
+//
+// Decoding the A shares isn't batched, and won't be: it needs no inversion, as Ed25519 and
+// ristretto255 fold it into the square root, and a square root is an exponentiation of each
+// point's own value, which Montgomery's trick can't share. Batching would only reorder the work.
+//
+// A server built WithRand reads from an io.Reader that may not be safe for concurrent use, so it
+// runs the whole batch on the calling goroutine, reading randomness in the order Handshake would.
+func (s *Server) HandshakeBatch(requests []HandshakeRequest) []HandshakeResult {
+	results := make([]HandshakeResult, len(requests))
+	if len(requests) == 0 {
+		return results
+	}
+
+	if s.rand != nil {
+		s.handshakeBatch(requests, results)
+		return results
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+	size := (len(requests) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(requests); lo += size {
+		hi := lo + size
+		if hi > len(requests) {
+			hi = len(requests)
+		}
+		wg.Add(1)
+		go func(requests []HandshakeRequest, results []HandshakeResult) {
+			defer wg.Done()
+			s.handshakeBatch(requests, results)
+		}(requests[lo:hi], results[lo:hi])
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (s *Server) handshakeBatch(requests []HandshakeRequest, results []HandshakeResult) {
+	started := make([]*serverHandshake, 0, len(requests))
+	index := make([]int, 0, len(requests))
+	for i, r := range requests {
+		h := &serverHandshake{ws: newWorkspace(s.suite.Group())}
+		if err := s.startHandshake(h, s.y, r.Source, r.Identity, r.A); err != nil {
+			results[i].Err = err
+			continue
+		}
+		started = append(started, h)
+		index = append(index, i)
+	}
+
+	elements := make([]suite.Element, 0, 3*len(started))
+	for _, h := range started {
+		elements = append(elements, h.Y, h.ws.Z, h.ws.V)
+	}
+	enc := suite.EncodeElements(s.suite.Group(), elements)
+
+	for j, h := range started {
+		secret := s.finishHandshake(h, enc[3*j], enc[3*j+1], enc[3*j+2])
+		results[index[j]] = HandshakeResult{Material: &ServerMaterial{B: enc[3*j]}, Secret: secret}
+	}
+}
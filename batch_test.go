@@ -0,0 +1,44 @@
+package spake2plus
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHandshakeBatchMatchesHandshake(t *testing.T) {
+	for _, s := range testSuites {
+		cs := s(mhfScrypt)
+		db := NewMapLookup()
+		server, err := NewServer(cs, db, []byte("server"), WithVerifierCache(16))
+		assert.NoError(t, err)
+
+		var requests []HandshakeRequest
+		for _, id := range []string{"alice", "bob", "carol"} {
+			client, err := NewClient(cs, []byte(id), []byte("server"), []byte("password "+id), []byte("NaCl"))
+			assert.NoError(t, err)
+			v, err := client.Verifier()
+			assert.NoError(t, err)
+			db.Add([]byte(id), v)
+			A, err := client.EphemeralPublic()
+			assert.NoError(t, err)
+			requests = append(requests, HandshakeRequest{Identity: []byte(id), A: A})
+		}
+		requests = append(requests,
+			HandshakeRequest{Identity: []byte("alice"), A: requests[0].A[1:]},
+			HandshakeRequest{Identity: []byte("mallory"), A: requests[1].A}, // simulated, kept by the cache
+			HandshakeRequest{Identity: []byte("bob"), A: cs.Group().Element().Identity().Bytes()},
+		)
+
+		var want []HandshakeResult
+		for _, r := range requests {
+			m, secret, err := server.Handshake(r.Identity, r.A)
+			want = append(want, HandshakeResult{m, secret, err})
+		}
+
+		got := server.HandshakeBatch(requests)
+		assert.Equal(t, want, got, cs.ID())
+		assert.Error(t, got[3].Err)
+		assert.Error(t, got[5].Err)
+		assert.Empty(t, server.HandshakeBatch(nil))
+	}
+}
@@ -0,0 +1,179 @@
+package spake2plus
+
+import (
+	"crypto"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"testing"
+)
+
+// The benchmarks below time one step of the protocol at a time, for every suite in testSuites.
+// Sub-benchmarks are named suite=<ID>, so that benchstat can put the suites side by side with
+// -col /suite; scripts/bench.sh runs them and compares commits.
+
+// benchPair is a registered client and a server, with A and the server's answer to it.
+type benchPair struct {
+	client *Client
+	server *Server
+	A      []byte
+	m      *ServerMaterial
+	secret *SharedSecret
+}
+
+func newBenchPair(b *testing.B, s Suite, opts ...Option) *benchPair {
+	cs := s(mhfScrypt)
+	db := NewMapLookup()
+	server, err := NewServer(cs, db, []byte("server"), opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	client, err := NewClient(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	v, err := client.Verifier()
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.Add([]byte("client"), v)
+
+	A, err := client.EphemeralPublic()
+	if err != nil {
+		b.Fatal(err)
+	}
+	m, secret, err := server.Handshake([]byte("client"), A)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &benchPair{client, server, A, m, secret}
+}
+
+func runPerSuite(b *testing.B, f func(b *testing.B, s Suite)) {
+	for _, s := range testSuites {
+		s := s
+		b.Run("suite="+s(mhfScrypt).ID(), func(b *testing.B) {
+			b.ReportAllocs()
+			f(b, s)
+		})
+	}
+}
+
+func BenchmarkEphemeralPublic(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.client.EphemeralPublic()
+		}
+	})
+}
+
+func BenchmarkHandshake(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.server.Handshake([]byte("client"), p.A)
+		}
+	})
+}
+
+func BenchmarkHandshakeCached(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s, WithVerifierCache(1))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.server.Handshake([]byte("client"), p.A)
+		}
+	})
+}
+
+func BenchmarkCompleteHandshake(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.client.CompleteHandshake(p.m)
+		}
+	})
+}
+
+// BenchmarkConfirmation times both MACs of one side and the check of the peer's.
+func BenchmarkConfirmation(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s)
+		clientSecret, err := p.client.CompleteHandshake(p.m)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cB := p.secret.Confirmation()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			clientSecret.generateConfirmations()
+			if err := clientSecret.Verify(cB); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkHandshakeParallel runs the server side of handshakes from GOMAXPROCS goroutines, each
+// with its own reused ServerSession, against a shared verifier cache.
+func BenchmarkHandshakeParallel(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s, WithVerifierCache(1))
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			ss, err := p.server.NewSession()
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			var B []byte
+			for pb.Next() {
+				ss.Reset()
+				if B, err = ss.AppendHandshake(B[:0], []byte("client"), p.A); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkHandshakeBatch(b *testing.B) {
+	runPerSuite(b, func(b *testing.B, s Suite) {
+		p := newBenchPair(b, s, WithVerifierCache(1))
+		requests := make([]HandshakeRequest, 64)
+		for i := range requests {
+			requests[i] = HandshakeRequest{Identity: []byte("client"), A: p.A}
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i += len(requests) {
+			p.server.HandshakeBatch(requests)
+		}
+	})
+}
+
+// BenchmarkMHF times the password hashing alone, with the parameters RFC 9383 and RFC 9106
+// suggest rather than the cheap ones the other tests use.
+func BenchmarkMHF(b *testing.B) {
+	for _, c := range []struct {
+		name string
+		mhf  suite.MHF
+	}{
+		{"scrypt", Scrypt(32768, 8, 1)},
+		{"argon2id", Argon2id(1, 64*1024, 4)},
+		{"pbkdf2-sha256", PBKDF2(crypto.SHA256, 600000)},
+		{"balloon-sha256", Balloon(16*1024, 3)},
+	} {
+		mhf := c.mhf
+		b.Run("mhf="+c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := mhf.Key([]byte("password"), []byte("NaCl"), 64); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
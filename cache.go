@@ -0,0 +1,84 @@
+package spake2plus
+
+import (
+	"container/list"
+	"crypto/subtle"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"sync"
+)
+
+// verifierEntry is a verifier decoded for the server side of a handshake: L, and w0 folded into
+// w0*N and -w0*M so a handshake doesn't need w0 itself. Entries are shared between handshakes
+// and never modified.
+type verifierEntry struct {
+	identity  string
+	v1, v2    []byte
+	simulated bool // stands in for an identity with no verifier
+	L         suite.Element
+	w0N       suite.Element
+	minusW0M  suite.Element
+}
+
+// current reports whether e still matches what the Lookup returned.
+func (e *verifierEntry) current(ok bool, v1, v2 []byte) bool {
+	if !ok {
+		return e.simulated
+	}
+	return !e.simulated && subtle.ConstantTimeCompare(e.v1, v1) == 1 && subtle.ConstantTimeCompare(e.v2, v2) == 1
+}
+
+// verifierCache holds the most recently used verifierEntries, up to size of them.
+type verifierCache struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+func newVerifierCache(size int) *verifierCache {
+	if size <= 0 {
+		return nil
+	}
+	return &verifierCache{size: size, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *verifierCache) get(identity []byte) *verifierEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[string(identity)]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*verifierEntry)
+}
+
+func (c *verifierCache) put(e *verifierEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.identity]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[e.identity] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*verifierEntry).identity)
+	}
+}
+
+func (c *verifierCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// purge drops every entry. Handshakes still holding one can finish with it.
+func (c *verifierCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+}
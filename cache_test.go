@@ -0,0 +1,88 @@
+package spake2plus
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVerifierCacheMatchesUncached(t *testing.T) {
+	for _, s := range testSuites {
+		newPair := func(opts ...Option) func(s Suite, db *MapLookup) (*Client, *Server, error) {
+			return func(s Suite, db *MapLookup) (*Client, *Server, error) {
+				cs := s(mhfScrypt)
+				server, err := NewServer(cs, db, []byte("server"), append(opts, WithRand(fixedRand()))...)
+				if err != nil {
+					return nil, nil, err
+				}
+				client, err := NewClient(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"), WithRand(fixedRand()))
+				return client, server, err
+			}
+		}
+
+		assert.Equal(t, runFixedHandshake(t, s, newPair()), runFixedHandshake(t, s, newPair(WithVerifierCache(4))))
+	}
+}
+
+func TestVerifierCacheFollowsLookup(t *testing.T) {
+	cs := Ed25519Sha256HkdfHmac(mhfScrypt)
+	db := NewMapLookup()
+	server, err := NewServer(cs, db, []byte("server"), WithVerifierCache(2))
+	assert.NoError(t, err)
+
+	login := func(password string) error {
+		client, err := NewClient(cs, []byte("client"), []byte("server"), []byte(password), []byte("NaCl"))
+		assert.NoError(t, err)
+		A, err := client.EphemeralPublic()
+		assert.NoError(t, err)
+		m, sB, err := server.Handshake([]byte("client"), A)
+		assert.NoError(t, err)
+		sA, err := client.CompleteHandshake(m)
+		assert.NoError(t, err)
+		return sB.Verify(sA.Confirmation())
+	}
+	register := func(password string) {
+		client, err := NewClient(cs, []byte("client"), []byte("server"), []byte(password), []byte("NaCl"))
+		assert.NoError(t, err)
+		v, err := client.Verifier()
+		assert.NoError(t, err)
+		db.Add([]byte("client"), v)
+	}
+
+	// a cached simulated verifier must not outlive registration
+	assert.Error(t, login("old"))
+	register("old")
+	assert.NoError(t, login("old"))
+	assert.NoError(t, login("old"))
+
+	// nor a cached verifier a password change
+	register("new")
+	assert.Error(t, login("old"))
+	assert.NoError(t, login("new"))
+	assert.Equal(t, 1, server.cache.len())
+
+	server.Destroy()
+	assert.Equal(t, 0, server.cache.len())
+}
+
+func TestVerifierCacheIsBounded(t *testing.T) {
+	cs := Ed25519Sha256HkdfHmac(mhfScrypt)
+	server, err := NewServer(cs, NewMapLookup(), []byte("server"), WithVerifierCache(2))
+	assert.NoError(t, err)
+	client, err := NewClient(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	assert.NoError(t, err)
+	A, err := client.EphemeralPublic()
+	assert.NoError(t, err)
+
+	first, _, err := server.Handshake([]byte("a"), A)
+	assert.NoError(t, err)
+	again, _, err := server.Handshake([]byte("a"), A)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again, "unknown identities get a consistent simulated verifier")
+
+	for _, id := range []string{"b", "c"} {
+		_, _, err := server.Handshake([]byte(id), A)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, server.cache.len())
+	assert.Nil(t, server.cache.get([]byte("a")))
+}
@@ -1,8 +1,10 @@
 package spake2plus
 
 import (
-	"errors"
+	"context"
 	"github.com/jtejido/spake2plus/internal/suite"
+	"io"
+	"time"
 )
 
 type Client struct {
@@ -13,21 +15,95 @@ type Client struct {
 	verifierW0     []byte
 	verifierW1     []byte
 	msg            []byte
+	rand           io.Reader
+	observer       Observer // nil unless WithObserver
+	suiteID        string
 }
 
-func NewClient(s suite.CipherSuite, clientIdentity, serverIdentity, password, salt []byte) (*Client, error) {
-	x, err := s.Group().RandomScalar()
+func NewClient(s suite.CipherSuite, clientIdentity, serverIdentity, password, salt []byte, opts ...Option) (*Client, error) {
+	return NewClientContext(context.Background(), s, clientIdentity, serverIdentity, password, salt, opts...)
+}
+
+// NewClientContext is NewClient with a context for the MHF. An MHF that implements KeyContext,
+// such as one calling out to a key-stretching service, is cancelled with ctx; any other MHF is only
+// kept from starting once ctx is done.
+func NewClientContext(ctx context.Context, s suite.CipherSuite, clientIdentity, serverIdentity, password, salt []byte, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	x, err := s.Group().RandomScalar(o.rand)
 
 	if err != nil {
 		return nil, err
 	}
 
-	w0, w1, err := computeW0W1(s, clientIdentity, serverIdentity, password, salt)
+	return newClientFromPassword(ctx, s, clientIdentity, serverIdentity, password, salt, x, o)
+}
+
+// NewClientWithScalar is NewClient with a caller-chosen ephemeral scalar x, encoded as the group's
+// Scalar().Bytes() would. It exists for test vectors and interop testing; x must never be reused.
+func NewClientWithScalar(s suite.CipherSuite, clientIdentity, serverIdentity, password, salt, x []byte, opts ...Option) (*Client, error) {
+	sc := s.Group().Scalar()
+	if err := sc.FromBytes(x); err != nil {
+		return nil, err
+	}
+
+	return newClientFromPassword(context.Background(), s, clientIdentity, serverIdentity, password, salt, sc, newOptions(opts))
+}
+
+// NewClientWithW0W1 is NewClient for w0 and w1 derived elsewhere, e.g. by a hardware token, as the
+// suite's Mhf would split them. Both must be scalars no longer than the group's ScalarLen. The
+// server side is unaffected, so its protections against enumeration stay as they are.
+func NewClientWithW0W1(s suite.CipherSuite, clientIdentity, serverIdentity, w0, w1 []byte, opts ...Option) (*Client, error) {
+	for _, w := range [][]byte{w0, w1} {
+		if len(w) == 0 || len(w) > s.Group().ScalarLen() {
+			return nil, suite.NewError(suite.BadLength, "wrong size w0 or w1")
+		}
+		sc := s.Group().Scalar()
+		if err := sc.FromBytes(padScalarBytes(w, s.Group().ScalarLen())); err != nil {
+			return nil, err
+		}
+		sc.Zero()
+	}
+
+	o := newOptions(opts)
+	if o.precis {
+		if err := prepareIdentities(&clientIdentity, &serverIdentity); err != nil {
+			return nil, err
+		}
+	}
+	x, err := s.Group().RandomScalar(o.rand)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{s, x, clientIdentity, serverIdentity, w0, w1, nil}, nil
+	// copied, so that Destroy doesn't wipe the caller's buffers
+	w0 = append([]byte(nil), w0...)
+	w1 = append([]byte(nil), w1...)
+	return newClient(s, clientIdentity, serverIdentity, w0, w1, x, o), nil
+}
+
+func newClientFromPassword(ctx context.Context, s suite.CipherSuite, clientIdentity, serverIdentity, password, salt []byte, x suite.Scalar, o *options) (*Client, error) {
+	if o.precis {
+		if err := prepareIdentities(&clientIdentity, &serverIdentity); err != nil {
+			return nil, err
+		}
+		p, err := PreparePassword(password)
+		if err != nil {
+			return nil, err
+		}
+		defer wipe(p)
+		password = p
+	}
+
+	w0, w1, err := computeW0W1(ctx, s, clientIdentity, serverIdentity, password, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(s, clientIdentity, serverIdentity, w0, w1, x, o), nil
+}
+
+func newClient(s suite.CipherSuite, clientIdentity, serverIdentity, w0, w1 []byte, x suite.Scalar, o *options) *Client {
+	return &Client{s, x, clientIdentity, serverIdentity, w0, w1, nil, o.rand, o.observer, s.ID()}
 }
 
 // Send this to server during Registration part
@@ -39,74 +115,121 @@ func (c *Client) Verifier() (*Verifier, error) {
 	}
 
 	L := c.suite.Group().Element().ScalarMult(w1Scalar, nil)
+	w1Scalar.Zero()
 
 	return &Verifier{
 		I: c.clientIdentity,
 		Verifier: VerifierPair{
-			V1: c.verifierW0,
+			// copied, so that Destroy doesn't reach into the registered record
+			V1: append([]byte(nil), c.verifierW0...),
 			V2: L.Bytes(),
 		},
+		MHF: c.suite.MhfDescriptor(),
 	}, nil
 }
 
 // EphemeralPublic returns A.
 // This will be sent to the server.
 func (c *Client) EphemeralPublic() ([]byte, error) {
-	w0Scalar := c.suite.Group().Scalar()
-	err := w0Scalar.FromBytes(padScalarBytes(c.verifierW0, c.suite.Group().ScalarLen()))
+	XBytes, err := c.share(c.x)
 	if err != nil {
 		return nil, err
 	}
-
-	// X=x*P+w0*M
-	x := c.suite.Group().Element().ScalarMult(c.x, nil)
-	x.Add(x, c.suite.Group().Element().ScalarMult(w0Scalar, c.suite.Group().M()))
-
-	XBytes := x.Bytes()
 	c.msg = XBytes
 
 	return XBytes, nil
 }
 
+func (c *Client) share(xScalar suite.Scalar) ([]byte, error) {
+	X, err := c.shareElement(&workspace{g: c.suite.Group()}, xScalar)
+	if err != nil {
+		return nil, err
+	}
+	return X.Bytes(), nil
+}
+
+// shareElement returns X. Only the scalars of ws are used.
+func (c *Client) shareElement(ws *workspace, xScalar suite.Scalar) (suite.Element, error) {
+	w0Scalar, _ := ws.scalars()
+	err := ws.setScalar(w0Scalar, c.verifierW0)
+	if err != nil {
+		return nil, err
+	}
+	defer w0Scalar.Zero()
+
+	// X=x*P+w0*M
+	return c.suite.Group().MultiScalarMult([]suite.Scalar{xScalar, w0Scalar}, []suite.Element{nil, c.suite.Group().M()}), nil
+}
+
 func (c *Client) CompleteHandshake(m *ServerMaterial) (*SharedSecret, error) {
-	incomingElement := c.suite.Group().Element()
-	err := incomingElement.FromBytes(m.B)
+	return c.complete(context.Background(), newWorkspace(c.suite.Group()), c.x, c.msg, m.B)
+}
+
+// CompleteHandshakeContext is CompleteHandshake passing ctx to the Observer's events.
+func (c *Client) CompleteHandshakeContext(ctx context.Context, m *ServerMaterial) (*SharedSecret, error) {
+	return c.complete(ctx, newWorkspace(c.suite.Group()), c.x, c.msg, m.B)
+}
+
+// complete derives the shared secret from B, using ws for the intermediate values. The
+// SharedSecret keeps X and B.
+func (c *Client) complete(ctx context.Context, ws *workspace, x suite.Scalar, X, B []byte) (*SharedSecret, error) {
+	if c.observer == nil {
+		return c.computeSecret(ws, x, X, B)
+	}
+
+	start := time.Now()
+	c.observer.HandshakeStarted(HandshakeEvent{Side: ClientSide, Suite: c.suiteID, Start: start, Context: ctx})
+	secret, err := c.computeSecret(ws, x, X, B)
+	e := HandshakeEvent{Side: ClientSide, Suite: c.suiteID, Start: start, Duration: time.Since(start), Context: ctx}
+	if err != nil {
+		e.Failed, e.Reason = true, reasonOf(err)
+	} else {
+		secret.observation = &observation{c.observer, ClientSide, c.suiteID, start, ctx}
+	}
+	c.observer.HandshakeFinished(e)
+	return secret, err
+}
+
+// computeSecret is complete without the Observer.
+func (c *Client) computeSecret(ws *workspace, x suite.Scalar, X, B []byte) (*SharedSecret, error) {
+	err := ws.incoming.FromBytes(B)
 	if err != nil {
 		return nil, err
 	}
 
-	if isElementSmall(c.suite, incomingElement) {
-		return nil, errors.New("Corrupt Message")
+	if ws.isElementSmall(ws.incoming) {
+		return nil, suite.NewError(suite.SmallOrderPoint, "Corrupt Message")
 	}
 
-	w0Scalar := c.suite.Group().Scalar()
-	err = w0Scalar.FromBytes(padScalarBytes(c.verifierW0, c.suite.Group().ScalarLen()))
+	w0Scalar, w1Scalar := ws.scalars()
+	defer w0Scalar.Zero()
+	defer w1Scalar.Zero()
+	err = ws.setScalar(w0Scalar, c.verifierW0)
 	if err != nil {
 		return nil, err
 	}
 
-	w1Scalar := c.suite.Group().Scalar()
-	err = w1Scalar.FromBytes(padScalarBytes(c.verifierW1, c.suite.Group().ScalarLen()))
+	err = ws.setScalar(w1Scalar, c.verifierW1)
 	if err != nil {
 		return nil, err
 	}
 
 	// A computes Z as h*x*(Y-w0*N), and V as h*w1*(Y-w0*N).
-	tmp := c.suite.Group().Element().ScalarMult(c.suite.Group().Scalar().Negate(w0Scalar), c.suite.Group().N())
-	tmp.Add(incomingElement, tmp)
-
-	ZElement := c.suite.Group().Element().ScalarMult(c.x, tmp)
-	ZElement = c.suite.Group().ClearCofactor(ZElement)
-	ZBytes := ZElement.Bytes()
+	ws.tmp.ScalarMult(w0Scalar.Negate(w0Scalar), c.suite.Group().N())
+	ws.tmp.Add(ws.incoming, ws.tmp)
 
-	VElement := c.suite.Group().Element().ScalarMult(w1Scalar, tmp)
-	VElement = c.suite.Group().ClearCofactor(VElement)
-	VBytes := VElement.Bytes()
+	ws.rawZ.ScalarMult(x, ws.tmp)
+	ws.rawV.ScalarMult(w1Scalar, ws.tmp)
+	ws.clearCofactors()
+	ZBytes, VBytes := ws.encodeZV()
 
-	return newClientSharedSecret(c.clientIdentity, c.serverIdentity, c.msg, m.B, ZBytes, VBytes, c.verifierW0, c.suite), nil
+	return ws.sharedSecret(c.suite, c.clientIdentity, c.serverIdentity, X, B, ZBytes, VBytes, c.verifierW0, true), nil
 }
 
-func newClientSharedSecret(idA, idB, X, Y, Z, V, w0 []byte, s suite.CipherSuite) *SharedSecret {
-	Ke, Ka, kcA, kcB := generateSharedSecrets(s, idA, idB, X, Y, Z, V, w0)
-	return newSharedSecret(Ke, Ka, X, Y, kcA, kcB, s)
+// Destroy overwrites w0, w1 and the ephemeral x. The Client, and any ClientSession created from it,
+// must not be used afterwards.
+func (c *Client) Destroy() {
+	wipe(c.verifierW0)
+	wipe(c.verifierW1)
+	c.x.Zero()
 }
@@ -0,0 +1,64 @@
+// Command auditverify checks a log written by the audit package: every signature, the hash chain
+// from the first record, and optionally that the log still contains an earlier head.
+//
+//	auditverify -pub HEX [-head SEQ:HASH] [-closed] LOG
+//
+// -pub is the hex Ed25519 public key of the log, that of audit.NewKey(serverKey). -head is a head
+// printed by an earlier run or returned by Log.Head; records removed after it are reported as a
+// truncation. -closed requires the log to end with a close record, as after a clean shutdown.
+// On success the command prints the number of records and the head to keep for the next run.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/jtejido/spake2plus/audit"
+	"os"
+)
+
+func main() {
+	pub := flag.String("pub", "", "hex Ed25519 public key of the log")
+	head := flag.String("head", "", "an earlier head, SEQ:HASH, that the log must contain")
+	closed := flag.Bool("closed", false, "require the log to end with a close record")
+	flag.Parse()
+	if flag.NArg() != 1 || *pub == "" {
+		fmt.Fprintln(os.Stderr, "usage: auditverify -pub HEX [-head SEQ:HASH] [-closed] LOG")
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *pub, *head, *closed); err != nil {
+		fmt.Fprintln(os.Stderr, "auditverify:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, pubHex, headStr string, closed bool) error {
+	pub, err := hex.DecodeString(pubHex)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("-pub isn't a hex Ed25519 public key")
+	}
+	var checkpoint *audit.Head
+	if headStr != "" {
+		h, err := audit.ParseHead(headStr)
+		if err != nil {
+			return err
+		}
+		checkpoint = &h
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	res, err := audit.Verify(f, ed25519.PublicKey(pub), checkpoint)
+	if err != nil {
+		return err
+	}
+	if closed && !res.Closed {
+		return fmt.Errorf("%s doesn't end with a close record: truncated, or still open", path)
+	}
+	fmt.Printf("%d records, head %s\n", res.Records, res.Head)
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"github.com/jtejido/spake2plus"
+	"github.com/jtejido/spake2plus/audit"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	key := audit.NewKey([]byte("a long-term server key of 32 bytes"))
+	pub := hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	l, err := audit.Open(path, key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	l.Audit(spake2plus.AuditEvent{Kind: spake2plus.AuditHandshake, Time: time.Now(), Identity: []byte("alice")})
+	l.Audit(spake2plus.AuditEvent{Kind: spake2plus.AuditConfirmation, Time: time.Now(), Identity: []byte("alice"),
+		Failed: true, Reason: spake2plus.ConfirmationMismatch})
+	mid := l.Head()
+	assert.NoError(t, l.Close())
+	head := l.Head()
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	write := func(name, log string) string {
+		p := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(p, []byte(log), 0o600))
+		return p
+	}
+
+	assert.NoError(t, run(path, pub, "", true))
+	assert.NoError(t, run(path, pub, head.String(), true))
+	assert.NoError(t, run(path, pub, mid.String(), true))
+
+	// the close record removed: fine alone, but not with -closed or the final head
+	lines := strings.SplitAfter(string(b), "\n")
+	truncated := write("truncated.log", strings.Join(lines[:len(lines)-2], ""))
+	assert.NoError(t, run(truncated, pub, mid.String(), false))
+	assert.Error(t, run(truncated, pub, "", true))
+	err = run(truncated, pub, head.String(), false)
+	assert.True(t, errors.Is(err, audit.ErrCheckpoint))
+
+	// a partial record
+	err = run(write("partial.log", string(b[:len(b)-10])), pub, "", false)
+	assert.True(t, errors.Is(err, audit.ErrTruncated))
+
+	// a tampered record
+	tampered := strings.Replace(string(b), `"result":"confirmation mismatch"`, `"result":"ok"`, 1)
+	assert.NotEqual(t, string(b), tampered)
+	var verr *audit.VerifyError
+	if assert.True(t, errors.As(run(write("tampered.log", tampered), pub, "", false), &verr)) {
+		assert.Equal(t, 3, verr.Line)
+	}
+
+	// a head the log doesn't go through
+	other := head
+	other.Hash[0] ^= 1
+	err = run(path, pub, other.String(), false)
+	assert.True(t, errors.Is(err, audit.ErrCheckpoint))
+
+	// bad arguments
+	assert.Error(t, run(path, pub[2:], "", false))
+	assert.Error(t, run(path, pub, "x", false))
+	assert.Error(t, run(filepath.Join(dir, "missing.log"), pub, "", false))
+}
@@ -0,0 +1,42 @@
+package spake2plus
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// Error carries a Reason for every failure with a known cause, so callers can map failures to
+// protocol alerts or metrics with errors.As, or match them with errors.Is against the sentinels below.
+type Error = suite.Error
+
+// Reason classifies an Error.
+type Reason = suite.Reason
+
+const (
+	ReasonUnknown        = suite.ReasonUnknown
+	InvalidPoint         = suite.InvalidPoint
+	SmallOrderPoint      = suite.SmallOrderPoint
+	BadLength            = suite.BadLength
+	InvalidScalar        = suite.InvalidScalar
+	ConfirmationMismatch = suite.ConfirmationMismatch
+	UnknownSuite         = suite.UnknownSuite
+	InvalidState         = suite.InvalidState
+	MalformedMessage     = suite.MalformedMessage
+	IntegrityCheckFailed = suite.IntegrityCheckFailed
+	InvalidInput         = suite.InvalidInput
+	RateLimited          = suite.RateLimited
+)
+
+// Sentinel errors. Each matches, through errors.Is, any Error with the same Reason.
+var (
+	ErrInvalidPoint         = suite.ErrInvalidPoint
+	ErrSmallOrderPoint      = suite.ErrSmallOrderPoint
+	ErrBadLength            = suite.ErrBadLength
+	ErrInvalidScalar        = suite.ErrInvalidScalar
+	ErrConfirmationMismatch = suite.ErrConfirmationMismatch
+	ErrUnknownSuite         = suite.ErrUnknownSuite
+	ErrInvalidState         = suite.ErrInvalidState
+	ErrMalformedMessage     = suite.ErrMalformedMessage
+	ErrIntegrityCheckFailed = suite.ErrIntegrityCheckFailed
+	ErrInvalidInput         = suite.ErrInvalidInput
+	ErrRateLimited          = suite.ErrRateLimited
+)
@@ -0,0 +1,41 @@
+package spake2plus
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestErrorReasons(t *testing.T) {
+	db := NewMapLookup()
+	suite := Ed25519Sha256HkdfHmac(mhfScrypt)
+	server, err := NewServer(suite, db, []byte("server"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// identity element
+	identity := make([]byte, 32)
+	identity[0] = 1
+	_, _, err = server.Handshake([]byte("client"), identity)
+	assert.True(t, errors.Is(err, ErrSmallOrderPoint))
+
+	var e *Error
+	if assert.True(t, errors.As(err, &e)) {
+		assert.Equal(t, SmallOrderPoint, e.Reason)
+	}
+
+	_, _, err = server.Handshake([]byte("client"), identity[:31])
+	assert.True(t, errors.Is(err, ErrBadLength))
+	assert.False(t, errors.Is(err, ErrInvalidPoint))
+
+	// y = 2 is not on the curve
+	notOnCurve := make([]byte, 32)
+	notOnCurve[0] = 2
+	_, _, err = server.Handshake([]byte("client"), notOnCurve)
+	assert.True(t, errors.Is(err, ErrInvalidPoint))
+
+	err = NewConfirmations(nil, []byte("expected"), suite).Verify([]byte("received"))
+	assert.True(t, errors.Is(err, ErrConfirmationMismatch))
+	assert.Equal(t, "Verification Failed", err.Error())
+}
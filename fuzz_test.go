@@ -0,0 +1,231 @@
+package spake2plus
+
+import (
+	"bytes"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"testing"
+)
+
+// Fuzz targets for everything that takes bytes from the peer or from storage. Each input picks a
+// suite from testSuites with its first argument; the seeds cover every suite. Without -fuzz they
+// run as tests over the seeds, so an encoding that once panicked can be pinned with f.Add.
+
+func fuzzSuite(which uint8) suite.CipherSuite {
+	return testSuites[int(which)%len(testSuites)](mhfScrypt)
+}
+
+// edgeEncodings returns encodings at the edges of what g accepts: empty, a byte short or long,
+// all zeros, all ones, and a few real elements with their leading or trailing byte flipped.
+func edgeEncodings(g suite.Group, valid ...[]byte) [][]byte {
+	n := g.ElementLen()
+	seeds := [][]byte{
+		nil,
+		make([]byte, n-1),
+		make([]byte, n),
+		make([]byte, n+1),
+		bytes.Repeat([]byte{0xff}, n),
+		append([]byte{0x04}, make([]byte, n-1)...),
+	}
+	for _, v := range valid {
+		seeds = append(seeds, v)
+		if len(v) == 0 {
+			continue
+		}
+		for _, i := range []int{0, len(v) - 1} {
+			flipped := append([]byte(nil), v...)
+			flipped[i] ^= 0x80
+			seeds = append(seeds, flipped)
+		}
+	}
+	return seeds
+}
+
+func elementSeeds(g suite.Group) [][]byte {
+	B := g.Element().ScalarMult(g.CofactorScalar(), nil)
+	return edgeEncodings(g, g.Element().Identity().Bytes(), B.Bytes(), g.M().Bytes(), g.N().Bytes())
+}
+
+func scalarSeeds(g suite.Group) [][]byte {
+	n := g.ScalarLen()
+	order := g.Order().Bytes()
+	orderMinusOne := append([]byte(nil), order...)
+	orderMinusOne[len(orderMinusOne)-1]--
+	return [][]byte{
+		nil,
+		make([]byte, n),
+		make([]byte, n+1),
+		bytes.Repeat([]byte{0xff}, n),
+		append(make([]byte, n-len(order)), order...),
+		append(make([]byte, n-len(order)), orderMinusOne...),
+		g.CofactorScalar().Bytes(),
+	}
+}
+
+func FuzzElementFromBytes(f *testing.F) {
+	for i, s := range testSuites {
+		for _, b := range elementSeeds(s(mhfScrypt).Group()) {
+			f.Add(uint8(i), b)
+		}
+	}
+	f.Fuzz(func(t *testing.T, which uint8, b []byte) {
+		g := fuzzSuite(which).Group()
+		e := g.Element()
+		if e.FromBytes(b) != nil {
+			return
+		}
+		// whatever decodes must encode to something that decodes to the same element
+		e2 := g.Element()
+		if err := e2.FromBytes(e.Bytes()); err != nil {
+			t.Fatalf("%s: re-decoding %x: %v", g, e.Bytes(), err)
+		}
+		if !e.Equal(e2) {
+			t.Fatalf("%s: %x does not round-trip", g, b)
+		}
+	})
+}
+
+func FuzzScalarFromBytes(f *testing.F) {
+	for i, s := range testSuites {
+		for _, b := range scalarSeeds(s(mhfScrypt).Group()) {
+			f.Add(uint8(i), b)
+		}
+	}
+	f.Fuzz(func(t *testing.T, which uint8, b []byte) {
+		g := fuzzSuite(which).Group()
+		sc := g.Scalar()
+		if sc.FromBytes(b) != nil {
+			return
+		}
+		sc2 := g.Scalar()
+		if err := sc2.FromBytes(sc.Bytes()); err != nil {
+			t.Fatalf("%s: re-decoding %x: %v", g, sc.Bytes(), err)
+		}
+		if !bytes.Equal(sc.Bytes(), sc2.Bytes()) {
+			t.Fatalf("%s: %x does not round-trip", g, b)
+		}
+	})
+}
+
+// fuzzPair is a registered client and a server for one suite.
+type fuzzPair struct {
+	client *Client
+	server *Server
+	A      []byte
+	m      *ServerMaterial
+}
+
+func newFuzzPairs(f *testing.F) []*fuzzPair {
+	var pairs []*fuzzPair
+	for _, s := range testSuites {
+		cs := s(mhfScrypt)
+		db := NewMapLookup()
+		server, err := NewServer(cs, db, []byte("server"))
+		if err != nil {
+			f.Fatal(err)
+		}
+		client, err := NewClient(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+		if err != nil {
+			f.Fatal(err)
+		}
+		v, err := client.Verifier()
+		if err != nil {
+			f.Fatal(err)
+		}
+		db.Add([]byte("client"), v)
+		A, err := client.EphemeralPublic()
+		if err != nil {
+			f.Fatal(err)
+		}
+		m, _, err := server.Handshake([]byte("client"), A)
+		if err != nil {
+			f.Fatal(err)
+		}
+		pairs = append(pairs, &fuzzPair{client, server, A, m})
+	}
+	return pairs
+}
+
+func FuzzServerHandshake(f *testing.F) {
+	pairs := newFuzzPairs(f)
+	for i, p := range pairs {
+		g := p.client.suite.Group()
+		for _, b := range append(elementSeeds(g), p.A) {
+			f.Add(uint8(i), []byte("client"), b)
+		}
+		f.Add(uint8(i), []byte(nil), p.A)
+		f.Add(uint8(i), []byte("mallory"), p.A)
+	}
+	f.Fuzz(func(t *testing.T, which uint8, identity, A []byte) {
+		p := pairs[int(which)%len(pairs)]
+		m, secret, err := p.server.Handshake(identity, A)
+		if err != nil {
+			return
+		}
+		if len(m.B) != p.client.suite.Group().ElementLen() || secret == nil {
+			t.Fatalf("accepted %x, but B is %x", A, m.B)
+		}
+
+		// the same through a session and a batch
+		ss, err := p.server.NewSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ss.Handshake(identity, A); err != nil {
+			t.Fatalf("Handshake accepted %x, ServerSession: %v", A, err)
+		}
+		if r := p.server.HandshakeBatch([]HandshakeRequest{{Identity: identity, A: A}}); r[0].Err != nil {
+			t.Fatalf("Handshake accepted %x, HandshakeBatch: %v", A, r[0].Err)
+		}
+	})
+}
+
+func FuzzClientCompleteHandshake(f *testing.F) {
+	pairs := newFuzzPairs(f)
+	for i, p := range pairs {
+		for _, b := range append(elementSeeds(p.client.suite.Group()), p.m.B) {
+			f.Add(uint8(i), b, []byte(nil))
+		}
+		f.Add(uint8(i), p.m.B, make([]byte, 32))
+	}
+	f.Fuzz(func(t *testing.T, which uint8, B, cB []byte) {
+		p := pairs[int(which)%len(pairs)]
+		if secret, err := p.client.CompleteHandshake(&ServerMaterial{B}); err == nil {
+			secret.Verify(cB)
+		}
+
+		cs, err := p.client.NewSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cs.Share(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cs.Confirm(&ServerMaterial{B}, cB); err == nil {
+			t.Fatalf("accepted the confirmation %x", cB)
+		}
+	})
+}
+
+func FuzzParseMHF(f *testing.F) {
+	for _, s := range []string{
+		"", "$", "$$", "$scrypt$", "$scrypt$ln=4,r=1,p=1", "$scrypt$ln=63,r=1,p=1", "$scrypt$ln=4,r=1,p=1,p=2",
+		"$argon2id$v=19$m=65536,t=1,p=4", "$argon2i$v=16$m=1,t=1,p=1", "$argon2id$v=19$m=4294967296,t=1,p=4",
+		"$pbkdf2-sha256$i=600000", "$pbkdf2-sha256$i=-1", "$balloon-sha256$s=16,t=3,d=3", "$balloon-sha256$s=16,t=3,d=4",
+		Scrypt(16, 1, 1).String(), Argon2id(1, 64, 1).String(),
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, descriptor string) {
+		m, err := ParseMHF(descriptor)
+		if err != nil {
+			return
+		}
+		m2, err := ParseMHF(m.String())
+		if err != nil {
+			t.Fatalf("%q parses, but its String %q doesn't: %v", descriptor, m.String(), err)
+		}
+		if m2.String() != m.String() {
+			t.Fatalf("%q: String is not stable, %q then %q", descriptor, m.String(), m2.String())
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package suite
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+)
+
+// CMAC (RFC 4493, NIST SP 800-38B) over AES.
+
+type cmac struct {
+	name    string
+	keySize int
+}
+
+// CMAC-AES-128 and CMAC-AES-256. Longer keys are truncated to the AES key size, as the draft
+// allows for KcA and KcB; shorter ones are a programming error and panic.
+var (
+	CMAC_AES128 MAC = cmac{"CMAC-AES128", 16}
+	CMAC_AES256 MAC = cmac{"CMAC-AES256", 32}
+)
+
+func (m cmac) String() string {
+	return m.name
+}
+
+func (m cmac) KeySize() int {
+	return m.keySize
+}
+
+// shift sets dst to src << 1 in GF(2^128), as used for the subkeys.
+func shift(dst, src []byte) {
+	msb := src[0] >> 7
+	for i := 0; i < aes.BlockSize-1; i++ {
+		dst[i] = src[i]<<1 | src[i+1]>>7
+	}
+	dst[aes.BlockSize-1] = src[aes.BlockSize-1]<<1 ^ byte(subtle.ConstantTimeByteEq(msb, 1))*0x87
+}
+
+func (m cmac) Sum(content, key []byte) []byte {
+	if len(key) < m.keySize {
+		panic("suite: " + m.name + " key too short")
+	}
+	block, err := aes.NewCipher(key[:m.keySize])
+	if err != nil {
+		panic(err)
+	}
+
+	var k1, k2, last, x [aes.BlockSize]byte
+	block.Encrypt(k1[:], k1[:])
+	shift(k1[:], k1[:])
+	shift(k2[:], k1[:])
+
+	n := (len(content) + aes.BlockSize - 1) / aes.BlockSize
+	if n == 0 {
+		n = 1
+	}
+	rest := content[(n-1)*aes.BlockSize:]
+	if len(rest) == aes.BlockSize {
+		subtle.XORBytes(last[:], rest, k1[:])
+	} else {
+		copy(last[:], rest)
+		last[len(rest)] = 0x80
+		subtle.XORBytes(last[:], last[:], k2[:])
+	}
+
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x[:], x[:], content[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x[:], x[:])
+	}
+	subtle.XORBytes(x[:], x[:], last[:])
+	block.Encrypt(x[:], x[:])
+
+	return x[:]
+}
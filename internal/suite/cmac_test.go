@@ -0,0 +1,44 @@
+package suite
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// RFC 4493, section 4 (AES-128) and NIST SP 800-38B, appendix D (AES-256).
+func TestCMAC(t *testing.T) {
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+
+	for _, c := range []struct {
+		mac  MAC
+		key  string
+		want []string
+	}{
+		{CMAC_AES128, "2b7e151628aed2a6abf7158809cf4f3c", []string{
+			"bb1d6929e95937287fa37d129b756746",
+			"070a16b46b4d4144f79bdd9dd04a287c",
+			"dfa66747de9ae63030ca32611497c827",
+			"51f0bebf7e3b9d92fc49741779363cfe",
+		}},
+		{CMAC_AES256, "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", []string{
+			"028962f61b7bf89efc6b551f4667d983",
+			"28a7023f452e8f82bd4bf28d8c37c35c",
+			"aaf3d8f1de5640c232f5b169b9c911e6",
+			"e1992190549f6ed5696a2c056c315410",
+		}},
+	} {
+		key, _ := hex.DecodeString(c.key)
+		for i, l := range []int{0, 16, 40, 64} {
+			assert.Equal(t, c.want[i], hex.EncodeToString(c.mac.Sum(msg[:l], key)), c.mac.String())
+		}
+	}
+}
+
+func TestCMACKeyTruncation(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	long := append(append([]byte(nil), key...), 0xff, 0xff, 0xff, 0xff)
+
+	assert.Equal(t, CMAC_AES128.Sum([]byte("msg"), key), CMAC_AES128.Sum([]byte("msg"), long))
+	assert.Panics(t, func() { CMAC_AES256.Sum([]byte("msg"), key) })
+}
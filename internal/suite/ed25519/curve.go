@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"github.com/jtejido/spake2plus/internal/suite"
+	ed "github.com/jtejido/spake2plus/internal/suite/ed25519/internal/ed25519"
+	"io"
 	"math/big"
 )
 
@@ -11,10 +13,16 @@ var cofactor = new(big.Int).SetInt64(8)
 var prime, _ = new(big.Int).SetString("57896044618658097711785492504343953926634992332820282019728792003956564819949", 10)
 var primeOrder, _ = new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
 
+// curve is Ed25519. The "Ed25519" suites clear the cofactor by multiplying by 2^251, as they always
+// have; h8 is the group of the "edwards25519" suites, which multiply by h = 8 as RFC 9383 does.
 type curve struct {
+	h8 bool
 }
 
 func (c curve) String() string {
+	if c.h8 {
+		return "edwards25519"
+	}
 	return "Ed25519"
 }
 
@@ -27,12 +35,14 @@ func (c curve) Element() suite.Element {
 	return P
 }
 
-func (c curve) M() suite.Element {
-	str, err := hex.DecodeString("d048032c6ea0b6d697ddc2e86bda85a33adac920f1bf18e1b0c6d166a5cecdaf")
+var fixedM, fixedN fixedPoint
+
+func (c curve) fromHex(s string) *point {
+	str, err := hex.DecodeString(s)
 	if err != nil {
 		panic(err)
 	}
-	point := c.Element()
+	point := new(point)
 	if err := point.FromBytes(str); err != nil {
 		panic(err)
 	}
@@ -40,27 +50,59 @@ func (c curve) M() suite.Element {
 	return point
 }
 
+func (c curve) M() suite.Element {
+	return fixedM.get(func() *point {
+		return c.fromHex("d048032c6ea0b6d697ddc2e86bda85a33adac920f1bf18e1b0c6d166a5cecdaf")
+	})
+}
+
 func (c curve) N() suite.Element {
-	str, err := hex.DecodeString("d3bfb518f44f3430f29d0c92af503865a1ed3281dc69b35dd868ba85f886c4ab")
-	if err != nil {
-		panic(err)
+	return fixedN.get(func() *point {
+		return c.fromHex("d3bfb518f44f3430f29d0c92af503865a1ed3281dc69b35dd868ba85f886c4ab")
+	})
+}
+
+func (c curve) MultiScalarMult(scalars []suite.Scalar, elements []suite.Element) suite.Element {
+	points := make([]*point, len(elements))
+	for i, e := range elements {
+		if e != nil {
+			points[i] = e.(*point)
+		}
 	}
-	point := c.Element()
-	if err := point.FromBytes(str); err != nil {
-		panic(err)
+	return new(point).multiScalarMult(scalars, points)
+}
+
+// EncodeElements shares one inversion between all the elements.
+func (c curve) EncodeElements(elements []suite.Element) [][]byte {
+	points := make([]*ed.ExtendedGroupElement, len(elements))
+	for i, e := range elements {
+		points[i] = &e.(*point).ge
 	}
+	enc := make([][32]byte, len(points))
+	ed.GeBatchToBytes(enc, points)
 
-	return point
+	out := make([][]byte, len(enc))
+	for i := range enc {
+		out[i] = enc[i][:]
+	}
+	return out
 }
 
-func (c curve) RandomElement() (suite.Element, error) {
-	sc, err := c.RandomScalar()
-	return c.Element().ScalarMult(sc, nil), err
+func (c curve) RandomElement(r io.Reader) (suite.Element, error) {
+	sc, err := c.RandomScalar(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.Element().ScalarMult(sc, nil), nil
 }
 
-func (c curve) RandomScalar() (suite.Scalar, error) {
+func (c curve) RandomScalar(r io.Reader) (suite.Scalar, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+
 	var b [64]byte
-	_, err := rand.Read(b[:])
+	_, err := io.ReadFull(r, b[:])
 	if err != nil {
 		return nil, err
 	}
@@ -84,16 +126,43 @@ func (c curve) Order() *big.Int {
 	return primeOrder
 }
 
-func (c curve) CofactorScalar() suite.Scalar {
-	var sc scalar
+// cofactorScalar is 2^251, what the "Ed25519" suites clear the cofactor with; ClearCofactor uses
+// it without a copy.
+var cofactorScalar = func() (sc scalar) {
 	buf := make([]byte, 32)
 	copy(buf[:1], cofactor.Bytes())
 	copy(sc[:], reverse(buf))
+	return
+}()
+
+func (c curve) CofactorScalar() suite.Scalar {
+	if c.h8 {
+		sc := scalar{byte(cofactor.Int64())}
+		return &sc
+	}
+	sc := cofactorScalar
 	return &sc
 }
 
 func (c curve) ClearCofactor(elem suite.Element) suite.Element {
-	return c.Element().ScalarMult(c.CofactorScalar(), elem)
+	return c.ClearCofactorTo(c.Element(), elem)
+}
+
+// ClearCofactorTo sets dst to 8*elem with three doublings, or to 2^251*elem for "Ed25519".
+func (c curve) ClearCofactorTo(dst, elem suite.Element) suite.Element {
+	if !c.h8 {
+		return dst.ScalarMult(&cofactorScalar, elem)
+	}
+	P := dst.(*point)
+	P.ge.MulByCofactor(&elem.(*point).ge)
+	P.table = nil
+	return P
+}
+
+// IsSmallOrder compares elem with the eight points of order dividing 8, in constant time. Either
+// cofactor clears exactly those.
+func (c curve) IsSmallOrder(elem suite.Element) bool {
+	return elem.(*point).ge.IsSmallOrder()
 }
 
 func reverse(p []byte) []byte {
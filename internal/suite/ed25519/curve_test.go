@@ -0,0 +1,50 @@
+package ed25519
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCofactor(t *testing.T) {
+	// "Ed25519" keeps clearing with 2^251, which takes the same points to the identity
+	legacy := curve{}
+	assert.Equal(t, append([]byte{8}, make([]byte, 31)...), legacy.CofactorScalar().Bytes())
+	Q := legacy.Element().Add(legacy.M(), legacy.N())
+	assert.True(t, legacy.ClearCofactor(Q).Equal(legacy.Element().ScalarMult(legacy.CofactorScalar(), Q)))
+	assert.Equal(t, "Ed25519", legacy.String())
+
+	g := curve{h8: true}
+	assert.Equal(t, append(make([]byte, 31), 8), g.CofactorScalar().Bytes())
+	assert.Equal(t, "edwards25519", g.String())
+
+	B := g.Element().ScalarMult(g.CofactorScalar(), nil)
+	P := g.Element().Add(B, g.M())
+	want := g.Element().Identity()
+	for i := 0; i < 8; i++ {
+		want.Add(want, P)
+	}
+	assert.True(t, g.ClearCofactor(P).Equal(want))
+
+	// a point of order 8, and the identity, are small
+	T := g.Element()
+	assert.NoError(t, T.FromBytes(mustHex("26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05")))
+	assert.True(t, g.IsSmallOrder(T))
+	assert.True(t, g.IsSmallOrder(g.Element().Identity()))
+	assert.True(t, g.ClearCofactor(T).Equal(g.Element().Identity()))
+	assert.False(t, g.IsSmallOrder(B))
+	assert.True(t, legacy.ClearCofactor(T).Equal(g.Element().Identity()))
+	assert.False(t, g.IsSmallOrder(T.Add(T, B)))
+
+	r := ristretto{}
+	assert.True(t, r.IsSmallOrder(r.Element().Identity()))
+	assert.False(t, r.IsSmallOrder(r.M()))
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,43 @@
+package ed25519
+
+// FeBatchInvert sets inv[i] = 1/z[i] for every i with a single inversion (Montgomery's trick).
+// None of z may be zero.
+func FeBatchInvert(inv, z []FieldElement) {
+	if len(z) == 0 {
+		return
+	}
+
+	// inv[i] holds the product of z[:i] until the backward pass
+	var acc, t FieldElement
+	FeOne(&acc)
+	for i := range z {
+		inv[i] = acc
+		FeMul(&acc, &acc, &z[i])
+	}
+
+	FeInvert(&acc, &acc)
+	for i := len(z) - 1; i >= 0; i-- {
+		FeMul(&t, &acc, &inv[i])
+		FeMul(&acc, &acc, &z[i])
+		inv[i] = t
+	}
+}
+
+// GeBatchToBytes sets s[i] to the encoding of p[i], as p[i].ToBytes would, sharing one inversion
+// between all points.
+func GeBatchToBytes(s [][32]byte, p []*ExtendedGroupElement) {
+	z := make([]FieldElement, len(p))
+	for i := range p {
+		z[i] = p[i].Z
+	}
+	inv := make([]FieldElement, len(p))
+	FeBatchInvert(inv, z)
+
+	var x, y FieldElement
+	for i := range p {
+		FeMul(&x, &p[i].X, &inv[i])
+		FeMul(&y, &p[i].Y, &inv[i])
+		FeToBytes(&s[i], &y)
+		s[i][31] ^= FeIsNegative(&x) << 7
+	}
+}
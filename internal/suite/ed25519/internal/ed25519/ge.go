@@ -351,14 +351,14 @@ func negative(b int32) int32 {
 	return (b >> 31) & 1
 }
 
-func selectPreComputed(t *PreComputedGroupElement, pos int32, b int32) {
+func selectPreComputed(t *PreComputedGroupElement, table *PrecomputedTable, pos int32, b int32) {
 	var minusT PreComputedGroupElement
 	bNegative := negative(b)
 	bAbs := b - (((-bNegative) & b) << 1)
 
 	t.Zero()
 	for i := int32(0); i < 8; i++ {
-		t.CMove(&base[pos][i], equal(bAbs, i+1))
+		t.CMove(&table[pos][i], equal(bAbs, i+1))
 	}
 	minusT.Neg(t)
 	t.CMove(&minusT, bNegative)
@@ -385,6 +385,12 @@ func computeScalarWindow4(s *[32]byte, w *[64]int8) {
 // Preconditions:
 //   a[31] <= 127
 func GeScalarMultBase(h *ExtendedGroupElement, a *[32]byte) {
+	GeScalarMultPrecomputed(h, a, (*PrecomputedTable)(&base))
+}
+
+// GeScalarMultPrecomputed computes h = a*P for the point P that table was built from, the same
+// way GeScalarMultBase does for B. Same preconditions.
+func GeScalarMultPrecomputed(h *ExtendedGroupElement, a *[32]byte, table *PrecomputedTable) {
 	var e [64]int8
 	computeScalarWindow4(a, &e)
 
@@ -392,7 +398,7 @@ func GeScalarMultBase(h *ExtendedGroupElement, a *[32]byte) {
 	var t PreComputedGroupElement
 	var r CompletedGroupElement
 	for i := int32(1); i < 64; i += 2 {
-		selectPreComputed(&t, i/2, int32(e[i]))
+		selectPreComputed(&t, table, i/2, int32(e[i]))
 		r.MixedAdd(h, &t)
 		r.ToExtended(h)
 	}
@@ -409,7 +415,7 @@ func GeScalarMultBase(h *ExtendedGroupElement, a *[32]byte) {
 	r.ToExtended(h)
 
 	for i := int32(0); i < 64; i += 2 {
-		selectPreComputed(&t, i/2, int32(e[i]))
+		selectPreComputed(&t, table, i/2, int32(e[i]))
 		r.MixedAdd(h, &t)
 		r.ToExtended(h)
 	}
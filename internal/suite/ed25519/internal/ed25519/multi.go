@@ -0,0 +1,119 @@
+package ed25519
+
+// PrecomputedTable holds (j+1)*256^i*P for i < 32 and j < 8, laid out like the base point
+// table, so that multiples of a fixed point P cost as much as multiples of B.
+type PrecomputedTable [32][8]PreComputedGroupElement
+
+// NewPrecomputedTable builds the table for P, with 256 additions and a single inversion shared by
+// all entries. It pays off after a handful of GeScalarMultPrecomputed calls.
+func NewPrecomputedTable(P *ExtendedGroupElement) *PrecomputedTable {
+	var points [32 * 8]ExtendedGroupElement
+	var row ExtendedGroupElement
+	var c CachedGroupElement
+	var r CompletedGroupElement
+	var s ProjectiveGroupElement
+
+	row = *P
+	for i := 0; i < 32; i++ {
+		// points[8i+j] = (j+1)*row
+		row.ToCached(&c)
+		points[8*i] = row
+		for j := 1; j < 8; j++ {
+			r.Add(&points[8*i+j-1], &c)
+			r.ToExtended(&points[8*i+j])
+		}
+
+		// row <<= 8
+		row.Double(&r)
+		for k := 0; k < 7; k++ {
+			r.ToProjective(&s)
+			s.Double(&r)
+		}
+		r.ToExtended(&row)
+	}
+
+	var z, inv [32 * 8]FieldElement
+	for k := range points {
+		z[k] = points[k].Z
+	}
+	FeBatchInvert(inv[:], z[:])
+
+	table := new(PrecomputedTable)
+	for k := range points {
+		toPreComputed(&table[k/8][k%8], &points[k], &inv[k])
+	}
+	return table
+}
+
+// toPreComputed sets out to (y+x, y-x, 2dxy) for the affine coordinates of p, given 1/Z, fully
+// reduced like the constants in base.
+func toPreComputed(out *PreComputedGroupElement, p *ExtendedGroupElement, zInv *FieldElement) {
+	var x, y FieldElement
+	FeMul(&x, &p.X, zInv)
+	FeMul(&y, &p.Y, zInv)
+
+	FeAdd(&out.yPlusX, &y, &x)
+	FeSub(&out.yMinusX, &y, &x)
+	FeMul(&out.xy2d, &x, &y)
+	FeMul(&out.xy2d, &out.xy2d, &d2)
+
+	feReduce(&out.yPlusX)
+	feReduce(&out.yMinusX)
+	feReduce(&out.xy2d)
+}
+
+func feReduce(f *FieldElement) {
+	var b [32]byte
+	FeToBytes(&b, f)
+	FeFromBytes(f, b[:])
+}
+
+// GeMultiScalarMult computes h = a[0]*A[0] + a[1]*A[1] + ... in constant time, sharing the
+// doublings between all terms (Straus' method with the signed 4-bit windows of GeScalarMult).
+// Each a[i] must satisfy a[i][31] <= 127.
+func GeMultiScalarMult(h *ExtendedGroupElement, a []*[32]byte, A []*ExtendedGroupElement) {
+	if len(a) != len(A) {
+		panic("ed25519: mismatched scalars and points")
+	}
+
+	var t CompletedGroupElement
+	var u ExtendedGroupElement
+	var r ProjectiveGroupElement
+	var c CachedGroupElement
+
+	e := make([][64]int8, len(a))
+	Ai := make([][8]CachedGroupElement, len(A)) // 1A through 8A for each point
+	for k := range A {
+		computeScalarWindow4(a[k], &e[k])
+		A[k].ToCached(&Ai[k][0])
+		for i := 0; i < 7; i++ {
+			t.Add(A[k], &Ai[k][i])
+			t.ToExtended(&u)
+			u.ToCached(&Ai[k][i+1])
+		}
+	}
+
+	u.Zero()
+	for i := 63; i >= 0; i-- {
+		if i != 63 {
+			// u <<= 4
+			u.Double(&t)
+			t.ToProjective(&r)
+			r.Double(&t)
+			t.ToProjective(&r)
+			r.Double(&t)
+			t.ToProjective(&r)
+			r.Double(&t)
+			t.ToExtended(&u)
+		}
+
+		// add the next nybble of every scalar
+		for k := range Ai {
+			selectCached(&c, &Ai[k], int32(e[k][i]))
+			t.Add(&u, &c)
+			t.ToExtended(&u)
+		}
+	}
+
+	*h = u
+}
@@ -0,0 +1,78 @@
+package ed25519
+
+import (
+	"bytes"
+	"testing"
+)
+
+func randomScalar(s *[32]byte) {
+	rnd.Read(s[:])
+	s[31] &= 127
+}
+
+func TestPrecomputedTableOfBase(t *testing.T) {
+	table := NewPrecomputedTable(&baseext)
+	if *table != base {
+		t.Fatal("table built from B differs from the constant table")
+	}
+}
+
+func TestGeMultiScalarMult(t *testing.T) {
+	var a, b [32]byte
+	var P, Q, h, want, tmp ExtendedGroupElement
+	var c CachedGroupElement
+	var r CompletedGroupElement
+	var got, exp [32]byte
+
+	for i := 0; i < 20; i++ {
+		randomScalar(&a)
+		GeScalarMultBase(&P, &a)
+		randomScalar(&a)
+		GeScalarMultBase(&Q, &a)
+		randomScalar(&a)
+		randomScalar(&b)
+
+		GeMultiScalarMult(&h, []*[32]byte{&a, &b}, []*ExtendedGroupElement{&P, &Q})
+
+		GeScalarMult(&want, &a, &P)
+		GeScalarMult(&tmp, &b, &Q)
+		tmp.ToCached(&c)
+		r.Add(&want, &c)
+		r.ToExtended(&want)
+
+		h.ToBytes(&got)
+		want.ToBytes(&exp)
+		if !bytes.Equal(got[:], exp[:]) {
+			t.Fatalf("a*P+b*Q: got %x, want %x", got, exp)
+		}
+
+		GeScalarMultPrecomputed(&h, &a, NewPrecomputedTable(&P))
+		GeScalarMult(&want, &a, &P)
+		h.ToBytes(&got)
+		want.ToBytes(&exp)
+		if !bytes.Equal(got[:], exp[:]) {
+			t.Fatalf("a*P with table: got %x, want %x", got, exp)
+		}
+	}
+}
+
+func TestGeBatchToBytes(t *testing.T) {
+	var a [32]byte
+	points := make([]*ExtendedGroupElement, 5)
+	for i := range points {
+		randomScalar(&a)
+		points[i] = new(ExtendedGroupElement)
+		GeScalarMultBase(points[i], &a)
+	}
+	points[2].Zero()
+
+	got := make([][32]byte, len(points))
+	GeBatchToBytes(got, points)
+	for i, p := range points {
+		var want [32]byte
+		p.ToBytes(&want)
+		if got[i] != want {
+			t.Fatalf("point %d: got %x, want %x", i, got[i], want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package ed25519
+
+import (
+	"crypto/subtle"
+)
+
+// ristretto255 encoding, decoding and equality (RFC 9496, section 4) over
+// ExtendedGroupElement. A ristretto255 element is a class of four curve points
+// that differ by 4-torsion, so these only make sense for points obtained from
+// RistrettoFromBytes or from arithmetic on them.
+
+// invSqrtAMinusD is 1/sqrt(a-d), where a = -1.
+var invSqrtAMinusD FieldElement
+
+func init() {
+	var one, aMinusD FieldElement
+	FeOne(&one)
+	FeNeg(&aMinusD, &d)
+	FeSub(&aMinusD, &aMinusD, &one)
+	feSqrtRatioM1(&invSqrtAMinusD, &one, &aMinusD)
+}
+
+// Returns 1 if a equals b, otherwise 0.
+func feEqualI(a, b *FieldElement) int32 {
+	var t FieldElement
+	FeSub(&t, a, b)
+	return 1 - t.IsNonZeroI()
+}
+
+// Sets out to |a|, the one of a and -a that is not negative.
+func feAbs(out, a *FieldElement) {
+	var n FieldElement
+	FeNeg(&n, a)
+	FeCopy(out, a)
+	FeCMove(out, &n, out.IsNegativeI())
+}
+
+// feSqrtRatioM1 sets out to the non-negative square root of u/v if it exists,
+// or of SQRT_M1*u/v otherwise, and returns 1 in the first case. If v is zero,
+// out is zero and it returns 0, unless u is zero as well.
+func feSqrtRatioM1(out, u, v *FieldElement) int32 {
+	var v3, v7, r, check, uNeg, uNegI, rPrime FieldElement
+
+	FeSquare(&v3, v)
+	FeMul(&v3, &v3, v) // v^3
+	FeSquare(&v7, &v3)
+	FeMul(&v7, &v7, v) // v^7
+	FeMul(&r, u, &v7)
+	fePow22523(&r, &r) // (uv^7)^((q-5)/8)
+	FeMul(&r, &r, &v3)
+	FeMul(&r, &r, u) // r = uv^3(uv^7)^((q-5)/8)
+
+	FeSquare(&check, &r)
+	FeMul(&check, &check, v)
+
+	FeNeg(&uNeg, u)
+	FeMul(&uNegI, &uNeg, &sqrtM1)
+	correct := feEqualI(&check, u)
+	flipped := feEqualI(&check, &uNeg)
+	flippedI := feEqualI(&check, &uNegI)
+
+	FeMul(&rPrime, &r, &sqrtM1)
+	FeCMove(&r, &rPrime, flipped|flippedI)
+	feAbs(out, &r)
+
+	return correct | flipped
+}
+
+// RistrettoFromBytes decodes a canonical ristretto255 encoding into p.
+func (p *ExtendedGroupElement) RistrettoFromBytes(in []byte) bool {
+	var s, ss, u1, u2, u2Sqr, v, one, t, invSqrt, denX, denY FieldElement
+	var buf [32]byte
+
+	if len(in) != 32 {
+		return false
+	}
+	FeFromBytes(&s, in)
+	FeToBytes(&buf, &s)
+	if subtle.ConstantTimeCompare(buf[:], in) != 1 || s.IsNegativeI() == 1 {
+		return false
+	}
+
+	FeOne(&one)
+	FeSquare(&ss, &s)
+	FeSub(&u1, &one, &ss) // 1 + as^2
+	FeAdd(&u2, &one, &ss) // 1 - as^2
+	FeSquare(&u2Sqr, &u2)
+
+	FeSquare(&v, &u1)
+	FeMul(&v, &v, &d)
+	FeNeg(&v, &v)
+	FeSub(&v, &v, &u2Sqr) // -(d*u1^2) - u2^2
+
+	FeMul(&t, &v, &u2Sqr)
+	wasSquare := feSqrtRatioM1(&invSqrt, &one, &t)
+
+	FeMul(&denX, &invSqrt, &u2)
+	FeMul(&denY, &invSqrt, &denX)
+	FeMul(&denY, &denY, &v)
+
+	FeAdd(&p.X, &s, &s)
+	FeMul(&p.X, &p.X, &denX)
+	feAbs(&p.X, &p.X)
+	FeMul(&p.Y, &u1, &denY)
+	FeOne(&p.Z)
+	FeMul(&p.T, &p.X, &p.Y)
+
+	return wasSquare&(1-p.T.IsNegativeI())&p.Y.IsNonZeroI() == 1
+}
+
+// RistrettoToBytes writes the canonical ristretto255 encoding of p to s.
+func (p *ExtendedGroupElement) RistrettoToBytes(s *[32]byte) {
+	var u1, u2, tmp, one, invSqrt, den1, den2, zInv, ix, iy, enchanted, x, y, denInv, yNeg, r FieldElement
+
+	FeAdd(&u1, &p.Z, &p.Y)
+	FeSub(&tmp, &p.Z, &p.Y)
+	FeMul(&u1, &u1, &tmp) // (z+y)(z-y)
+	FeMul(&u2, &p.X, &p.Y)
+
+	FeOne(&one)
+	FeSquare(&tmp, &u2)
+	FeMul(&tmp, &tmp, &u1)
+	feSqrtRatioM1(&invSqrt, &one, &tmp)
+
+	FeMul(&den1, &invSqrt, &u1)
+	FeMul(&den2, &invSqrt, &u2)
+	FeMul(&zInv, &den1, &den2)
+	FeMul(&zInv, &zInv, &p.T)
+
+	FeMul(&ix, &p.X, &sqrtM1)
+	FeMul(&iy, &p.Y, &sqrtM1)
+	FeMul(&enchanted, &den1, &invSqrtAMinusD)
+
+	FeMul(&tmp, &p.T, &zInv)
+	rotate := tmp.IsNegativeI()
+	FeCopy(&x, &p.X)
+	FeCMove(&x, &iy, rotate)
+	FeCopy(&y, &p.Y)
+	FeCMove(&y, &ix, rotate)
+	FeCopy(&denInv, &den2)
+	FeCMove(&denInv, &enchanted, rotate)
+
+	FeMul(&tmp, &x, &zInv)
+	FeNeg(&yNeg, &y)
+	FeCMove(&y, &yNeg, tmp.IsNegativeI())
+
+	FeSub(&r, &p.Z, &y)
+	FeMul(&r, &r, &denInv)
+	feAbs(&r, &r)
+	FeToBytes(s, &r)
+}
+
+// RistrettoEqual reports whether p and q encode to the same ristretto255 element.
+func (p *ExtendedGroupElement) RistrettoEqual(q *ExtendedGroupElement) bool {
+	var a, b FieldElement
+
+	FeMul(&a, &p.X, &q.Y)
+	FeMul(&b, &p.Y, &q.X)
+	xy := feEqualI(&a, &b)
+
+	FeMul(&a, &p.Y, &q.Y)
+	FeMul(&b, &p.X, &q.X)
+	yy := feEqualI(&a, &b)
+
+	return xy|yy == 1
+}
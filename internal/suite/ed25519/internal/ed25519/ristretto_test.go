@@ -0,0 +1,95 @@
+package ed25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Multiples of the generator, from RFC 9496, appendix A.1.
+var ristrettoMultiples = []string{
+	"0000000000000000000000000000000000000000000000000000000000000000",
+	"e2f2ae0a6abc4e71a884a961c500515f58e30b6aa582dd8db6a65945e08d2d76",
+	"6a493210f7499cd17fecb510ae0cea23a110e8d5b901f8acadd3095c73a3b919",
+	"94741f5d5d52755ece4f23f044ee27d5d1ea1e2bd196b462166b16152a9d0259",
+}
+
+func TestRistrettoConstants(t *testing.T) {
+	if invSqrtAMinusD.bigInt().String() != "54469307008909316920995813868745141605393597292927456921205312896311721017578" {
+		t.Errorf("INVSQRT_A_MINUS_D mismatch: %v", invSqrtAMinusD.bigInt())
+	}
+}
+
+func TestRistrettoMultiples(t *testing.T) {
+	var p ExtendedGroupElement
+	var c CachedGroupElement
+	var r CompletedGroupElement
+	var buf [32]byte
+
+	p.Zero()
+	baseext.ToCached(&c)
+	for i, want := range ristrettoMultiples {
+		p.RistrettoToBytes(&buf)
+		if hex.EncodeToString(buf[:]) != want {
+			t.Fatalf("%dB = %x, want %s", i, buf, want)
+		}
+
+		var q ExtendedGroupElement
+		if !q.RistrettoFromBytes(buf[:]) {
+			t.Fatalf("%dB does not decode", i)
+		}
+		if !q.RistrettoEqual(&p) {
+			t.Fatalf("%dB does not round trip", i)
+		}
+
+		r.Add(&p, &c)
+		r.ToExtended(&p)
+	}
+}
+
+func TestRistrettoRejects(t *testing.T) {
+	bad := []string{
+		// non-canonical field encodings
+		"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+		"0000000000000000000000000000000000000000000000000000000000000080",
+		// negative field elements
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		"01ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	}
+
+	var p ExtendedGroupElement
+	for _, s := range bad {
+		b, _ := hex.DecodeString(s)
+		if p.RistrettoFromBytes(b) {
+			t.Errorf("%s decoded", s)
+		}
+	}
+}
+
+func TestRistrettoTorsion(t *testing.T) {
+	var p, q ExtendedGroupElement
+	var c CachedGroupElement
+	var r CompletedGroupElement
+	var b1, b2 [32]byte
+
+	b, _ := hex.DecodeString(ristrettoMultiples[2])
+	if !p.RistrettoFromBytes(b) {
+		t.Fatal("2B does not decode")
+	}
+
+	// (0, -1) has order two; adding it must not change the encoding
+	FeZero(&q.X)
+	FeOne(&q.Y)
+	FeNeg(&q.Y, &q.Y)
+	FeOne(&q.Z)
+	FeZero(&q.T)
+	q.ToCached(&c)
+	r.Add(&p, &c)
+	r.ToExtended(&q)
+
+	p.RistrettoToBytes(&b1)
+	q.RistrettoToBytes(&b2)
+	if !bytes.Equal(b1[:], b2[:]) || !p.RistrettoEqual(&q) {
+		t.Error("encoding depends on the torsion component")
+	}
+}
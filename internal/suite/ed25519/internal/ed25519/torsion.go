@@ -0,0 +1,60 @@
+package ed25519
+
+// Multiplication by the cofactor, and the small-order check, which compares against the eight
+// points of the torsion subgroup.
+
+// torsion holds the affine coordinates of the points of order dividing 8, k*T8 for k < 8.
+var torsion [8]struct{ x, y FieldElement }
+
+// t8 encodes a point of order 8.
+var t8 = [32]byte{
+	0x26, 0xe8, 0x95, 0x8f, 0xc2, 0xb2, 0x27, 0xb0, 0x45, 0xc3, 0xf4, 0x89, 0xf2, 0xef, 0x98, 0xf0,
+	0xd5, 0xdf, 0xac, 0x05, 0xd3, 0xc6, 0x33, 0x39, 0xb1, 0x38, 0x02, 0x88, 0x6d, 0x53, 0xfc, 0x05,
+}
+
+func init() {
+	var T, kT ExtendedGroupElement
+	if !T.FromBytes(t8[:]) {
+		panic("ed25519: bad torsion point")
+	}
+
+	var c CachedGroupElement
+	var r CompletedGroupElement
+	var zInv FieldElement
+	T.ToCached(&c)
+	kT.Zero()
+	for k := range torsion {
+		FeInvert(&zInv, &kT.Z)
+		FeMul(&torsion[k].x, &kT.X, &zInv)
+		FeMul(&torsion[k].y, &kT.Y, &zInv)
+		r.Add(&kT, &c)
+		r.ToExtended(&kT)
+	}
+}
+
+// MulByCofactor sets p = 8a with three doublings. p may alias a.
+func (p *ExtendedGroupElement) MulByCofactor(a *ExtendedGroupElement) {
+	var r CompletedGroupElement
+	var s ProjectiveGroupElement
+
+	a.ToProjective(&s)
+	s.Double(&r)
+	r.ToProjective(&s)
+	s.Double(&r)
+	r.ToProjective(&s)
+	s.Double(&r)
+	r.ToExtended(p)
+}
+
+// IsSmallOrder reports whether 8p is the identity, in constant time, by comparing p with every
+// point of the torsion subgroup.
+func (p *ExtendedGroupElement) IsSmallOrder() bool {
+	var x, y FieldElement
+	small := int32(0)
+	for i := range torsion {
+		FeMul(&x, &torsion[i].x, &p.Z)
+		FeMul(&y, &torsion[i].y, &p.Z)
+		small |= feEqualI(&x, &p.X) & feEqualI(&y, &p.Y)
+	}
+	return small == 1
+}
@@ -0,0 +1,81 @@
+package ed25519
+
+import (
+	"testing"
+)
+
+func TestTorsionTable(t *testing.T) {
+	var P ExtendedGroupElement
+	var b [32]byte
+	seen := make(map[[32]byte]bool)
+	for k := range torsion {
+		FeCopy(&P.X, &torsion[k].x)
+		FeCopy(&P.Y, &torsion[k].y)
+		FeOne(&P.Z)
+		FeMul(&P.T, &P.X, &P.Y)
+		P.ToBytes(&b)
+		seen[b] = true
+
+		P.MulByCofactor(&P)
+		P.ToBytes(&b)
+		if b != [32]byte{1} {
+			t.Fatalf("8*(%d*T8) is not the identity", k)
+		}
+	}
+	if len(seen) != 8 {
+		t.Fatalf("%d distinct torsion points, want 8", len(seen))
+	}
+}
+
+// add sets p = a + b through the cached and completed forms.
+func add(p, a, b *ExtendedGroupElement) {
+	var c CachedGroupElement
+	var r CompletedGroupElement
+	b.ToCached(&c)
+	r.Add(a, &c)
+	r.ToExtended(p)
+}
+
+func TestMulByCofactor(t *testing.T) {
+	var a [32]byte
+	var P, sum, want ExtendedGroupElement
+	var got, exp [32]byte
+
+	for i := 0; i < 20; i++ {
+		randomScalar(&a)
+		GeScalarMultBase(&P, &a)
+
+		sum.MulByCofactor(&P)
+		GeScalarMult(&want, &[32]byte{8}, &P)
+		sum.ToBytes(&got)
+		want.ToBytes(&exp)
+		if got != exp {
+			t.Fatalf("8P: got %x, want %x", got, exp)
+		}
+	}
+}
+
+func TestIsSmallOrder(t *testing.T) {
+	var a [32]byte
+	var P, T, PT ExtendedGroupElement
+
+	T.FromBytes(t8[:])
+	for i := 0; i < 20; i++ {
+		randomScalar(&a)
+		GeScalarMultBase(&P, &a)
+		add(&PT, &P, &T)
+		if P.IsSmallOrder() || PT.IsSmallOrder() {
+			t.Fatal("point of large order reported as small")
+		}
+
+		// the same torsion point with another Z
+		FeMul(&T.X, &T.X, &P.Z)
+		FeMul(&T.Y, &T.Y, &P.Z)
+		FeMul(&T.T, &T.T, &P.Z)
+		FeMul(&T.Z, &T.Z, &P.Z)
+		add(&T, &T, &T)
+		if !T.IsSmallOrder() {
+			t.Fatal("torsion point reported as large")
+		}
+	}
+}
@@ -1,24 +1,33 @@
 package ed25519
 
 import (
-	"errors"
 	"github.com/jtejido/spake2plus/internal/suite"
 	ed "github.com/jtejido/spake2plus/internal/suite/ed25519/internal/ed25519"
+	"sync"
 )
 
 type point struct {
-	ge ed.ExtendedGroupElement
+	ge    ed.ExtendedGroupElement
+	table *ed.PrecomputedTable // multiples of ge when it's M or N; every write to ge drops it
 }
 
 func (P *point) Bytes() []byte {
+	return P.AppendBytes(make([]byte, 0, 32))
+}
+
+func (P *point) AppendBytes(dst []byte) []byte {
 	var b [32]byte
 	P.ge.ToBytes(&b)
-	return b[:]
+	return append(dst, b[:]...)
 }
 
 func (P *point) FromBytes(b []byte) error {
+	if len(b) != 32 {
+		return suite.NewError(suite.BadLength, "wrong size buffer")
+	}
+	P.table = nil
 	if !P.ge.FromBytes(b) {
-		return errors.New("invalid Ed25519 curve point")
+		return suite.NewError(suite.InvalidPoint, "invalid Ed25519 curve point")
 	}
 	return nil
 }
@@ -37,6 +46,7 @@ func (P *point) Equal(P2 suite.Element) bool {
 
 func (P *point) Identity() suite.Element {
 	P.ge.Zero()
+	P.table = nil
 	return P
 }
 
@@ -50,12 +60,14 @@ func (P *point) Add(P1, P2 suite.Element) suite.Element {
 	E2.ge.ToCached(&t2)
 	r.Add(&E1.ge, &t2)
 	r.ToExtended(&P.ge)
+	P.table = nil
 
 	return P
 }
 
 func (P *point) Negate(A suite.Element) suite.Element {
 	P.ge.Neg(&A.(*point).ge)
+	P.table = nil
 	return P
 }
 
@@ -65,9 +77,72 @@ func (P *point) ScalarMult(s suite.Scalar, A suite.Element) suite.Element {
 	copy(tmp[:], a[:])
 	if A == nil {
 		ed.GeScalarMultBase(&P.ge, &tmp)
+	} else if table := A.(*point).table; table != nil {
+		ed.GeScalarMultPrecomputed(&P.ge, &tmp, table)
 	} else {
 		ed.GeScalarMult(&P.ge, &tmp, &A.(*point).ge)
 	}
+	P.table = nil
 
 	return P
 }
+
+// multiScalarMult sets P to the sum of scalars[i]*points[i], where a nil point is the base point.
+// Points with a table are multiplied through it; the others share their doublings.
+func (P *point) multiScalarMult(scalars []suite.Scalar, points []*point) *point {
+	if len(scalars) != len(points) {
+		panic("ed25519: mismatched scalars and elements")
+	}
+
+	var sum, t ed.ExtendedGroupElement
+	var c ed.CachedGroupElement
+	var r ed.CompletedGroupElement
+	add := func() {
+		t.ToCached(&c)
+		r.Add(&sum, &c)
+		r.ToExtended(&sum)
+	}
+
+	var as []*[32]byte
+	var As []*ed.ExtendedGroupElement
+	var a [32]byte
+	sum.Zero()
+	for i, s := range scalars {
+		a = *s.(*scalar)
+		switch {
+		case points[i] == nil:
+			ed.GeScalarMultBase(&t, &a)
+			add()
+		case points[i].table != nil:
+			ed.GeScalarMultPrecomputed(&t, &a, points[i].table)
+			add()
+		default:
+			as = append(as, &[32]byte{})
+			*as[len(as)-1] = a
+			As = append(As, &points[i].ge)
+		}
+	}
+	if len(As) > 0 {
+		ed.GeMultiScalarMult(&t, as, As)
+		add()
+	}
+
+	P.ge = sum
+	P.table = nil
+	return P
+}
+
+// fixedPoint is M or N, decoded and tabulated on first use.
+type fixedPoint struct {
+	once sync.Once
+	p    point
+}
+
+func (f *fixedPoint) get(decode func() *point) *point {
+	f.once.Do(func() {
+		f.p = *decode()
+		f.p.table = ed.NewPrecomputedTable(&f.p.ge)
+	})
+	p := f.p
+	return &p
+}
@@ -0,0 +1,454 @@
+package ed25519
+
+import (
+	"bytes"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// The tests below check the Ed25519 and ristretto255 groups, and the ref10 scalar arithmetic under
+// them, against a plain math/big implementation of edwards25519 (RFC 8032, section 5.1) and
+// ristretto255 (RFC 9496, section 4), on boundary and random inputs. The reference uses affine
+// coordinates and the textbook formulas; it is slow and variable time, and only has to be
+// obviously right.
+
+var (
+	refOne    = big.NewInt(1)
+	refD      = fdiv(big.NewInt(-121665), big.NewInt(121666))
+	refSqrtM1 = new(big.Int).Exp(big.NewInt(2), new(big.Int).Rsh(new(big.Int).Sub(prime, refOne), 2), prime)
+
+	refIdentity = refPoint{big.NewInt(0), big.NewInt(1)}
+	refB        = refBasePoint()
+
+	// 1/sqrt(a-d), where a = -1
+	_, refInvSqrtAMinusD = refSqrtRatioM1(refOne, fsub(big.NewInt(-1), refD))
+)
+
+func fmod(x *big.Int) *big.Int { return x.Mod(x, prime) }
+
+func fadd(a, b *big.Int) *big.Int { return fmod(new(big.Int).Add(a, b)) }
+
+func fsub(a, b *big.Int) *big.Int { return fmod(new(big.Int).Sub(a, b)) }
+
+func fneg(a *big.Int) *big.Int { return fmod(new(big.Int).Neg(a)) }
+
+func fmul(a ...*big.Int) *big.Int {
+	r := big.NewInt(1)
+	for _, x := range a {
+		fmod(r.Mul(r, x))
+	}
+	return r
+}
+
+func fdiv(a, b *big.Int) *big.Int {
+	return fmul(a, new(big.Int).ModInverse(fmod(new(big.Int).Set(b)), prime))
+}
+
+func isNeg(x *big.Int) bool { return x.Bit(0) == 1 }
+
+func fabs(x *big.Int) *big.Int {
+	if isNeg(x) {
+		return fneg(x)
+	}
+	return x
+}
+
+// refSqrt returns a square root of a, which must be reduced, and whether there is one.
+func refSqrt(a *big.Int) (*big.Int, bool) {
+	e := new(big.Int).Add(prime, big.NewInt(3))
+	r := new(big.Int).Exp(a, e.Rsh(e, 3), prime)
+	if fmul(r, r).Cmp(a) != 0 {
+		r = fmul(r, refSqrtM1)
+	}
+	return r, fmul(r, r).Cmp(a) == 0
+}
+
+// refSqrtRatioM1 is SQRT_RATIO_M1 of RFC 9496, section 4.2, by its definition.
+func refSqrtRatioM1(u, v *big.Int) (bool, *big.Int) {
+	if v.Sign() == 0 {
+		return u.Sign() == 0, big.NewInt(0)
+	}
+	if r, ok := refSqrt(fdiv(u, v)); ok {
+		return true, fabs(r)
+	}
+	r, _ := refSqrt(fmul(refSqrtM1, fdiv(u, v)))
+	return false, fabs(r)
+}
+
+func le32(x *big.Int) []byte {
+	return reverse(x.FillBytes(make([]byte, 32)))
+}
+
+type refPoint struct {
+	x, y *big.Int
+}
+
+func refBasePoint() refPoint {
+	B, _ := refDecode(refPoint{big.NewInt(0), fdiv(big.NewInt(4), big.NewInt(5))}.encode())
+	return B
+}
+
+func (P refPoint) add(Q refPoint) refPoint {
+	dxy := fmul(refD, P.x, Q.x, P.y, Q.y)
+	x := fdiv(fadd(fmul(P.x, Q.y), fmul(P.y, Q.x)), fadd(refOne, dxy))
+	y := fdiv(fadd(fmul(P.y, Q.y), fmul(P.x, Q.x)), fsub(refOne, dxy))
+	return refPoint{x, y}
+}
+
+func (P refPoint) neg() refPoint {
+	return refPoint{fneg(P.x), P.y}
+}
+
+func (P refPoint) mul(k *big.Int) refPoint {
+	R := refIdentity
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = R.add(R)
+		if k.Bit(i) == 1 {
+			R = R.add(P)
+		}
+	}
+	return R
+}
+
+func (P refPoint) encode() []byte {
+	b := le32(P.y)
+	b[31] |= byte(P.x.Bit(0)) << 7
+	return b
+}
+
+// refDecode follows ref10 rather than RFC 8032, as FromBytes does: y is taken mod p, and x = 0
+// comes out of either sign bit.
+func refDecode(b []byte) (refPoint, bool) {
+	be := reverse(b)
+	sign := uint(be[0] >> 7)
+	be[0] &= 0x7f
+	y := fmod(new(big.Int).SetBytes(be))
+	yy := fmul(y, y)
+	x, ok := refSqrt(fdiv(fsub(yy, refOne), fadd(fmul(refD, yy), refOne)))
+	if !ok {
+		return refPoint{}, false
+	}
+	if x.Bit(0) != sign {
+		x = fneg(x)
+	}
+	return refPoint{x, y}, true
+}
+
+// ristrettoEncode is RFC 9496, section 4.3.2, for the affine point P (z = 1).
+func (P refPoint) ristrettoEncode() []byte {
+	x0, y0, t0 := P.x, P.y, fmul(P.x, P.y)
+	u1 := fmul(fadd(refOne, y0), fsub(refOne, y0))
+	u2 := fmul(x0, y0)
+	_, invsqrt := refSqrtRatioM1(refOne, fmul(u1, u2, u2))
+	den1, den2 := fmul(invsqrt, u1), fmul(invsqrt, u2)
+	zInv := fmul(den1, den2, t0)
+
+	x, y, denInv := x0, y0, den2
+	if isNeg(fmul(t0, zInv)) {
+		x, y, denInv = fmul(y0, refSqrtM1), fmul(x0, refSqrtM1), fmul(den1, refInvSqrtAMinusD)
+	}
+	if isNeg(fmul(x, zInv)) {
+		y = fneg(y)
+	}
+	return le32(fabs(fmul(denInv, fsub(refOne, y))))
+}
+
+// refRistrettoDecode is RFC 9496, section 4.3.1.
+func refRistrettoDecode(b []byte) (refPoint, bool) {
+	s := new(big.Int).SetBytes(reverse(b))
+	if s.Cmp(prime) >= 0 || isNeg(s) {
+		return refPoint{}, false
+	}
+	ss := fmul(s, s)
+	u1, u2 := fsub(refOne, ss), fadd(refOne, ss)
+	u2Sqr := fmul(u2, u2)
+	v := fsub(fneg(fmul(refD, u1, u1)), u2Sqr)
+	wasSquare, invsqrt := refSqrtRatioM1(refOne, fmul(v, u2Sqr))
+	denX := fmul(invsqrt, u2)
+	denY := fmul(invsqrt, denX, v)
+	x := fabs(fmul(big.NewInt(2), s, denX))
+	y := fmul(u1, denY)
+	if !wasSquare || isNeg(fmul(x, y)) || y.Sign() == 0 {
+		return refPoint{}, false
+	}
+	return refPoint{x, y}, true
+}
+
+// refT8 is a point of order 8.
+func refT8(t *testing.T) refPoint {
+	T, ok := refDecode(mustHex("26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05"))
+	assert.True(t, ok)
+	return T
+}
+
+// refScalars returns scalars below l: the edges, and random ones.
+func refScalars(r *rand.Rand, n int) []*big.Int {
+	ks := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(8),
+		new(big.Int).Sub(primeOrder, big.NewInt(1)),
+		new(big.Int).Sub(primeOrder, big.NewInt(2)),
+		new(big.Int).Sub(primeOrder, big.NewInt(8)),
+		new(big.Int).Rsh(primeOrder, 1),
+		new(big.Int).Sub(new(big.Int).Lsh(refOne, 252), refOne),
+	}
+	for i := 0; i < n; i++ {
+		ks = append(ks, new(big.Int).Rand(r, primeOrder))
+	}
+	return ks
+}
+
+// refPoints returns the small-order points, and points with and without a torsion component.
+func refPoints(t *testing.T, r *rand.Rand) []refPoint {
+	T := refT8(t)
+	ps := []refPoint{refIdentity, refB, refB.neg(), T, T.mul(big.NewInt(2)), T.mul(big.NewInt(4)), refB.add(T)}
+	for i := int64(1); i <= 4; i++ {
+		P := refB.mul(new(big.Int).Rand(r, primeOrder))
+		ps = append(ps, P, P.add(T.mul(big.NewInt(i))))
+	}
+	return ps
+}
+
+func libScalar(t *testing.T, k *big.Int) *scalar {
+	sc := new(scalar)
+	assert.NoError(t, sc.FromBytes(k.FillBytes(make([]byte, 32))))
+	return sc
+}
+
+func libPoint(t *testing.T, P refPoint) *point {
+	e := new(point)
+	assert.NoError(t, e.FromBytes(P.encode()))
+	return e
+}
+
+func TestReferenceScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	g := curve{}
+	refM, _ := refDecode(g.M().Bytes())
+	for _, k := range refScalars(r, 8) {
+		sc := libScalar(t, k)
+		assert.Equal(t, refB.mul(k).encode(), g.Element().ScalarMult(sc, nil).Bytes(), "%v·B", k)
+		// M has a precomputed table
+		assert.Equal(t, refM.mul(k).encode(), g.Element().ScalarMult(sc, g.M()).Bytes(), "%v·M", k)
+	}
+	for i, P := range refPoints(t, r) {
+		k := new(big.Int).Rand(r, primeOrder)
+		assert.Equal(t, P.mul(k).encode(), g.Element().ScalarMult(libScalar(t, k), libPoint(t, P)).Bytes(), "point %d", i)
+	}
+}
+
+func TestReferenceMultiScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	g := curve{}
+	refM, _ := refDecode(g.M().Bytes())
+	ps := refPoints(t, r)
+	ks := refScalars(r, 4)
+	for n := 1; n <= 4; n++ {
+		scalars := make([]suite.Scalar, n)
+		elements := make([]suite.Element, n)
+		want := refIdentity
+		for i := range scalars {
+			k := ks[r.Intn(len(ks))]
+			scalars[i] = libScalar(t, k)
+			switch i {
+			case 0:
+				want = want.add(refB.mul(k)) // nil is the base point
+			case 1:
+				elements[i] = g.M()
+				want = want.add(refM.mul(k))
+			default:
+				P := ps[r.Intn(len(ps))]
+				elements[i] = libPoint(t, P)
+				want = want.add(P.mul(k))
+			}
+		}
+		assert.Equal(t, want.encode(), g.MultiScalarMult(scalars, elements).Bytes(), "%d terms", n)
+	}
+
+	elements := make([]suite.Element, len(ps))
+	for i, P := range ps {
+		elements[i] = libPoint(t, P)
+	}
+	for i, b := range g.EncodeElements(elements) {
+		assert.Equal(t, ps[i].encode(), b, "point %d", i)
+	}
+}
+
+func TestReferenceAddNegate(t *testing.T) {
+	ps := refPoints(t, rand.New(rand.NewSource(3)))
+	for i, P := range ps {
+		assert.Equal(t, P.neg().encode(), new(point).Negate(libPoint(t, P)).Bytes(), "-point %d", i)
+		for j, Q := range ps {
+			assert.Equal(t, P.add(Q).encode(), new(point).Add(libPoint(t, P), libPoint(t, Q)).Bytes(), "point %d + point %d", i, j)
+			assert.Equal(t, i == j, libPoint(t, P).Equal(libPoint(t, Q)), "point %d == point %d", i, j)
+		}
+	}
+}
+
+func TestReferenceDecode(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	var inputs [][]byte
+	for _, P := range refPoints(t, r) {
+		b := P.encode()
+		flipped := append([]byte(nil), b...)
+		flipped[31] ^= 0x80
+		inputs = append(inputs, b, flipped)
+	}
+	for _, y := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(prime, refOne),
+		prime,
+		new(big.Int).Add(prime, refOne),
+		new(big.Int).Sub(new(big.Int).Lsh(refOne, 255), refOne),
+	} {
+		b := le32(y)
+		inputs = append(inputs, b, append(b[:31:31], b[31]|0x80))
+	}
+	for i := 0; i < 64; i++ {
+		b := make([]byte, 32)
+		r.Read(b)
+		inputs = append(inputs, b)
+	}
+
+	for _, b := range inputs {
+		P, ok := refDecode(b)
+		e := new(point)
+		err := e.FromBytes(b)
+		if assert.Equal(t, ok, err == nil, "%x", b) && ok {
+			assert.Equal(t, P.encode(), e.Bytes(), "%x", b)
+		}
+	}
+}
+
+func TestReferenceScalar(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	max := new(big.Int).Sub(new(big.Int).Lsh(refOne, 256), refOne)
+	ks := append(refScalars(r, 8), primeOrder, new(big.Int).Add(primeOrder, refOne), new(big.Int).Lsh(refOne, 253), max)
+
+	for _, k := range ks {
+		sc := new(scalar)
+		err := sc.FromBytes(k.FillBytes(make([]byte, 32)))
+		if k.Cmp(primeOrder) >= 0 {
+			assert.Error(t, err, "%v", k)
+			continue
+		}
+		assert.NoError(t, err, "%v", k)
+		assert.Equal(t, k.FillBytes(make([]byte, 32)), sc.Bytes(), "%v", k)
+
+		neg := new(big.Int).Sub(primeOrder, k)
+		assert.Equal(t, neg.Mod(neg, primeOrder).FillBytes(make([]byte, 32)), new(scalar).Negate(sc).Bytes(), "-%v", k)
+	}
+
+	// scMulAdd and Bytes take any 256-bit input, not only reduced ones
+	for _, a := range ks {
+		for _, b := range ks {
+			c := ks[r.Intn(len(ks))]
+			var sa, sb, sc, s scalar
+			copy(sa[:], le32(a))
+			copy(sb[:], le32(b))
+			copy(sc[:], le32(c))
+			scMulAdd(&s, &sa, &sb, &sc)
+
+			want := new(big.Int).Mul(a, b)
+			want.Add(want, c).Mod(want, primeOrder)
+			assert.Equal(t, le32(want), s[:], "%v·%v+%v", a, b, c)
+		}
+		var sa scalar
+		copy(sa[:], le32(a))
+		assert.Equal(t, new(big.Int).Mod(a, primeOrder).FillBytes(make([]byte, 32)), sa.Bytes(), "%v", a)
+	}
+
+	wide := [][]byte{make([]byte, 64), bytes.Repeat([]byte{0xff}, 64), append(le32(primeOrder), make([]byte, 32)...)}
+	for i := 0; i < 32; i++ {
+		b := make([]byte, 64)
+		r.Read(b)
+		wide = append(wide, b)
+	}
+	for _, b := range wide {
+		var in [64]byte
+		var s scalar
+		copy(in[:], b)
+		scReduce(&s, &in)
+
+		want := new(big.Int).SetBytes(reverse(b))
+		assert.Equal(t, le32(want.Mod(want, primeOrder)), s[:], "%x", b)
+	}
+}
+
+func TestReferenceRistretto(t *testing.T) {
+	r := rand.New(rand.NewSource(6))
+	g := ristretto{}
+	T4 := refT8(t).mul(big.NewInt(2))
+
+	// the reference encoding doesn't depend on the representative, and neither does the group's
+	var multiples []refPoint
+	for i, k := range refScalars(r, 8) {
+		P := refB.mul(k)
+		multiples = append(multiples, P)
+		want := P.ristrettoEncode()
+		assert.Equal(t, want, g.Element().ScalarMult(libScalar(t, k), nil).Bytes(), "%v·B", k)
+
+		Q := P.add(T4.mul(big.NewInt(int64(i % 4))))
+		assert.Equal(t, want, Q.ristrettoEncode(), "%v·B + T", k)
+		assert.Equal(t, want, (&ristrettoPoint{*libPoint(t, Q)}).Bytes(), "%v·B + T", k)
+	}
+
+	for i, P := range multiples {
+		lp := g.Element()
+		assert.NoError(t, lp.FromBytes(P.ristrettoEncode()))
+		assert.Equal(t, P.neg().ristrettoEncode(), g.Element().Negate(lp).Bytes(), "-multiple %d", i)
+		for j, Q := range multiples {
+			lq := g.Element()
+			assert.NoError(t, lq.FromBytes(Q.ristrettoEncode()))
+			assert.Equal(t, P.add(Q).ristrettoEncode(), g.Element().Add(lp, lq).Bytes(), "multiple %d + multiple %d", i, j)
+		}
+	}
+
+	var inputs [][]byte
+	for _, P := range multiples {
+		b := P.ristrettoEncode()
+		flipped := append([]byte(nil), b...)
+		flipped[0] ^= 1
+		inputs = append(inputs, b, flipped)
+	}
+	for _, s := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(prime, refOne),
+		new(big.Int).Sub(prime, big.NewInt(2)),
+		prime,
+		new(big.Int).Add(prime, refOne),
+		new(big.Int).Sub(new(big.Int).Lsh(refOne, 255), refOne),
+	} {
+		inputs = append(inputs, le32(s))
+	}
+	for i := 0; i < 64; i++ {
+		b := make([]byte, 32)
+		r.Read(b)
+		// a random string is rarely a valid encoding, so half of them are made non-negative and
+		// below 2^254
+		if i%2 == 0 {
+			b[0] &^= 1
+			b[31] &= 0x3f
+		}
+		inputs = append(inputs, b)
+	}
+
+	for _, b := range inputs {
+		P, ok := refRistrettoDecode(b)
+		e := g.Element().(*ristrettoPoint)
+		err := e.FromBytes(b)
+		if assert.Equal(t, ok, err == nil, "%x", b) && ok {
+			assert.Equal(t, P.encode(), e.point.Bytes(), "%x", b)
+			assert.Equal(t, b, e.Bytes(), "%x", b)
+		}
+	}
+}
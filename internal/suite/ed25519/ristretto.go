@@ -0,0 +1,175 @@
+package ed25519
+
+import (
+	"encoding/hex"
+	"github.com/jtejido/spake2plus/internal/suite"
+	ed "github.com/jtejido/spake2plus/internal/suite/ed25519/internal/ed25519"
+	"io"
+	"math/big"
+)
+
+// M and N are the first valid ristretto255 encodings among SHA-512(seed || counter)[:32], for an
+// 8-bit counter starting at zero, with the seeds "ristretto255 point generation seed (M)" and
+// "ristretto255 point generation seed (N)". Nobody knows their discrete logarithms.
+const (
+	ristrettoM = "ecb5bd3c55468d03ba4eeca92680a8f402caeec31046695ec8d97f1e2eda714b"
+	ristrettoN = "4a8e831da4a70cc37c16abac972731dae2c874618c3e097338fad1a29b9a3c10"
+)
+
+// ristretto is the prime-order group ristretto255 (RFC 9496), built on the Ed25519 curve arithmetic.
+// It shares the Ed25519 scalars and generator.
+type ristretto struct {
+}
+
+func (c ristretto) String() string {
+	return "ristretto255"
+}
+
+func (c ristretto) Scalar() suite.Scalar {
+	return &scalar{}
+}
+
+func (c ristretto) Element() suite.Element {
+	P := new(ristrettoPoint)
+	P.ge.Zero()
+	return P
+}
+
+func (c ristretto) fromHex(s string) suite.Element {
+	str, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	point := c.Element()
+	if err := point.FromBytes(str); err != nil {
+		panic(err)
+	}
+
+	return point
+}
+
+var fixedRistrettoM, fixedRistrettoN fixedPoint
+
+func (c ristretto) fixed(f *fixedPoint, s string) suite.Element {
+	p := f.get(func() *point {
+		return &c.fromHex(s).(*ristrettoPoint).point
+	})
+	return &ristrettoPoint{*p}
+}
+
+func (c ristretto) M() suite.Element {
+	return c.fixed(&fixedRistrettoM, ristrettoM)
+}
+
+func (c ristretto) N() suite.Element {
+	return c.fixed(&fixedRistrettoN, ristrettoN)
+}
+
+func (c ristretto) MultiScalarMult(scalars []suite.Scalar, elements []suite.Element) suite.Element {
+	points := make([]*point, len(elements))
+	for i, e := range elements {
+		if e != nil {
+			points[i] = &e.(*ristrettoPoint).point
+		}
+	}
+	P := new(ristrettoPoint)
+	P.multiScalarMult(scalars, points)
+	return P
+}
+
+func (c ristretto) RandomElement(r io.Reader) (suite.Element, error) {
+	sc, err := c.RandomScalar(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.Element().ScalarMult(sc, nil), nil
+}
+
+func (c ristretto) RandomScalar(r io.Reader) (suite.Scalar, error) {
+	return curve{}.RandomScalar(r)
+}
+
+func (c ristretto) ScalarLen() int {
+	return 32
+}
+
+func (c ristretto) ElementLen() int {
+	return 32
+}
+
+func (c ristretto) Order() *big.Int {
+	return primeOrder
+}
+
+// The group has prime order, so the cofactor is one.
+func (c ristretto) CofactorScalar() suite.Scalar {
+	sc := scOne
+	return &sc
+}
+
+func (c ristretto) ClearCofactor(elem suite.Element) suite.Element {
+	return elem
+}
+
+// IsSmallOrder reports whether elem is the identity, the only element of small order.
+func (c ristretto) IsSmallOrder(elem suite.Element) bool {
+	var identity ed.ExtendedGroupElement
+	identity.Zero()
+	return elem.(*ristrettoPoint).ge.RistrettoEqual(&identity)
+}
+
+// ristrettoPoint is an Ed25519 point that stands for its ristretto255 class. Only encoding and
+// equality differ; the curve arithmetic is shared with point.
+type ristrettoPoint struct {
+	point
+}
+
+func (P *ristrettoPoint) Bytes() []byte {
+	return P.AppendBytes(make([]byte, 0, 32))
+}
+
+func (P *ristrettoPoint) AppendBytes(dst []byte) []byte {
+	var b [32]byte
+	P.ge.RistrettoToBytes(&b)
+	return append(dst, b[:]...)
+}
+
+func (P *ristrettoPoint) FromBytes(b []byte) error {
+	if len(b) != 32 {
+		return suite.NewError(suite.BadLength, "wrong size buffer")
+	}
+	P.table = nil
+	if !P.ge.RistrettoFromBytes(b) {
+		return suite.NewError(suite.InvalidPoint, "invalid ristretto255 encoding")
+	}
+	return nil
+}
+
+func (P *ristrettoPoint) Equal(P2 suite.Element) bool {
+	return P.ge.RistrettoEqual(&P2.(*ristrettoPoint).ge)
+}
+
+func (P *ristrettoPoint) Identity() suite.Element {
+	P.ge.Zero()
+	P.table = nil
+	return P
+}
+
+func (P *ristrettoPoint) Add(P1, P2 suite.Element) suite.Element {
+	P.point.Add(&P1.(*ristrettoPoint).point, &P2.(*ristrettoPoint).point)
+	return P
+}
+
+func (P *ristrettoPoint) Negate(A suite.Element) suite.Element {
+	P.point.Negate(&A.(*ristrettoPoint).point)
+	return P
+}
+
+func (P *ristrettoPoint) ScalarMult(s suite.Scalar, A suite.Element) suite.Element {
+	if A == nil {
+		P.point.ScalarMult(s, nil)
+	} else {
+		P.point.ScalarMult(s, &A.(*ristrettoPoint).point)
+	}
+	return P
+}
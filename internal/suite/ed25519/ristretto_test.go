@@ -0,0 +1,35 @@
+package ed25519
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRistrettoMN(t *testing.T) {
+	g := ristretto{}
+	for _, c := range []struct{ name, want string }{{"M", ristrettoM}, {"N", ristrettoN}} {
+		seed := []byte("ristretto255 point generation seed (" + c.name + ")")
+		for ctr := 0; ctr < 256; ctr++ {
+			h := sha512.Sum512(append(seed, byte(ctr)))
+			if g.Element().FromBytes(h[:32]) == nil {
+				assert.Equal(t, c.want, hex.EncodeToString(h[:32]))
+				break
+			}
+		}
+	}
+}
+
+func TestRistrettoGroup(t *testing.T) {
+	g := ristretto{}
+	B := g.Element().ScalarMult(g.CofactorScalar(), nil)
+	assert.Equal(t, "e2f2ae0a6abc4e71a884a961c500515f58e30b6aa582dd8db6a65945e08d2d76", hex.EncodeToString(B.Bytes()))
+
+	// B - B is the identity, which encodes to zero
+	P := g.Element().Negate(B)
+	P.Add(P, B)
+	assert.True(t, P.Equal(g.Element().Identity()))
+	assert.Equal(t, make([]byte, 32), P.Bytes())
+	assert.True(t, g.ClearCofactor(P).Equal(g.Element().Identity()))
+}
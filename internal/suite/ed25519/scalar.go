@@ -6,7 +6,6 @@ package ed25519
 
 import (
 	"encoding/binary"
-	"errors"
 	"github.com/jtejido/spake2plus/internal/suite"
 )
 
@@ -49,27 +48,25 @@ func (s *scalar) reduce() *scalar {
 func (s *scalar) Bytes() []byte {
 	t := *s
 	t.reduce()
-	var b [32]byte
-	copy(b[:], t[:])
 
-	return reverse(b[:])
+	return reverse(t[:])
 }
 
 // spits big endian
 func (s *scalar) FromBytes(buf []byte) error {
 	if len(buf) != 32 {
-		return errors.New("wrong size buffer")
+		return suite.NewError(suite.BadLength, "wrong size buffer")
 	}
 
-	b := make([]byte, 32)
-
-	copy(b, buf)
-	b = reverse(b)
-	if !scMinimal(b) {
-		return errors.New("invalid scalar encoding")
+	var b [32]byte
+	for i := range b {
+		b[i] = buf[31-i]
+	}
+	if !scMinimal(b[:]) {
+		return suite.NewError(suite.InvalidScalar, "invalid scalar encoding")
 	}
 
-	copy(s[:], b)
+	*s = b
 	return nil
 }
 
@@ -78,6 +75,10 @@ func (s *scalar) Negate(a suite.Scalar) suite.Scalar {
 	return s
 }
 
+func (s *scalar) Zero() {
+	*s = scZero
+}
+
 // Input:
 //   a[0]+256*a[1]+...+256^31*a[31] = a
 //   b[0]+256*b[1]+...+256^31*b[31] = b
@@ -0,0 +1,11 @@
+package ed25519
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// NewRistretto255Blake2bHkdfBlake2b is ristretto255 with BLAKE2b-512, HKDF-BLAKE2b-512 and keyed
+// BLAKE2b-512 as MAC.
+func NewRistretto255Blake2bHkdfBlake2b(mhf suite.MHF) *suite.Suite {
+	return suite.New(ristretto{}, suite.BLAKE2b512, suite.HKDF(suite.BLAKE2b512), suite.BLAKE2bMAC, mhf)
+}
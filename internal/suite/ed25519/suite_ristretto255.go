@@ -0,0 +1,9 @@
+package ed25519
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+func NewRistretto255Sha512HkdfHmac(mhf suite.MHF) *suite.Suite {
+	return suite.NewHkdfHmac(ristretto{}, suite.SHA512, mhf)
+}
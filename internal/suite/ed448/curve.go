@@ -5,7 +5,9 @@ import (
 	"encoding/hex"
 	"github.com/cloudflare/circl/ecc/goldilocks"
 	"github.com/jtejido/spake2plus/internal/suite"
+	"io"
 	"math/big"
+	"sync"
 )
 
 var cofactor = new(big.Int).SetInt64(4)
@@ -30,40 +32,68 @@ func (c curve) Element() suite.Element {
 	return P
 }
 
-func (c curve) M() suite.Element {
-	pointString, err := hex.DecodeString("b6221038a775ecd007a4e4dde39fd76ae91d3cf0cc92be8f0c2fa6d6b66f9a12942f5a92646109152292464f3e63d354701c7848d9fc3b8880")
+// fixedPoint is M or N, decoded on first use. get returns a copy, as points are mutable.
+type fixedPoint struct {
+	once sync.Once
+	p    goldilocks.Point
+}
+
+func (f *fixedPoint) get(decode func() *goldilocks.Point) *goldilocks.Point {
+	f.once.Do(func() {
+		f.p = *decode()
+	})
+	p := f.p
+	return &p
+}
+
+var fixedM, fixedN fixedPoint
+
+func (c curve) fromHex(s string) suite.Element {
+	str, err := hex.DecodeString(s)
 	if err != nil {
 		panic(err)
 	}
 	point := c.Element()
-	if err := point.FromBytes(pointString); err != nil {
+	if err := point.FromBytes(str); err != nil {
 		panic(err)
 	}
 
 	return point
 }
 
+func (c curve) M() suite.Element {
+	return &point{fixedM.get(func() *goldilocks.Point {
+		return c.fromHex("b6221038a775ecd007a4e4dde39fd76ae91d3cf0cc92be8f0c2fa6d6b66f9a12942f5a92646109152292464f3e63d354701c7848d9fc3b8880").(*point).p
+	})}
+}
+
 func (c curve) N() suite.Element {
-	pointString, err := hex.DecodeString("6034c65b66e4cd7a49b0edec3e3c9ccc4588afd8cf324e29f0a84a072531c4dbf97ff9af195ed714a689251f08f8e06e2d1f24a0ffc0146600")
-	if err != nil {
-		panic(err)
-	}
-	point := c.Element()
-	if err := point.FromBytes(pointString); err != nil {
-		panic(err)
-	}
+	return &point{fixedN.get(func() *goldilocks.Point {
+		return c.fromHex("6034c65b66e4cd7a49b0edec3e3c9ccc4588afd8cf324e29f0a84a072531c4dbf97ff9af195ed714a689251f08f8e06e2d1f24a0ffc0146600").(*point).p
+	})}
+}
 
-	return point
+// circl only combines multiplications in variable time, which won't do for secret scalars, so the
+// terms are computed separately.
+func (c curve) MultiScalarMult(scalars []suite.Scalar, elements []suite.Element) suite.Element {
+	return suite.MultiScalarMultSeparately(c, scalars, elements)
 }
 
-func (c curve) RandomElement() (suite.Element, error) {
-	sc, err := c.RandomScalar()
-	return c.Element().ScalarMult(sc, nil), err
+func (c curve) RandomElement(r io.Reader) (suite.Element, error) {
+	sc, err := c.RandomScalar(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.Element().ScalarMult(sc, nil), nil
 }
 
-func (c curve) RandomScalar() (suite.Scalar, error) {
+func (c curve) RandomScalar(r io.Reader) (suite.Scalar, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+
 	b := make([]byte, 56)
-	_, err := rand.Read(b)
+	_, err := io.ReadFull(r, b)
 	if err != nil {
 		return nil, err
 	}
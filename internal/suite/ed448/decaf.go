@@ -0,0 +1,286 @@
+package ed448
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/cloudflare/circl/ecc/goldilocks"
+	fp "github.com/cloudflare/circl/math/fp448"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"io"
+	"math/big"
+)
+
+// M and N are the first valid decaf448 encodings among SHA-512(seed || counter)[:56], for an
+// 8-bit counter starting at zero, with the seeds "decaf448 point generation seed (M)" and
+// "decaf448 point generation seed (N)". Nobody knows their discrete logarithms.
+const (
+	decafM = "1cd8ea81cfa043f8df1bfeda0a723ac4485edea4da386b159933d199d45396daa2cdda100298b91a19a052f9b4d626e56cbca7f9aac5a302"
+	decafN = "d0802003a948a68edce829c0a876670d2dae8a998e7604910e49f3040a11dd857e68e344607205c6753574538f0c446708ea37dc12fbaa60"
+)
+
+var (
+	decafD         fp.Elt // -39081
+	decafOneMinusD = fp.Elt{0xaa, 0x98}
+	sqrtMinusD     fp.Elt
+	invSqrtMinusD  fp.Elt
+)
+
+func init() {
+	minusD := fp.Elt{0xa9, 0x98}
+	fp.Neg(&decafD, &minusD)
+
+	// sqrt(-d) = -d/sqrt(-d), and both are non-negative
+	sqrtRatio(&invSqrtMinusD, &minusD)
+	fp.Mul(&sqrtMinusD, &invSqrtMinusD, &minusD)
+	fp.Modp(&sqrtMinusD)
+}
+
+func isNegative(x *fp.Elt) uint {
+	t := *x
+	fp.Modp(&t)
+	return uint(t[0] & 1)
+}
+
+// ctAbs sets z to the one of x and -x that is not negative.
+func ctAbs(z, x *fp.Elt) {
+	var n fp.Elt
+	fp.Neg(&n, x)
+	t := *x
+	fp.Cmov(&t, &n, isNegative(&t))
+	fp.Modp(&t)
+	*z = t
+}
+
+// sqrtRatio sets z to the non-negative square root of 1/v and reports whether v is a square. It is
+// SQRT_RATIO_M1(1, v) of RFC 9496: the non-square case only differs in the sign, which ctAbs drops.
+func sqrtRatio(z, v *fp.Elt) bool {
+	one := fp.One()
+	isQR := fp.InvSqrt(z, &one, v)
+	ctAbs(z, z)
+	return isQR
+}
+
+// decafEncode is the encoding of RFC 9496, section 5.3.2, for an affine point (z = 1).
+func decafEncode(x, y *fp.Elt) []byte {
+	var t, u1, tmp, invSqrt, ratio, u2, s fp.Elt
+
+	fp.Mul(&t, x, y)
+	fp.Add(&u1, x, &t)
+	fp.Sub(&tmp, x, &t)
+	fp.Mul(&u1, &u1, &tmp) // (x+t)(x-t)
+
+	fp.Sqr(&tmp, x)
+	fp.Mul(&tmp, &tmp, &decafOneMinusD)
+	fp.Mul(&tmp, &tmp, &u1)
+	sqrtRatio(&invSqrt, &tmp)
+
+	fp.Mul(&ratio, &invSqrt, &u1)
+	fp.Mul(&ratio, &ratio, &sqrtMinusD)
+	ctAbs(&ratio, &ratio)
+
+	fp.Mul(&u2, &invSqrtMinusD, &ratio)
+	fp.Sub(&u2, &u2, &t)
+
+	fp.Mul(&s, &decafOneMinusD, &invSqrt)
+	fp.Mul(&s, &s, x)
+	fp.Mul(&s, &s, &u2)
+	ctAbs(&s, &s)
+
+	out := make([]byte, fp.Size)
+	if err := fp.ToBytes(out, &s); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// decafDecode is the decoding of RFC 9496, section 5.3.1. It returns affine coordinates.
+func decafDecode(in []byte) (x, y fp.Elt, ok bool) {
+	var s, ss, u1, u2, tmp, invSqrt, u3 fp.Elt
+
+	p := fp.P()
+	copy(s[:], in)
+	if !isLessThan(s[:], p[:]) || isNegative(&s) == 1 {
+		return x, y, false
+	}
+
+	fp.Sqr(&ss, &s)
+	one := fp.One()
+	fp.Add(&u1, &one, &ss)
+	fp.Sqr(&u2, &u1)
+	fp.Mul(&tmp, &decafD, &ss)
+	fp.Add(&tmp, &tmp, &tmp)
+	fp.Add(&tmp, &tmp, &tmp)
+	fp.Sub(&u2, &u2, &tmp) // u1^2 - 4*d*s^2
+
+	fp.Sqr(&tmp, &u1)
+	fp.Mul(&tmp, &tmp, &u2)
+	wasSquare := sqrtRatio(&invSqrt, &tmp)
+
+	fp.Add(&u3, &s, &s)
+	fp.Mul(&u3, &u3, &invSqrt)
+	fp.Mul(&u3, &u3, &u1)
+	fp.Mul(&u3, &u3, &sqrtMinusD)
+	ctAbs(&u3, &u3)
+
+	fp.Mul(&x, &u3, &invSqrt)
+	fp.Mul(&x, &x, &u2)
+	fp.Mul(&x, &x, &invSqrtMinusD)
+
+	fp.Sub(&y, &one, &ss)
+	fp.Mul(&y, &y, &invSqrt)
+	fp.Mul(&y, &y, &u1)
+
+	return x, y, wasSquare
+}
+
+// isLessThan returns true if x < y, both little-endian and of the same length.
+func isLessThan(x, y []byte) bool {
+	i := len(x) - 1
+	for i > 0 && x[i] == y[i] {
+		i--
+	}
+	return x[i] < y[i]
+}
+
+// decaf is the prime-order group decaf448 (RFC 9496), built on the Ed448-Goldilocks curve
+// arithmetic. It shares the Ed448 scalars; its generator is twice the Ed448 base point.
+type decaf struct {
+	goldilocks.Curve
+}
+
+func (c decaf) String() string {
+	return "decaf448"
+}
+
+func (c decaf) Scalar() suite.Scalar {
+	return &scalar{&goldilocks.Scalar{}}
+}
+
+func (c decaf) Element() suite.Element {
+	return &decafPoint{c.Curve.Identity()}
+}
+
+func (c decaf) fromHex(s string) suite.Element {
+	str, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	point := c.Element()
+	if err := point.FromBytes(str); err != nil {
+		panic(err)
+	}
+
+	return point
+}
+
+var fixedDecafM, fixedDecafN fixedPoint
+
+func (c decaf) M() suite.Element {
+	return &decafPoint{fixedDecafM.get(func() *goldilocks.Point { return c.fromHex(decafM).(*decafPoint).p })}
+}
+
+func (c decaf) N() suite.Element {
+	return &decafPoint{fixedDecafN.get(func() *goldilocks.Point { return c.fromHex(decafN).(*decafPoint).p })}
+}
+
+func (c decaf) MultiScalarMult(scalars []suite.Scalar, elements []suite.Element) suite.Element {
+	return suite.MultiScalarMultSeparately(c, scalars, elements)
+}
+
+func (c decaf) RandomElement(r io.Reader) (suite.Element, error) {
+	sc, err := c.RandomScalar(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.Element().ScalarMult(sc, nil), nil
+}
+
+func (c decaf) RandomScalar(r io.Reader) (suite.Scalar, error) {
+	return curve{}.RandomScalar(r)
+}
+
+func (c decaf) ScalarLen() int {
+	return goldilocks.ScalarSize
+}
+
+func (c decaf) ElementLen() int {
+	return fp.Size
+}
+
+func (c decaf) Order() *big.Int {
+	return primeOrder
+}
+
+// The group has prime order, so the cofactor is one.
+func (c decaf) CofactorScalar() suite.Scalar {
+	sc := c.Scalar().(*scalar)
+	sc.v[0] = 1
+	return sc
+}
+
+func (c decaf) ClearCofactor(elem suite.Element) suite.Element {
+	return elem
+}
+
+// decafPoint is an Ed448 point that stands for its decaf448 class.
+type decafPoint struct {
+	p *goldilocks.Point
+}
+
+func (P *decafPoint) Bytes() []byte {
+	q := *P.p
+	x, y := q.ToAffine()
+	return decafEncode(&x, &y)
+}
+
+func (P *decafPoint) FromBytes(b []byte) error {
+	if len(b) != fp.Size {
+		return suite.NewError(suite.BadLength, "wrong size buffer")
+	}
+	x, y, ok := decafDecode(b)
+	if !ok {
+		return suite.NewError(suite.InvalidPoint, "invalid decaf448 encoding")
+	}
+	p, err := goldilocks.FromAffine(&x, &y)
+	if err != nil {
+		return suite.NewError(suite.InvalidPoint, "invalid decaf448 encoding")
+	}
+	P.p = p
+	return nil
+}
+
+// Equal compares encodings, as two points of the same class may differ by 4-torsion.
+func (P *decafPoint) Equal(P2 suite.Element) bool {
+	return subtle.ConstantTimeCompare(P.Bytes(), P2.Bytes()) == 1
+}
+
+func (P *decafPoint) Identity() suite.Element {
+	P.p = goldilocks.Curve{}.Identity()
+	return P
+}
+
+func (P *decafPoint) Add(P1, P2 suite.Element) suite.Element {
+	q := *P1.(*decafPoint).p
+	q.Add(P2.(*decafPoint).p)
+	P.p = &q
+	return P
+}
+
+func (P *decafPoint) Negate(A suite.Element) suite.Element {
+	q := *A.(*decafPoint).p
+	q.Neg()
+	P.p = &q
+	return P
+}
+
+func (P *decafPoint) ScalarMult(s suite.Scalar, A suite.Element) suite.Element {
+	c := goldilocks.Curve{}
+	if A != nil {
+		P.p = c.ScalarMult(s.(*scalar).v, A.(*decafPoint).p)
+	} else {
+		P.p = c.ScalarBaseMult(s.(*scalar).v)
+		P.p.Double()
+	}
+
+	return P
+}
@@ -0,0 +1,60 @@
+package ed448
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecafMultiples(t *testing.T) {
+	// RFC 9496, appendix A.2
+	multiples := []string{
+		"0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		"6666666666666666666666666666666666666666666666666666666633333333333333333333333333333333333333333333333333333333",
+		"c898eb4f87f97c564c6fd61fc7e49689314a1f818ec85eeb3bd5514ac816d38778f69ef347a89fca817e66defdedce178c7cc709b2116e75",
+		"a0c09bf2ba7208fda0f4bfe3d0f5b29a543012306d43831b5adc6fe7f8596fa308763db15468323b11cf6e4aeb8c18fe44678f44545a69bc",
+	}
+
+	g := decaf{}
+	one := g.CofactorScalar()
+	B := g.Element().ScalarMult(one, nil)
+	P := g.Element()
+	for _, want := range multiples {
+		assert.Equal(t, want, hex.EncodeToString(P.Bytes()))
+
+		Q := g.Element()
+		assert.NoError(t, Q.FromBytes(P.Bytes()))
+		assert.True(t, Q.Equal(P))
+
+		P.Add(P, B)
+	}
+}
+
+func TestDecafRejects(t *testing.T) {
+	bad := []string{
+		// non-canonical
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		// negative
+		"0100000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+	}
+	for _, s := range bad {
+		b, _ := hex.DecodeString(s)
+		assert.Error(t, decaf{}.Element().FromBytes(b))
+	}
+	assert.Error(t, decaf{}.Element().FromBytes(make([]byte, 57)))
+}
+
+func TestDecafMN(t *testing.T) {
+	g := decaf{}
+	for _, c := range []struct{ name, want string }{{"M", decafM}, {"N", decafN}} {
+		seed := []byte("decaf448 point generation seed (" + c.name + ")")
+		for ctr := 0; ctr < 256; ctr++ {
+			h := sha512.Sum512(append(seed, byte(ctr)))
+			if g.Element().FromBytes(h[:56]) == nil {
+				assert.Equal(t, c.want, hex.EncodeToString(h[:56]))
+				break
+			}
+		}
+	}
+}
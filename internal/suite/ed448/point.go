@@ -17,9 +17,20 @@ func (P *point) Bytes() []byte {
 	return b
 }
 
-func (P *point) FromBytes(b []byte) (err error) {
-	P.p, err = goldilocks.FromBytes(b)
-	return
+func (P *point) FromBytes(b []byte) error {
+	if len(b) != 57 {
+		return suite.NewError(suite.BadLength, "wrong size buffer")
+	}
+	// goldilocks ignores the rest of the last byte, which RFC 8032 counts as bits of y
+	if b[56]&0x7f != 0 {
+		return suite.NewError(suite.InvalidPoint, "invalid Ed448 curve point")
+	}
+	p, err := goldilocks.FromBytes(b)
+	if err != nil {
+		return suite.NewError(suite.InvalidPoint, "invalid Ed448 curve point")
+	}
+	P.p = p
+	return nil
 }
 
 func (P *point) Equal(P2 suite.Element) bool {
@@ -33,20 +44,17 @@ func (P *point) Identity() suite.Element {
 }
 
 func (P *point) Add(P1, P2 suite.Element) suite.Element {
-	E1 := P1.(*point)
-	E2 := P2.(*point)
-	tmp := *E1
-	tmp.p.Add(E2.p)
-	P.p = tmp.p
+	q := *P1.(*point).p
+	q.Add(P2.(*point).p)
+	P.p = &q
 
 	return P
 }
 
 func (P *point) Negate(A suite.Element) suite.Element {
-	a := A.(*point)
-	tmp := *a
-	tmp.p.Neg()
-	P.p = tmp.p
+	q := *A.(*point).p
+	q.Neg()
+	P.p = &q
 	return P
 }
 
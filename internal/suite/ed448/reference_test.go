@@ -0,0 +1,407 @@
+package ed448
+
+import (
+	"bytes"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// The tests below check the Ed448 and decaf448 groups, that is the goldilocks wrapper and the
+// decaf448 encoding, against a plain math/big implementation of edwards448 (RFC 8032, section
+// 5.2) and decaf448 (RFC 9496, section 5), on boundary and random inputs. The reference uses
+// affine coordinates and the textbook formulas; it is slow and variable time, and only has to be
+// obviously right.
+
+var (
+	refOne = big.NewInt(1)
+	refD   = fmod(big.NewInt(-39081))
+
+	refIdentity = refPoint{big.NewInt(0), big.NewInt(1)}
+	refB        = refBasePoint()
+
+	// decaf448 constants, RFC 9496, section 5.1
+	refOneMinusD      = fsub(refOne, refD)
+	_, refSqrtMinusD  = refSqrtRatio(fneg(refD), refOne)
+	_, refInvSqrtMinD = refSqrtRatio(refOne, fneg(refD))
+)
+
+func fmod(x *big.Int) *big.Int { return x.Mod(x, prime) }
+
+func fadd(a, b *big.Int) *big.Int { return fmod(new(big.Int).Add(a, b)) }
+
+func fsub(a, b *big.Int) *big.Int { return fmod(new(big.Int).Sub(a, b)) }
+
+func fneg(a *big.Int) *big.Int { return fmod(new(big.Int).Neg(a)) }
+
+func fmul(a ...*big.Int) *big.Int {
+	r := big.NewInt(1)
+	for _, x := range a {
+		fmod(r.Mul(r, x))
+	}
+	return r
+}
+
+func fdiv(a, b *big.Int) *big.Int {
+	return fmul(a, new(big.Int).ModInverse(fmod(new(big.Int).Set(b)), prime))
+}
+
+func isNeg(x *big.Int) bool { return x.Bit(0) == 1 }
+
+func fabs(x *big.Int) *big.Int {
+	if isNeg(x) {
+		return fneg(x)
+	}
+	return x
+}
+
+// refSqrt returns a square root of a, which must be reduced, and whether there is one. p is 3
+// mod 4.
+func refSqrt(a *big.Int) (*big.Int, bool) {
+	e := new(big.Int).Add(prime, refOne)
+	r := new(big.Int).Exp(a, e.Rsh(e, 2), prime)
+	return r, fmul(r, r).Cmp(a) == 0
+}
+
+// refSqrtRatio is SQRT_RATIO_M1 of RFC 9496, section 5.2, by its definition.
+func refSqrtRatio(u, v *big.Int) (bool, *big.Int) {
+	if v.Sign() == 0 {
+		return u.Sign() == 0, big.NewInt(0)
+	}
+	if r, ok := refSqrt(fdiv(u, v)); ok {
+		return true, fabs(r)
+	}
+	r, _ := refSqrt(fneg(fdiv(u, v)))
+	return false, fabs(r)
+}
+
+func le(x *big.Int, n int) []byte {
+	return reverse(x.FillBytes(make([]byte, n)))
+}
+
+type refPoint struct {
+	x, y *big.Int
+}
+
+// refBasePoint returns B of RFC 8032, section 5.2, from its y and the sign of its x.
+func refBasePoint() refPoint {
+	y, _ := new(big.Int).SetString("298819210078481492676017930443930673437544040154080242095928241372331506189835876003536878655418784733982303233503462500531545062832660", 10)
+	B, _ := refDecode(refPoint{big.NewInt(0), y}.encode())
+	return B
+}
+
+func (P refPoint) add(Q refPoint) refPoint {
+	dxy := fmul(refD, P.x, Q.x, P.y, Q.y)
+	x := fdiv(fadd(fmul(P.x, Q.y), fmul(P.y, Q.x)), fadd(refOne, dxy))
+	y := fdiv(fsub(fmul(P.y, Q.y), fmul(P.x, Q.x)), fsub(refOne, dxy))
+	return refPoint{x, y}
+}
+
+func (P refPoint) equal(Q refPoint) bool {
+	return P.x.Cmp(Q.x) == 0 && P.y.Cmp(Q.y) == 0
+}
+
+func (P refPoint) neg() refPoint {
+	return refPoint{fneg(P.x), P.y}
+}
+
+func (P refPoint) mul(k *big.Int) refPoint {
+	R := refIdentity
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = R.add(R)
+		if k.Bit(i) == 1 {
+			R = R.add(P)
+		}
+	}
+	return R
+}
+
+// mulPrime is k·P as circl computes it, through the 4-isogenous twist: (k/4)·4P. It is k·P in the
+// prime-order subgroup, and drops the torsion component of other points.
+func (P refPoint) mulPrime(k *big.Int) refPoint {
+	k4 := new(big.Int).ModInverse(big.NewInt(4), primeOrder)
+	k4.Mul(k4, k).Mod(k4, primeOrder)
+	return P.mul(big.NewInt(4)).mul(k4)
+}
+
+func (P refPoint) encode() []byte {
+	b := le(P.y, 57)
+	b[56] |= byte(P.x.Bit(0)) << 7
+	return b
+}
+
+// refDecode is RFC 8032, section 5.2.3.
+func refDecode(b []byte) (refPoint, bool) {
+	be := reverse(b)
+	sign := uint(be[0] >> 7)
+	be[0] &= 0x7f
+	y := new(big.Int).SetBytes(be)
+	if y.Cmp(prime) >= 0 {
+		return refPoint{}, false
+	}
+	yy := fmul(y, y)
+	x, ok := refSqrt(fdiv(fsub(yy, refOne), fsub(fmul(refD, yy), refOne)))
+	if !ok || (x.Sign() == 0 && sign == 1) {
+		return refPoint{}, false
+	}
+	if x.Bit(0) != sign {
+		x = fneg(x)
+	}
+	return refPoint{x, y}, true
+}
+
+// decafEncode is RFC 9496, section 5.3.2, for the affine point P (z = 1).
+func (P refPoint) decafEncode() []byte {
+	x0, t0 := P.x, fmul(P.x, P.y)
+	u1 := fmul(fadd(x0, t0), fsub(x0, t0))
+	_, invsqrt := refSqrtRatio(refOne, fmul(u1, refOneMinusD, x0, x0))
+	ratio := fabs(fmul(invsqrt, u1, refSqrtMinusD))
+	u2 := fsub(fmul(refInvSqrtMinD, ratio), t0)
+	return le(fabs(fmul(refOneMinusD, invsqrt, x0, u2)), 56)
+}
+
+// refDecafDecode is RFC 9496, section 5.3.1.
+func refDecafDecode(b []byte) (refPoint, bool) {
+	s := new(big.Int).SetBytes(reverse(b))
+	if s.Cmp(prime) >= 0 || isNeg(s) {
+		return refPoint{}, false
+	}
+	ss := fmul(s, s)
+	u1 := fadd(refOne, ss)
+	u2 := fsub(fmul(u1, u1), fmul(big.NewInt(4), refD, ss))
+	wasSquare, invsqrt := refSqrtRatio(refOne, fmul(u2, u1, u1))
+	u3 := fabs(fmul(big.NewInt(2), s, invsqrt, u1, refSqrtMinusD))
+	x := fmul(u3, invsqrt, u2, refInvSqrtMinD)
+	y := fmul(fsub(refOne, ss), invsqrt, u1)
+	if !wasSquare {
+		return refPoint{}, false
+	}
+	return refPoint{x, y}, true
+}
+
+// refScalars returns scalars below l: the edges, and random ones.
+func refScalars(r *rand.Rand, n int) []*big.Int {
+	ks := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(4),
+		new(big.Int).Sub(primeOrder, big.NewInt(1)),
+		new(big.Int).Sub(primeOrder, big.NewInt(2)),
+		new(big.Int).Sub(primeOrder, big.NewInt(4)),
+		new(big.Int).Rsh(primeOrder, 1),
+		new(big.Int).Sub(new(big.Int).Lsh(refOne, 445), refOne),
+	}
+	for i := 0; i < n; i++ {
+		ks = append(ks, new(big.Int).Rand(r, primeOrder))
+	}
+	return ks
+}
+
+// refPoints returns the small-order points, and points with and without a torsion component. The
+// points of order 4 are (±1, 0).
+func refPoints(r *rand.Rand) []refPoint {
+	T := refPoint{big.NewInt(1), big.NewInt(0)}
+	ps := []refPoint{refIdentity, refB, refB.neg(), T, T.mul(big.NewInt(2)), refB.add(T)}
+	for i := int64(1); i <= 4; i++ {
+		P := refB.mul(new(big.Int).Rand(r, primeOrder))
+		ps = append(ps, P, P.add(T.mul(big.NewInt(i%3+1))))
+	}
+	return ps
+}
+
+func libScalar(k *big.Int) *scalar {
+	sc := curve{}.Scalar().(*scalar)
+	sc.FromBytes(k.FillBytes(make([]byte, 56)))
+	return sc
+}
+
+func libPoint(t *testing.T, P refPoint) *point {
+	e := curve{}.Element().(*point)
+	assert.NoError(t, e.FromBytes(P.encode()))
+	return e
+}
+
+func TestReferenceBasePoint(t *testing.T) {
+	x, _ := new(big.Int).SetString("224580040295924300187604334099896036246789641632564134246125461686950415467406032909029192869357953282578032075146446173674602635247710", 10)
+	assert.Zero(t, x.Cmp(refB.x))
+	assert.True(t, refB.mul(primeOrder).equal(refIdentity))
+	assert.Equal(t, refB.encode(), curve{}.Element().ScalarMult(libScalar(refOne), nil).Bytes())
+}
+
+func TestReferenceScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	g := curve{}
+	refM, _ := refDecode(g.M().Bytes())
+	for _, k := range refScalars(r, 6) {
+		sc := libScalar(k)
+		assert.Equal(t, refB.mul(k).encode(), g.Element().ScalarMult(sc, nil).Bytes(), "%v·B", k)
+		assert.Equal(t, refM.mul(k).encode(), g.Element().ScalarMult(sc, g.M()).Bytes(), "%v·M", k)
+	}
+	for i, P := range refPoints(r) {
+		k := new(big.Int).Rand(r, primeOrder)
+		assert.Equal(t, P.mulPrime(k).encode(), g.Element().ScalarMult(libScalar(k), libPoint(t, P)).Bytes(), "point %d", i)
+	}
+
+	ks := refScalars(r, 2)
+	scalars := []suite.Scalar{libScalar(ks[3]), libScalar(ks[9]), libScalar(ks[10])}
+	elements := []suite.Element{nil, g.M(), g.N()}
+	refN, _ := refDecode(g.N().Bytes())
+	want := refB.mul(ks[3]).add(refM.mul(ks[9])).add(refN.mul(ks[10]))
+	assert.Equal(t, want.encode(), g.MultiScalarMult(scalars, elements).Bytes())
+}
+
+func TestReferenceAddNegate(t *testing.T) {
+	ps := refPoints(rand.New(rand.NewSource(2)))
+	for i, P := range ps {
+		assert.Equal(t, P.neg().encode(), curve{}.Element().Negate(libPoint(t, P)).Bytes(), "-point %d", i)
+		for j, Q := range ps {
+			assert.Equal(t, P.add(Q).encode(), curve{}.Element().Add(libPoint(t, P), libPoint(t, Q)).Bytes(), "point %d + point %d", i, j)
+			assert.Equal(t, i == j, libPoint(t, P).Equal(libPoint(t, Q)), "point %d == point %d", i, j)
+		}
+	}
+}
+
+func TestReferenceDecode(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	var inputs [][]byte
+	for _, P := range refPoints(r) {
+		b := P.encode()
+		flipped := append([]byte(nil), b...)
+		flipped[56] ^= 0x80
+		inputs = append(inputs, b, flipped)
+		// bits 0-6 of the last byte are part of y, which is then at least 2^448
+		high := append([]byte(nil), b...)
+		high[56] |= 1
+		inputs = append(inputs, high)
+	}
+	for _, y := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		new(big.Int).Sub(prime, refOne),
+		prime,
+		new(big.Int).Add(prime, refOne),
+		new(big.Int).Sub(new(big.Int).Lsh(refOne, 448), refOne),
+	} {
+		b := le(y, 57)
+		inputs = append(inputs, b, append(b[:56:56], 0x80))
+	}
+	for i := 0; i < 64; i++ {
+		b := make([]byte, 57)
+		r.Read(b)
+		b[56] &= 0x80
+		inputs = append(inputs, b)
+	}
+
+	for _, b := range inputs {
+		P, ok := refDecode(b)
+		e := curve{}.Element()
+		err := e.FromBytes(b)
+		if assert.Equal(t, ok, err == nil, "%x", b) && ok {
+			assert.Equal(t, P.encode(), e.Bytes(), "%x", b)
+		}
+	}
+}
+
+func TestReferenceScalar(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	max := new(big.Int).Sub(new(big.Int).Lsh(refOne, 448), refOne)
+	ks := append(refScalars(r, 8), primeOrder, new(big.Int).Add(primeOrder, refOne), new(big.Int).Lsh(refOne, 447), max)
+
+	// FromBytes takes any 56 bytes and reduces them
+	for _, k := range ks {
+		sc := curve{}.Scalar()
+		assert.NoError(t, sc.FromBytes(k.FillBytes(make([]byte, 56))))
+		reduced := new(big.Int).Mod(k, primeOrder)
+		assert.Equal(t, reduced.FillBytes(make([]byte, 56)), sc.Bytes(), "%v", k)
+
+		neg := new(big.Int).Sub(primeOrder, reduced)
+		assert.Equal(t, neg.Mod(neg, primeOrder).FillBytes(make([]byte, 56)), curve{}.Scalar().Negate(sc).Bytes(), "-%v", k)
+	}
+
+	for i := 0; i < 32; i++ {
+		b := make([]byte, 56)
+		r.Read(b)
+		sc, err := curve{}.RandomScalar(bytes.NewReader(b))
+		assert.NoError(t, err)
+		want := new(big.Int).SetBytes(reverse(b))
+		assert.Equal(t, want.Mod(want, primeOrder).FillBytes(make([]byte, 56)), sc.Bytes(), "%x", b)
+	}
+}
+
+func TestReferenceDecaf(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	dg := decaf{}
+	T2 := refPoint{big.NewInt(0), big.NewInt(-1)}
+
+	// the generator is 2B; P and P + (0, -1) are the same element, and both encodings agree
+	var multiples []refPoint
+	for i, k := range refScalars(r, 6) {
+		P := refB.mul(k).mul(big.NewInt(2))
+		multiples = append(multiples, P)
+		want := P.decafEncode()
+		assert.Equal(t, want, dg.Element().ScalarMult(libScalar(k), nil).Bytes(), "%v·G", k)
+
+		Q := P
+		if i%2 == 1 {
+			Q = P.add(T2)
+		}
+		assert.Equal(t, want, Q.decafEncode(), "%v·G + T", k)
+		assert.Equal(t, want, (&decafPoint{libPoint(t, Q).p}).Bytes(), "%v·G + T", k)
+	}
+
+	for i, P := range multiples {
+		lp := dg.Element()
+		assert.NoError(t, lp.FromBytes(P.decafEncode()))
+		assert.Equal(t, P.neg().decafEncode(), dg.Element().Negate(lp).Bytes(), "-multiple %d", i)
+		k := new(big.Int).Rand(r, primeOrder)
+		assert.Equal(t, P.mul(k).decafEncode(), dg.Element().ScalarMult(libScalar(k), lp).Bytes(), "%v·multiple %d", k, i)
+		for j, Q := range multiples {
+			lq := dg.Element()
+			assert.NoError(t, lq.FromBytes(Q.decafEncode()))
+			assert.Equal(t, P.add(Q).decafEncode(), dg.Element().Add(lp, lq).Bytes(), "multiple %d + multiple %d", i, j)
+		}
+	}
+
+	var inputs [][]byte
+	for _, P := range multiples {
+		b := P.decafEncode()
+		flipped := append([]byte(nil), b...)
+		flipped[0] ^= 1
+		inputs = append(inputs, b, flipped)
+	}
+	for _, s := range []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(prime, refOne),
+		new(big.Int).Sub(prime, big.NewInt(2)),
+		prime,
+		new(big.Int).Add(prime, refOne),
+		new(big.Int).Sub(new(big.Int).Lsh(refOne, 448), refOne),
+	} {
+		inputs = append(inputs, le(s, 56))
+	}
+	for i := 0; i < 64; i++ {
+		b := make([]byte, 56)
+		r.Read(b)
+		// half of them are made non-negative and below p, as random strings rarely are both
+		if i%2 == 0 {
+			b[0] &^= 1
+			b[55] &= 0x7f
+		}
+		inputs = append(inputs, b)
+	}
+
+	for _, b := range inputs {
+		P, ok := refDecafDecode(b)
+		e := dg.Element().(*decafPoint)
+		err := e.FromBytes(b)
+		if assert.Equal(t, ok, err == nil, "%x", b) && ok {
+			assert.Equal(t, P.encode(), (&point{e.p}).Bytes(), "%x", b)
+			assert.Equal(t, b, e.Bytes(), "%x", b)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package ed448
 
 import (
-	"errors"
 	"github.com/cloudflare/circl/ecc/goldilocks"
 	"github.com/jtejido/spake2plus/internal/suite"
 )
@@ -21,7 +20,7 @@ func (s *scalar) Bytes() []byte {
 
 func (s *scalar) FromBytes(buf []byte) error {
 	if len(buf) != 56 {
-		return errors.New("wrong size buffer")
+		return suite.NewError(suite.BadLength, "wrong size buffer")
 	}
 
 	b := make([]byte, 56)
@@ -36,3 +35,7 @@ func (s *scalar) Negate(a suite.Scalar) suite.Scalar {
 	s.v.Neg()
 	return s
 }
+
+func (s *scalar) Zero() {
+	*s.v = goldilocks.Scalar{}
+}
@@ -0,0 +1,9 @@
+package ed448
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+func NewDecaf448Sha512HkdfHmac(mhf suite.MHF) *suite.Suite {
+	return suite.NewHkdfHmac(decaf{}, suite.SHA512, mhf)
+}
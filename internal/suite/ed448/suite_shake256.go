@@ -0,0 +1,10 @@
+package ed448
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// NewEd448Shake256Kmac is Ed448 with SHAKE256 (64 bytes of output) and KMAC256 as both KDF and MAC.
+func NewEd448Shake256Kmac(mhf suite.MHF) *suite.Suite {
+	return suite.New(curve{}, suite.SHAKE256, suite.KMAC256, suite.KMAC256, mhf)
+}
@@ -4,13 +4,16 @@ import (
 	el "crypto/elliptic"
 	"crypto/rand"
 	"github.com/jtejido/spake2plus/internal/suite"
+	"io"
 	"math/big"
+	"sync"
 )
 
 type curve struct {
 	el.Curve
-	n, m []byte
-	p    *el.CurveParams
+	n, m  []byte
+	p     *el.CurveParams
+	fixed *fixedPoints
 }
 
 func (c curve) String() string {
@@ -37,15 +40,20 @@ func (c curve) Element() suite.Element {
 	return p
 }
 
-// TO-DO: 5.  Per-User M and N in SPAKE2 RFC
-func (c curve) M() suite.Element {
+// fixedPoints holds M and N, decompressed on first use.
+type fixedPoints struct {
+	once sync.Once
+	m, n *curvePoint
+}
+
+func (c curve) decompress(buf []byte) *curvePoint {
 	point := c.Element().(*curvePoint)
 	var ch byte
-	for _, b := range c.n[1:] {
+	for _, b := range buf[1:] {
 		ch |= b
 	}
 	if ch != 0 {
-		point.x, point.y = point.unmarshalCompressed(c.n, 1+c.coordLen())
+		point.x, point.y = point.unmarshalCompressed(buf, 1+c.coordLen())
 		if point.x == nil || !point.valid() {
 			panic("invalid elliptic curve point")
 		}
@@ -55,43 +63,75 @@ func (c curve) M() suite.Element {
 	}
 
 	return point
+}
 
+func (c curve) loadFixed() {
+	c.fixed.once.Do(func() {
+		c.fixed.m = c.decompress(c.m)
+		c.fixed.n = c.decompress(c.n)
+	})
+}
+
+// M returns the fixed point the client blinds X with.
+func (c curve) M() suite.Element {
+	c.loadFixed()
+	return c.fixed.m.clone()
 }
 
-// TO-DO: 5.  Per-User M and N in SPAKE2 RFC
+// N returns the fixed point the server blinds Y with.
 func (c curve) N() suite.Element {
-	point := c.Element().(*curvePoint)
-	var ch byte
-	for _, b := range c.n[1:] {
-		ch |= b
+	c.loadFixed()
+	return c.fixed.n.clone()
+}
+
+// MultiScalarMult uses Straus' method on the curves implemented here. The standard library keeps
+// its fast arithmetic behind el.Curve, so on its curves the products are computed separately.
+func (c curve) MultiScalarMult(scalars []suite.Scalar, elements []suite.Element) suite.Element {
+	w, ok := c.Curve.(*weierstrass)
+	if !ok {
+		return suite.MultiScalarMultSeparately(c, scalars, elements)
 	}
-	if ch != 0 {
-		point.x, point.y = point.unmarshalCompressed(c.n, 1+c.coordLen())
-		if point.x == nil || !point.valid() {
-			panic("invalid elliptic curve point")
-		}
-	} else {
-		point.x = big.NewInt(0)
-		point.y = big.NewInt(0)
+	if len(scalars) != len(elements) {
+		panic("elliptic: mismatched scalars and elements")
 	}
 
-	return point
+	x := make([]*big.Int, len(elements))
+	y := make([]*big.Int, len(elements))
+	k := make([][]byte, len(scalars))
+	for i := range elements {
+		if elements[i] == nil {
+			x[i], y[i] = c.p.Gx, c.p.Gy
+		} else {
+			x[i], y[i] = elements[i].(*curvePoint).x, elements[i].(*curvePoint).y
+		}
+		k[i] = scalars[i].Bytes()
+	}
 
+	p := c.Element().(*curvePoint)
+	p.x, p.y = w.MultiScalarMult(x, y, k)
+	return p
 }
 
 var mask = []byte{0xff, 0x1, 0x3, 0x7, 0xf, 0x1f, 0x3f, 0x7f}
 
-func (c curve) RandomElement() (suite.Element, error) {
-	sc, err := c.RandomScalar()
-	return c.Element().ScalarMult(sc, nil), err
+func (c curve) RandomElement(r io.Reader) (suite.Element, error) {
+	sc, err := c.RandomScalar(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.Element().ScalarMult(sc, nil), nil
 }
 
-func (c curve) RandomScalar() (suite.Scalar, error) {
+func (c curve) RandomScalar(r io.Reader) (suite.Scalar, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+
 	buf := make([]byte, c.ScalarLen())
 	bitLen := c.p.N.BitLen()
 
 	for {
-		_, err := rand.Read(buf)
+		_, err := io.ReadFull(r, buf)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,43 @@
+package elliptic
+
+import (
+	"encoding/hex"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestFixedPointsGolden pins M and N of the NIST curves to the compressed encodings of RFC 9383,
+// section 4, as the suites' groups return them.
+func TestFixedPointsGolden(t *testing.T) {
+	for _, tc := range []struct {
+		g    suite.Group
+		m, n string
+	}{
+		{
+			NewP256Sha256HkdfHmac(nil).Group(),
+			"02886e2f97ace46e55ba9dd7242579f2993b64e16ef3dcab95afd497333d8fa12f",
+			"03d8bbd6c639c62937b04d997f38c3770719c629d7014d49a24b4f98baa1292b49",
+		},
+		{
+			NewP384Sha512HkdfHmac(nil).Group(),
+			"030ff0895ae5ebf6187080a82d82b42e2765e3b2f8749c7e05eba366434b363d3dc36f15314739074d2eb8613fceec2853",
+			"02c72cf2e390853a1c1c4ad816a62fd15824f56078918f43f922ca21518f9c543bb252c5490214cf9aa3f0baab4b665c10",
+		},
+		{
+			NewP521Sha512HkdfHmac(nil).Group(),
+			"02003f06f38131b2ba2600791e82488e8d20ab889af753a41806c5db18d37d85608cfae06b82e4a72cd744c719193562a653ea1f119eef9356907edc9b56979962d7aa",
+			"0200c7924b9ec017f3094562894336a53c50167ba8c5963876880542bc669e494b2532d76c5b53dfb349fdf69154b9e0048c58a42e8ed04cef052a3bc349d95575cd25",
+		},
+	} {
+		assert.Equal(t, tc.m, compress(tc.g.M().Bytes()), tc.g.String())
+		assert.Equal(t, tc.n, compress(tc.g.N().Bytes()), tc.g.String())
+	}
+}
+
+// compress turns an uncompressed SEC 1 encoding into the hex of the compressed one.
+func compress(b []byte) string {
+	size := (len(b) - 1) / 2
+	c := append([]byte{2 | b[len(b)-1]&1}, b[1:1+size]...)
+	return hex.EncodeToString(c)
+}
@@ -0,0 +1,183 @@
+package elliptic
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// maxLimbs is the number of 64-bit limbs of the largest field, brainpoolP512r1's.
+const maxLimbs = 8
+
+// fieldElement is an element of a field, in Montgomery form, in the first n limbs of the field,
+// least significant first. The others stay zero.
+type fieldElement [maxLimbs]uint64
+
+// field is GF(p) for an odd p of up to 512 bits. Its arithmetic runs in constant time: loops only
+// depend on the number of limbs, and reductions select their result with masks.
+type field struct {
+	n    int          // limbs
+	size int          // bytes of an element
+	p    fieldElement // not in Montgomery form
+	pInv uint64       // -p⁻¹ mod 2⁶⁴
+	rr   fieldElement // R² mod p, with R = 2^(64n), to enter Montgomery form
+	one  fieldElement // R mod p
+	pm2  []byte       // p - 2, big-endian, the exponent of inversion
+}
+
+func newField(p *big.Int) *field {
+	f := &field{n: (p.BitLen() + 63) / 64, size: (p.BitLen() + 7) / 8}
+	f.p = f.limbs(p)
+
+	// Newton's iteration doubles the correct low bits of p⁻¹ each step
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - f.p[0]*inv
+	}
+	f.pInv = -inv
+
+	r := new(big.Int).Lsh(big.NewInt(1), uint(64*f.n))
+	f.one = f.limbs(new(big.Int).Mod(r, p))
+	f.rr = f.limbs(new(big.Int).Mod(new(big.Int).Mul(r, r), p))
+	f.pm2 = new(big.Int).Sub(p, big.NewInt(2)).Bytes()
+	return f
+}
+
+// limbs splits x < 2^(64n) into limbs, without entering Montgomery form.
+func (f *field) limbs(x *big.Int) fieldElement {
+	var z fieldElement
+	buf := make([]byte, 8*f.n)
+	x.FillBytes(buf)
+	for i := 0; i < f.n; i++ {
+		for _, b := range buf[len(buf)-8*(i+1) : len(buf)-8*i] {
+			z[i] = z[i]<<8 | uint64(b)
+		}
+	}
+	return z
+}
+
+// fromBig sets z to x mod p, for 0 ≤ x < p.
+func (f *field) fromBig(z *fieldElement, x *big.Int) {
+	l := f.limbs(x)
+	f.mul(z, &l, &f.rr)
+}
+
+func (f *field) toBig(x *fieldElement) *big.Int {
+	var one, z fieldElement
+	one[0] = 1
+	f.mul(&z, x, &one)
+	buf := make([]byte, 8*f.n)
+	for i := 0; i < f.n; i++ {
+		for j := 0; j < 8; j++ {
+			buf[len(buf)-8*i-1-j] = byte(z[i] >> (8 * j))
+		}
+	}
+	return new(big.Int).SetBytes(buf)
+}
+
+// reduce sets z to t - p if that doesn't borrow, or to t otherwise, where t < 2p is given by its n
+// limbs and a carry.
+func (f *field) reduce(z, t *fieldElement, carry uint64) {
+	var d fieldElement
+	var b uint64
+	for i := 0; i < f.n; i++ {
+		d[i], b = bits.Sub64(t[i], f.p[i], b)
+	}
+	_, b = bits.Sub64(carry, 0, b)
+	f.selectElement(z, t, &d, b)
+}
+
+// selectElement sets z to a if c is 1, or to b if c is 0.
+func (f *field) selectElement(z, a, b *fieldElement, c uint64) {
+	mask := -c
+	for i := 0; i < f.n; i++ {
+		z[i] = a[i]&mask | b[i]&^mask
+	}
+}
+
+func (f *field) add(z, x, y *fieldElement) {
+	var t fieldElement
+	var c uint64
+	for i := 0; i < f.n; i++ {
+		t[i], c = bits.Add64(x[i], y[i], c)
+	}
+	f.reduce(z, &t, c)
+}
+
+func (f *field) sub(z, x, y *fieldElement) {
+	var t fieldElement
+	var b uint64
+	for i := 0; i < f.n; i++ {
+		t[i], b = bits.Sub64(x[i], y[i], b)
+	}
+	// add p back if it borrowed
+	mask := -b
+	var c uint64
+	for i := 0; i < f.n; i++ {
+		z[i], c = bits.Add64(t[i], f.p[i]&mask, c)
+	}
+}
+
+// mul sets z to x*y/R mod p with the CIOS method of Koç, Acar and Kaliski.
+func (f *field) mul(z, x, y *fieldElement) {
+	var t [maxLimbs + 2]uint64
+	n := f.n
+	for i := 0; i < n; i++ {
+		// t += x*y[i]
+		var c, cc uint64
+		for j := 0; j < n; j++ {
+			hi, lo := bits.Mul64(x[j], y[i])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j], c = lo, hi
+		}
+		t[n], cc = bits.Add64(t[n], c, 0)
+		t[n+1] = cc
+
+		// t = (t + m*p) / 2⁶⁴, which is exact for this m
+		m := t[0] * f.pInv
+		hi, lo := bits.Mul64(m, f.p[0])
+		_, cc = bits.Add64(lo, t[0], 0)
+		c = hi + cc
+		for j := 1; j < n; j++ {
+			hi, lo = bits.Mul64(m, f.p[j])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j-1], c = lo, hi
+		}
+		t[n-1], cc = bits.Add64(t[n], c, 0)
+		t[n] = t[n+1] + cc
+	}
+
+	var r fieldElement
+	copy(r[:n], t[:n])
+	f.reduce(z, &r, t[n])
+}
+
+// invert sets z to 1/x, or to zero for x = 0, as x^(p-2). The exponent is public, so branching on
+// its bits leaks nothing about x.
+func (f *field) invert(z, x *fieldElement) {
+	r := f.one
+	for _, b := range f.pm2 {
+		for i := 7; i >= 0; i-- {
+			f.mul(&r, &r, &r)
+			if b>>uint(i)&1 == 1 {
+				f.mul(&r, &r, x)
+			}
+		}
+	}
+	*z = r
+}
+
+// isZero returns 1 if x is zero, and 0 otherwise.
+func (f *field) isZero(x *fieldElement) uint64 {
+	var acc uint64
+	for i := 0; i < f.n; i++ {
+		acc |= x[i]
+	}
+	// the top bit of acc | -acc is set unless acc is zero
+	return 1 ^ (acc|-acc)>>63
+}
@@ -0,0 +1,279 @@
+package elliptic
+
+import (
+	el "crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"hash"
+	"math/big"
+	"testing"
+)
+
+// An implementation of hash_to_curve from RFC 9380 on math/big, written from the RFC for these
+// tests: expand_message_xmd (section 5.3.1), hash_to_field (5.2), the simplified SWU map (6.6.2),
+// the 3-isogeny of secp256k1 (appendix E.1) and the choice of Z (appendix H.2). TestHashToCurve
+// checks it against the RFC's vectors, as circl's tests carry them, and
+// TestWeierstrassMN derives M and N with it.
+
+type h2cSuite struct {
+	c    curve
+	h    func() hash.Hash
+	l    int      // bytes per field element, L in the RFC
+	a, b *big.Int // of the curve SSWU maps to, isogenous to c for secp256k1
+	z    *big.Int
+	iso  func(x, y *big.Int) (*big.Int, *big.Int)
+}
+
+func expandMessageXMD(h func() hash.Hash, msg, dst []byte, n int) []byte {
+	hh := h()
+	b, s := hh.Size(), hh.BlockSize()
+	ell := (n + b - 1) / b
+	if ell > 255 || len(dst) > 255 {
+		panic("expand_message_xmd: too long")
+	}
+	dstPrime := append(append([]byte(nil), dst...), byte(len(dst)))
+
+	hh.Write(make([]byte, s))
+	hh.Write(msg)
+	hh.Write([]byte{byte(n >> 8), byte(n), 0})
+	hh.Write(dstPrime)
+	b0 := hh.Sum(nil)
+
+	hh.Reset()
+	hh.Write(b0)
+	hh.Write([]byte{1})
+	hh.Write(dstPrime)
+	bi := hh.Sum(nil)
+	out := append([]byte(nil), bi...)
+	for i := 2; i <= ell; i++ {
+		x := make([]byte, b)
+		for j := range x {
+			x[j] = b0[j] ^ bi[j]
+		}
+		hh.Reset()
+		hh.Write(x)
+		hh.Write([]byte{byte(i)})
+		hh.Write(dstPrime)
+		bi = hh.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:n]
+}
+
+func (s *h2cSuite) mod(x *big.Int) *big.Int { return x.Mod(x, s.c.p.P) }
+
+// g returns x³ + ax + b on the curve SSWU maps to.
+func (s *h2cSuite) g(x *big.Int) *big.Int {
+	y := new(big.Int).Mul(x, x)
+	y.Add(y, s.a).Mul(y, x).Add(y, s.b)
+	return s.mod(y)
+}
+
+func (s *h2cSuite) isSquare(x *big.Int) bool {
+	return big.Jacobi(x, s.c.p.P) >= 0
+}
+
+func (s *h2cSuite) inv0(x *big.Int) *big.Int {
+	if x.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).ModInverse(x, s.c.p.P)
+}
+
+func (s *h2cSuite) sswu(u *big.Int) (*big.Int, *big.Int) {
+	p := s.c.p.P
+	zu2 := s.mod(new(big.Int).Mul(s.z, new(big.Int).Mul(u, u)))
+	tv1 := s.inv0(s.mod(new(big.Int).Add(new(big.Int).Mul(zu2, zu2), zu2)))
+	var x1 *big.Int
+	if tv1.Sign() == 0 {
+		x1 = s.mod(new(big.Int).Mul(s.b, s.inv0(s.mod(new(big.Int).Mul(s.z, s.a)))))
+	} else {
+		x1 = new(big.Int).Neg(s.b)
+		x1.Mul(x1, s.inv0(s.a)).Mul(x1, tv1.Add(tv1, big.NewInt(1)))
+		s.mod(x1)
+	}
+	x := x1
+	if !s.isSquare(s.g(x1)) {
+		x = s.mod(new(big.Int).Mul(zu2, x1))
+	}
+	y := new(big.Int).ModSqrt(s.g(x), p)
+	if u.Bit(0) != y.Bit(0) {
+		s.mod(y.Neg(y))
+	}
+	return x, y
+}
+
+func (s *h2cSuite) hashToCurve(msg, dst []byte) refPoint {
+	uniform := expandMessageXMD(s.h, msg, dst, 2*s.l)
+	ref := newRefCurve(s.c)
+	R := refPoint{}
+	for i := 0; i < 2; i++ {
+		u := s.mod(new(big.Int).SetBytes(uniform[i*s.l : (i+1)*s.l]))
+		x, y := s.sswu(u)
+		if s.iso != nil {
+			x, y = s.iso(x, y)
+		}
+		if !ref.onCurve(x, y) {
+			panic("hash_to_curve: point not on " + s.c.p.Name)
+		}
+		R = ref.add(R, refPoint{x, y})
+	}
+	// the cofactor is 1
+	return R
+}
+
+// secp256k1Iso is the 3-isogeny map of RFC 9380, appendix E.1.
+func secp256k1Iso(x, y *big.Int) (*big.Int, *big.Int) {
+	p := secp256k1.params.P
+	poly := func(k ...string) *big.Int {
+		r := new(big.Int)
+		for i := len(k) - 1; i >= 0; i-- {
+			r.Mul(r, x).Add(r, hexInt(k[i])).Mod(r, p)
+		}
+		return r
+	}
+	xNum := poly("8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa8c7",
+		"07d3d4c80bc321d5b9f315cea7fd44c5d595d2fc0bf63b92dfff1044f17c6581",
+		"534c328d23f234e6e2a413deca25caece4506144037c40314ecbd0b53d9dd262",
+		"8e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38e38daaaaa88c")
+	xDen := poly("d35771193d94918a9ca34ccbb7b640dd86cd409542f8487d9fe6b745781eb49b",
+		"edadc6f64383dc1df7c4b2d51b54225406d36b641f5e41bbc52a56612a8c6d14", "1")
+	yNum := poly("4bda12f684bda12f684bda12f684bda12f684bda12f684bda12f684b8e38e23c",
+		"c75e0c32d5cb7c0fa9d0a54b12a0a6d5647ab046d686da6fdffc90fc201d71a3",
+		"29a6194691f91a73715209ef6512e576722830a201be2018a765e85a9ecee931",
+		"2f684bda12f684bda12f684bda12f684bda12f684bda12f684bda12f38e38d84")
+	yDen := poly("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffff93b",
+		"7a06534bb8bdb49fd5e9e6632722c2989467c1bfc8e8d978dfb425d2685c2573",
+		"6484aa716545ca2cf3a70c3fa8fe337e0a3d21162f0d6299a7bf8192bfd2a76f", "1")
+	X := xNum.Mul(xNum, xDen.ModInverse(xDen, p)).Mod(xNum, p)
+	Y := yNum.Mul(yNum, yDen.ModInverse(yDen, p)).Mul(yNum, y).Mod(yNum, p)
+	return X, Y
+}
+
+// findZ is find_z_sswu of RFC 9380, appendix H.2.
+func findZ(p, a, b *big.Int) *big.Int {
+	square := func(x *big.Int) bool { return big.Jacobi(x, p) >= 0 }
+	minusOne := new(big.Int).Sub(p, big.NewInt(1))
+	for ctr := int64(1); ; ctr++ {
+		for _, z := range []*big.Int{big.NewInt(ctr), new(big.Int).Sub(p, big.NewInt(ctr))} {
+			if square(z) || z.Cmp(minusOne) == 0 || !cubicIrreducible(p, a, new(big.Int).Sub(b, z)) {
+				continue
+			}
+			// g(B / (Z * A)) must be square
+			x := new(big.Int).Mul(z, a)
+			x.ModInverse(x.Mod(x, p), p).Mul(x, b).Mod(x, p)
+			gx := new(big.Int).Mul(x, x)
+			gx.Add(gx, a).Mul(gx, x).Add(gx, b).Mod(gx, p)
+			if square(gx) {
+				return z
+			}
+		}
+	}
+}
+
+// cubicIrreducible reports whether x³ + ax + c has no root mod p, which for a cubic means it is
+// irreducible: it checks that gcd(x^p - x, x³ + ax + c) is constant.
+func cubicIrreducible(p, a, c *big.Int) bool {
+	// polynomials mod f = x³ + ax + c, as coefficients of 1, x and x²
+	mulMod := func(u, v [3]*big.Int) [3]*big.Int {
+		var w [5]*big.Int
+		for i := range w {
+			w[i] = new(big.Int)
+		}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				w[i+j].Add(w[i+j], new(big.Int).Mul(u[i], v[j]))
+			}
+		}
+		// x³ = -ax - c
+		for k := 4; k >= 3; k-- {
+			w[k-2].Sub(w[k-2], new(big.Int).Mul(w[k], a))
+			w[k-3].Sub(w[k-3], new(big.Int).Mul(w[k], c))
+		}
+		return [3]*big.Int{w[0].Mod(w[0], p), w[1].Mod(w[1], p), w[2].Mod(w[2], p)}
+	}
+	r := [3]*big.Int{big.NewInt(1), new(big.Int), new(big.Int)}
+	x := [3]*big.Int{new(big.Int), big.NewInt(1), new(big.Int)}
+	for i := p.BitLen() - 1; i >= 0; i-- {
+		r = mulMod(r, r)
+		if p.Bit(i) == 1 {
+			r = mulMod(r, x)
+		}
+	}
+	r[1].Sub(r[1], big.NewInt(1)).Mod(r[1], p)
+
+	// Euclid on f and x^p - x mod f, both as coefficient slices from the constant term
+	u := []*big.Int{new(big.Int).Mod(c, p), new(big.Int).Mod(a, p), new(big.Int), big.NewInt(1)}
+	v := []*big.Int{r[0], r[1], r[2]}
+	trim := func(q []*big.Int) []*big.Int {
+		for len(q) > 0 && q[len(q)-1].Sign() == 0 {
+			q = q[:len(q)-1]
+		}
+		return q
+	}
+	for v = trim(v); len(v) > 0; {
+		lead := new(big.Int).ModInverse(v[len(v)-1], p)
+		for len(u) >= len(v) {
+			k := new(big.Int).Mul(u[len(u)-1], lead)
+			shift := len(u) - len(v)
+			for i := range v {
+				u[i+shift].Sub(u[i+shift], new(big.Int).Mul(k, v[i])).Mod(u[i+shift], p)
+			}
+			u = trim(u)
+		}
+		u, v = v, u
+	}
+	return len(u) == 1
+}
+
+func h2cSuites() map[string]*h2cSuite {
+	suite := func(c curve, h func() hash.Hash, l int, z int64) *h2cSuite {
+		a := big.NewInt(-3)
+		if w, ok := c.Curve.(*weierstrass); ok {
+			a = w.a
+		}
+		p := c.p.P
+		return &h2cSuite{c: c, h: h, l: l, a: new(big.Int).Mod(a, p), b: c.p.B, z: new(big.Int).Mod(big.NewInt(z), p)}
+	}
+	k1 := suite(newCurve(secp256k1, nil, nil), sha256.New, 48, -11)
+	k1.a = hexInt("3f8731abdd661adca08a5558f0f5d272e953d363cb6f0e5d405447c01a444533")
+	k1.b = big.NewInt(1771)
+	k1.iso = secp256k1Iso
+	return map[string]*h2cSuite{
+		"P256_XMD:SHA-256_SSWU_RO_":            suite(newCurve(el.P256(), nil, nil), sha256.New, 48, -10),
+		"P384_XMD:SHA-384_SSWU_RO_":            suite(newCurve(P384(), nil, nil), sha512.New384, 72, -12),
+		"P521_XMD:SHA-512_SSWU_RO_":            suite(newCurve(el.P521(), nil, nil), sha512.New, 98, -4),
+		"secp256k1_XMD:SHA-256_SSWU_RO_":       k1,
+		"brainpoolP256r1_XMD:SHA-256_SSWU_RO_": suite(newCurve(brainpoolP256r1, nil, nil), sha256.New, 48, -2),
+		"brainpoolP384r1_XMD:SHA-384_SSWU_RO_": suite(newCurve(brainpoolP384r1, nil, nil), sha512.New384, 72, -5),
+		"brainpoolP512r1_XMD:SHA-512_SSWU_RO_": suite(newCurve(brainpoolP512r1, nil, nil), sha512.New, 96, 7),
+	}
+}
+
+func TestHashToCurve(t *testing.T) {
+	suites := h2cSuites()
+
+	// from RFC 9380, appendix J, with DST "QUUX-V01-CS02-with-" and the suite ID
+	for _, v := range []struct{ suite, msg, x, y string }{
+		{"P256_XMD:SHA-256_SSWU_RO_", "", "2c15230b26dbc6fc9a37051158c95b79656e17a1a920b11394ca91c44247d3e4", "8a7a74985cc5c776cdfe4b1f19884970453912e9d31528c060be9ab5c43e8415"},
+		{"P256_XMD:SHA-256_SSWU_RO_", "abc", "0bb8b87485551aa43ed54f009230450b492fead5f1cc91658775dac4a3388a0f", "5c41b3d0731a27a7b14bc0bf0ccded2d8751f83493404c84a88e71ffd424212e"},
+		{"P384_XMD:SHA-384_SSWU_RO_", "", "eb9fe1b4f4e14e7140803c1d99d0a93cd823d2b024040f9c067a8eca1f5a2eeac9ad604973527a356f3fa3aeff0e4d83", "0c21708cff382b7f4643c07b105c2eaec2cead93a917d825601e63c8f21f6abd9abc22c93c2bed6f235954b25048bb1a"},
+		{"P384_XMD:SHA-384_SSWU_RO_", "abc", "e02fc1a5f44a7519419dd314e29863f30df55a514da2d655775a81d413003c4d4e7fd59af0826dfaad4200ac6f60abe1", "01f638d04d98677d65bef99aef1a12a70a4cbb9270ec55248c04530d8bc1f8f90f8a6a859a7c1f1ddccedf8f96d675f6"},
+		{"P521_XMD:SHA-512_SSWU_RO_", "", "00fd767cebb2452030358d0e9cf907f525f50920c8f607889a6a35680727f64f4d66b161fafeb2654bea0d35086bec0a10b30b14adef3556ed9f7f1bc23cecc9c088", "0169ba78d8d851e930680322596e39c78f4fe31b97e57629ef6460ddd68f8763fd7bd767a4e94a80d3d21a3c2ee98347e024fc73ee1c27166dc3fe5eeef782be411d"},
+		{"P521_XMD:SHA-512_SSWU_RO_", "abc", "002f89a1677b28054b50d15e1f81ed6669b5a2158211118ebdef8a6efc77f8ccaa528f698214e4340155abc1fa08f8f613ef14a043717503d57e267d57155cf784a4", "010e0be5dc8e753da8ce51091908b72396d3deed14ae166f66d8ebf0a4e7059ead169ea4bead0232e9b700dd380b316e9361cfdba55a08c73545563a80966ecbb86d"},
+		{"secp256k1_XMD:SHA-256_SSWU_RO_", "", "c1cae290e291aee617ebaef1be6d73861479c48b841eaba9b7b5852ddfeb1346", "64fa678e07ae116126f08b022a94af6de15985c996c3a91b64c406a960e51067"},
+	} {
+		P := suites[v.suite].hashToCurve([]byte(v.msg), []byte("QUUX-V01-CS02-with-"+v.suite))
+		size := suites[v.suite].c.coordLen()
+		assert.Equal(t, v.x, hex.EncodeToString(P.x.FillBytes(make([]byte, size))), v.suite)
+		assert.Equal(t, v.y, hex.EncodeToString(P.y.FillBytes(make([]byte, size))), v.suite)
+	}
+
+	// find_z_sswu gives the Z of the RFC's suites, and those of Brainpool, which the RFC doesn't
+	// define suites for
+	for name, s := range suites {
+		assert.Equal(t, s.z.String(), findZ(s.c.p.P, s.a, s.b).String(), name)
+	}
+}
@@ -2,7 +2,6 @@ package elliptic
 
 import (
 	el "crypto/elliptic"
-	"errors"
 	"github.com/jtejido/spake2plus/internal/suite"
 	"math/big"
 )
@@ -51,13 +50,22 @@ func (p *curvePoint) ScalarMult(s suite.Scalar, b suite.Element) suite.Element {
 	return p
 }
 
-var errInvalidPoint = errors.New("invalid elliptic curve point")
+// clone returns a copy that doesn't share p's coordinates, as Equal reduces them in place.
+func (p *curvePoint) clone() *curvePoint {
+	return &curvePoint{new(big.Int).Set(p.x), new(big.Int).Set(p.y), p.c}
+}
+
+var errInvalidPoint = suite.NewError(suite.InvalidPoint, "invalid elliptic curve point")
 
 func (p *curvePoint) Bytes() []byte {
 	return el.Marshal(p.c, p.x, p.y)
 }
 
 func (p *curvePoint) FromBytes(buf []byte) error {
+	if len(buf) != p.c.ElementLen() {
+		return suite.NewError(suite.BadLength, "wrong size buffer")
+	}
+
 	var c byte
 	for _, b := range buf[1:] {
 		c |= b
@@ -68,6 +76,9 @@ func (p *curvePoint) FromBytes(buf []byte) error {
 		if p.x == nil || !p.valid() {
 			return errInvalidPoint
 		}
+	} else if buf[0] != 4 {
+		// the identity is marshalled as (0, 0), uncompressed like any other point
+		return errInvalidPoint
 	} else {
 		p.x = big.NewInt(0)
 		p.y = big.NewInt(0)
@@ -95,7 +106,7 @@ func (p *curvePoint) unmarshalCompressed(buf []byte, size int) (x, y *big.Int) {
 	if x.Cmp(pp) >= 0 {
 		return nil, nil
 	}
-	// y² = x³ - 3x + b
+	// y² = x³ + ax + b
 	y = polynomial(p.c, x)
 	y = y.ModSqrt(y, pp)
 	if y == nil {
@@ -111,7 +122,12 @@ func (p *curvePoint) unmarshalCompressed(buf []byte, size int) (x, y *big.Int) {
 	return
 }
 
+// polynomial returns x³ - 3x + b, or x³ + ax + b for curves with a different a.
 func polynomial(c *curve, x *big.Int) *big.Int {
+	if w, ok := c.Curve.(*weierstrass); ok {
+		return w.polynomial(x)
+	}
+
 	x3 := new(big.Int).Mul(x, x)
 	x3.Mul(x3, x)
 
@@ -0,0 +1,302 @@
+package elliptic
+
+import (
+	el "crypto/elliptic"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// The tests below check every curve of the package, the standard library's and circl's as well as
+// weierstrass, against the affine chord-and-tangent formulas on math/big, on boundary and random
+// inputs. The reference is slow and variable time, and only has to be obviously right.
+
+// refCurve is y² = x³ + ax + b over p, with the point at infinity encoded as 0x04 followed by
+// zeros, as crypto/elliptic marshals (0, 0).
+type refCurve struct {
+	p, a, b, n *big.Int
+	g          refPoint
+	size       int
+}
+
+// refPoint is an affine point; a nil x is the point at infinity.
+type refPoint struct {
+	x, y *big.Int
+}
+
+func referenceCurves() []curve {
+	return []curve{
+		newCurve(el.P256(), p256m, p256n),
+		newCurve(P384(), p384m, p384n),
+		newCurve(el.P521(), p521m, p521n),
+		newCurve(secp256k1, secp256k1m, secp256k1n),
+		newCurve(brainpoolP256r1, bp256m, bp256n),
+		newCurve(brainpoolP384r1, bp384m, bp384n),
+		newCurve(brainpoolP512r1, bp512m, bp512n),
+	}
+}
+
+func newRefCurve(c curve) *refCurve {
+	a := big.NewInt(-3)
+	if w, ok := c.Curve.(*weierstrass); ok {
+		a = w.a
+	}
+	p := c.p.P
+	return &refCurve{p, new(big.Int).Mod(a, p), c.p.B, c.p.N, refPoint{c.p.Gx, c.p.Gy}, c.coordLen()}
+}
+
+func (c *refCurve) mod(x *big.Int) *big.Int { return x.Mod(x, c.p) }
+
+func (c *refCurve) onCurve(x, y *big.Int) bool {
+	lhs := c.mod(new(big.Int).Mul(y, y))
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, new(big.Int).Mul(c.a, x))
+	rhs.Add(rhs, c.b)
+	return lhs.Cmp(c.mod(rhs)) == 0
+}
+
+func (c *refCurve) add(P, Q refPoint) refPoint {
+	if P.x == nil {
+		return Q
+	}
+	if Q.x == nil {
+		return P
+	}
+
+	var num, den *big.Int
+	if P.x.Cmp(Q.x) == 0 {
+		if c.mod(new(big.Int).Add(P.y, Q.y)).Sign() == 0 {
+			return refPoint{}
+		}
+		// the tangent: (3x² + a) / 2y
+		num = new(big.Int).Mul(P.x, P.x)
+		num.Mul(num, big.NewInt(3)).Add(num, c.a)
+		den = new(big.Int).Lsh(P.y, 1)
+	} else {
+		num = new(big.Int).Sub(Q.y, P.y)
+		den = new(big.Int).Sub(Q.x, P.x)
+	}
+	l := new(big.Int).ModInverse(c.mod(den), c.p)
+	c.mod(l.Mul(l, num))
+
+	x := new(big.Int).Mul(l, l)
+	c.mod(x.Sub(x, P.x).Sub(x, Q.x))
+	y := new(big.Int).Sub(P.x, x)
+	c.mod(y.Mul(y, l).Sub(y, P.y))
+	return refPoint{x, y}
+}
+
+func (c *refCurve) neg(P refPoint) refPoint {
+	if P.x == nil {
+		return P
+	}
+	return refPoint{P.x, c.mod(new(big.Int).Neg(P.y))}
+}
+
+func (c *refCurve) mul(k *big.Int, P refPoint) refPoint {
+	R := refPoint{}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = c.add(R, R)
+		if k.Bit(i) == 1 {
+			R = c.add(R, P)
+		}
+	}
+	return R
+}
+
+func (c *refCurve) encode(P refPoint) []byte {
+	b := make([]byte, 1+2*c.size)
+	b[0] = 4
+	if P.x != nil {
+		P.x.FillBytes(b[1 : 1+c.size])
+		P.y.FillBytes(b[1+c.size:])
+	}
+	return b
+}
+
+// decode takes the uncompressed encoding of SEC 1, section 2.3.4, or the encoding of the point at
+// infinity above.
+func (c *refCurve) decode(b []byte) (refPoint, bool) {
+	if len(b) != 1+2*c.size || b[0] != 4 {
+		return refPoint{}, false
+	}
+	x := new(big.Int).SetBytes(b[1 : 1+c.size])
+	y := new(big.Int).SetBytes(b[1+c.size:])
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return refPoint{}, true
+	}
+	if x.Cmp(c.p) >= 0 || y.Cmp(c.p) >= 0 || !c.onCurve(x, y) {
+		return refPoint{}, false
+	}
+	return refPoint{x, y}, true
+}
+
+func (c *refCurve) scalars(r *rand.Rand, n int) []*big.Int {
+	ks := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(2),
+		new(big.Int).Sub(c.n, big.NewInt(1)),
+		new(big.Int).Sub(c.n, big.NewInt(2)),
+		new(big.Int).Rsh(c.n, 1),
+	}
+	for i := 0; i < n; i++ {
+		ks = append(ks, new(big.Int).Rand(r, c.n))
+	}
+	return ks
+}
+
+// points returns the point at infinity, G and -G, M and N, and random multiples of G.
+func (c *refCurve) points(t *testing.T, g curve, r *rand.Rand) []refPoint {
+	M, okM := c.decode(g.M().Bytes())
+	N, okN := c.decode(g.N().Bytes())
+	assert.True(t, okM && okN, g.String())
+	ps := []refPoint{{}, c.g, c.neg(c.g), M, N}
+	for i := 0; i < 3; i++ {
+		ps = append(ps, c.mul(new(big.Int).Rand(r, c.n), c.g))
+	}
+	return ps
+}
+
+func libScalar(t *testing.T, g curve, k *big.Int) suite.Scalar {
+	sc := g.Scalar()
+	assert.NoError(t, sc.FromBytes(k.FillBytes(make([]byte, g.ScalarLen()))), "%s: %v", g, k)
+	return sc
+}
+
+func libPoint(t *testing.T, g curve, c *refCurve, P refPoint) suite.Element {
+	e := g.Element()
+	assert.NoError(t, e.FromBytes(c.encode(P)), g.String())
+	return e
+}
+
+func TestReferenceScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, g := range referenceCurves() {
+		c := newRefCurve(g)
+		M, _ := c.decode(g.M().Bytes())
+		for _, k := range c.scalars(r, 4) {
+			sc := libScalar(t, g, k)
+			assert.Equal(t, c.encode(c.mul(k, c.g)), g.Element().ScalarMult(sc, nil).Bytes(), "%s: %v·G", g, k)
+			assert.Equal(t, c.encode(c.mul(k, M)), g.Element().ScalarMult(sc, g.M()).Bytes(), "%s: %v·M", g, k)
+		}
+		for i, P := range c.points(t, g, r) {
+			k := new(big.Int).Rand(r, c.n)
+			assert.Equal(t, c.encode(c.mul(k, P)), g.Element().ScalarMult(libScalar(t, g, k), libPoint(t, g, c, P)).Bytes(), "%s: point %d", g, i)
+		}
+	}
+}
+
+func TestReferenceMultiScalarMult(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, g := range referenceCurves() {
+		c := newRefCurve(g)
+		ps := c.points(t, g, r)
+		ks := c.scalars(r, 2)
+		for n := 1; n <= 4; n++ {
+			scalars := make([]suite.Scalar, n)
+			elements := make([]suite.Element, n)
+			want := refPoint{}
+			for i := range scalars {
+				k := ks[r.Intn(len(ks))]
+				scalars[i] = libScalar(t, g, k)
+				if i == 0 {
+					want = c.add(want, c.mul(k, c.g)) // nil is the base point
+					continue
+				}
+				P := ps[r.Intn(len(ps))]
+				elements[i] = libPoint(t, g, c, P)
+				want = c.add(want, c.mul(k, P))
+			}
+			assert.Equal(t, c.encode(want), g.MultiScalarMult(scalars, elements).Bytes(), "%s: %d terms", g, n)
+		}
+	}
+}
+
+func TestReferenceAddNegate(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, g := range referenceCurves() {
+		c := newRefCurve(g)
+		ps := c.points(t, g, r)
+		for i, P := range ps {
+			assert.Equal(t, c.encode(c.neg(P)), g.Element().Negate(libPoint(t, g, c, P)).Bytes(), "%s: -point %d", g, i)
+			for j, Q := range ps {
+				assert.Equal(t, c.encode(c.add(P, Q)), g.Element().Add(libPoint(t, g, c, P), libPoint(t, g, c, Q)).Bytes(), "%s: point %d + point %d", g, i, j)
+				assert.Equal(t, i == j, libPoint(t, g, c, P).Equal(libPoint(t, g, c, Q)), "%s: point %d == point %d", g, i, j)
+			}
+		}
+	}
+}
+
+func TestReferenceDecode(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for _, g := range referenceCurves() {
+		c := newRefCurve(g)
+		n := c.size
+		var inputs [][]byte
+		for _, P := range c.points(t, g, r) {
+			b := c.encode(P)
+			inputs = append(inputs, b)
+			for _, prefix := range []byte{0, 2, 3, 5} {
+				other := append([]byte(nil), b...)
+				other[0] = prefix
+				inputs = append(inputs, other)
+			}
+			offCurve := append([]byte(nil), b...)
+			offCurve[len(b)-1] ^= 1
+			inputs = append(inputs, offCurve)
+
+			// x and y plus p, where they still fit
+			if P.x != nil {
+				for _, i := range []int{1, 1 + n} {
+					v := new(big.Int).SetBytes(b[i : i+n])
+					if v.Add(v, c.p).BitLen() <= 8*n {
+						big := append([]byte(nil), b...)
+						v.FillBytes(big[i : i+n])
+						inputs = append(inputs, big)
+					}
+				}
+			}
+		}
+		for i := 0; i < 16; i++ {
+			b := make([]byte, 1+2*n)
+			r.Read(b)
+			b[0] = 4
+			inputs = append(inputs, b)
+		}
+
+		for _, b := range inputs {
+			P, ok := c.decode(b)
+			e := g.Element()
+			err := e.FromBytes(b)
+			if assert.Equal(t, ok, err == nil, "%s: %x", g, b) && ok {
+				assert.Equal(t, c.encode(P), e.Bytes(), "%s: %x", g, b)
+			}
+		}
+	}
+}
+
+func TestReferenceScalar(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for _, g := range referenceCurves() {
+		c := newRefCurve(g)
+		n := g.ScalarLen()
+		max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(8*n)), big.NewInt(1))
+		for _, k := range append(c.scalars(r, 8), c.n, new(big.Int).Add(c.n, big.NewInt(1)), max) {
+			sc := g.Scalar()
+			err := sc.FromBytes(k.FillBytes(make([]byte, n)))
+			if k.Cmp(c.n) >= 0 {
+				assert.Error(t, err, "%s: %v", g, k)
+				continue
+			}
+			assert.NoError(t, err, "%s: %v", g, k)
+			assert.Equal(t, k.FillBytes(make([]byte, n)), sc.Bytes(), "%s: %v", g, k)
+
+			neg := new(big.Int).Sub(c.n, k)
+			assert.Equal(t, neg.Mod(neg, c.n).FillBytes(make([]byte, n)), g.Scalar().Negate(sc).Bytes(), "%s: -%v", g, k)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package elliptic
 
 import (
-	"errors"
 	"github.com/jtejido/spake2plus/internal/suite"
 	"math/big"
 )
@@ -49,12 +48,12 @@ func (i *scalar) Bytes() []byte {
 func (i *scalar) FromBytes(buf []byte) error {
 	l := (i.m.BitLen() + 7) / 8
 	if len(buf) != l {
-		return errors.New("wrong size buffer")
+		return suite.NewError(suite.BadLength, "wrong size buffer")
 	}
 
 	i.v.SetBytes(buf)
 	if i.v.Cmp(i.m) >= 0 {
-		return errors.New("value out of range")
+		return suite.NewError(suite.InvalidScalar, "value out of range")
 	}
 	return nil
 }
@@ -69,3 +68,12 @@ func (i *scalar) Negate(a suite.Scalar) suite.Scalar {
 	}
 	return i
 }
+
+// Zero clears the words backing v before resetting it, as SetInt64 alone would leave them in memory.
+func (i *scalar) Zero() {
+	w := i.v.Bits()
+	for j := range w {
+		w[j] = 0
+	}
+	i.v.SetInt64(0)
+}
@@ -0,0 +1,18 @@
+package elliptic
+
+import (
+	el "crypto/elliptic"
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// NewP256Sha256HkdfCmacAes128 is P-256 with SHA256, HKDF-SHA256 and CMAC-AES-128, as listed by
+// the draft. KcA and KcB are 16 bytes each, exactly an AES-128 key.
+func NewP256Sha256HkdfCmacAes128(mhf suite.MHF) *suite.Suite {
+	return suite.New(newCurve(el.P256(), p256m, p256n), suite.SHA256, suite.HKDF(suite.SHA256), suite.CMAC_AES128, mhf)
+}
+
+// NewP521Sha512HkdfCmacAes256 is P-521 with SHA512, HKDF-SHA512 and CMAC-AES-256. KcA and KcB are
+// 33 bytes each and get truncated to the AES-256 key size.
+func NewP521Sha512HkdfCmacAes256(mhf suite.MHF) *suite.Suite {
+	return suite.New(newCurve(el.P521(), p521m, p521n), suite.SHA512, suite.HKDF(suite.SHA512), suite.CMAC_AES256, mhf)
+}
@@ -2,73 +2,24 @@ package elliptic
 
 import (
 	el "crypto/elliptic"
-	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/hex"
 	"github.com/jtejido/spake2plus/internal/suite"
-	"golang.org/x/crypto/hkdf"
-	"hash"
-	"io"
-	"math/big"
 )
 
-type EllipticSha256HkdfHmac struct {
-	curve
-	mhf suite.MHF
-}
-
-func (s EllipticSha256HkdfHmac) Group() suite.Group {
-	return s.curve
-}
-
-func (s EllipticSha256HkdfHmac) Hash() hash.Hash {
-	return sha256.New()
-}
-
-func (s EllipticSha256HkdfHmac) HashDigest(content []byte) []byte {
-	hash := sha256.Sum256(content)
-	return hash[:]
-}
-
-func (s EllipticSha256HkdfHmac) HashSize() int {
-	return s.curve.ScalarLen()
-}
-
-func (s EllipticSha256HkdfHmac) DeriveKey(salt, ikm, info []byte) []byte {
-	hkdf := hkdf.New(sha256.New, ikm, salt, info)
-	key := make([]byte, s.curve.ScalarLen())
-	if _, err := io.ReadFull(hkdf, key); err != nil {
-		panic(err)
-	}
-	return key[:]
-}
-
-func (s EllipticSha256HkdfHmac) Mac(content, secret []byte) []byte {
-	mac := hmac.New(sha256.New, secret)
-	mac.Write(content)
-	hash := mac.Sum(nil)
-	return hash[:]
-}
-
-func (s EllipticSha256HkdfHmac) MacEqual(a, b []byte) bool {
-	return hmac.Equal(a, b)
-}
-
-func (s EllipticSha256HkdfHmac) Mhf(password, salt []byte) ([]byte, error) {
-	buf, err := s.mhf(password, salt, s.ScalarLen())
-	if err != nil {
-		return nil, err
-	}
-
-	// ensure that it is within order, Unmarshalling it to mod.Int won't do it for you.
-	oversized := new(big.Int)
-	oversized.SetBytes(buf)
-	return oversized.Mod(oversized, s.Group().Order()).Bytes(), nil
-}
-
 var p256m, p256n, p384m, p384n []byte
 var p521m, p521n []byte
 
+// The M and N of secp256k1 and the Brainpool curves are hash_to_curve (RFC 9380) of the messages
+// "<curve name> point generation seed (M)" and "<curve name> point generation seed (N)", e.g.
+// "brainpoolP256r1 point generation seed (M)", with DST "SPAKE2PLUS-V01-CS01-with-<suite ID>".
+// secp256k1 uses the RFC's secp256k1_XMD:SHA-256_SSWU_RO_. The RFC has no Brainpool suites, so
+// they map with SSWU directly, with Z chosen as in its appendix H.2 (-2, -5 and 7), in
+// brainpoolP256r1_XMD:SHA-256_SSWU_RO_, brainpoolP384r1_XMD:SHA-384_SSWU_RO_ and
+// brainpoolP512r1_XMD:SHA-512_SSWU_RO_, with L = 48, 72 and 96. Nobody knows their discrete
+// logarithms. Versions before these used try-and-increment, and don't interoperate.
+var secp256k1m, secp256k1n, bp256m, bp256n []byte
+var bp384m, bp384n, bp512m, bp512n []byte
+
 func init() {
 	p256m, _ = hex.DecodeString("02886e2f97ace46e55ba9dd7242579f2993b64e16ef3dcab95afd497333d8fa12f")
 	p256n, _ = hex.DecodeString("03d8bbd6c639c62937b04d997f38c3770719c629d7014d49a24b4f98baa1292b49")
@@ -76,25 +27,34 @@ func init() {
 	p384n, _ = hex.DecodeString("02c72cf2e390853a1c1c4ad816a62fd15824f56078918f43f922ca21518f9c543bb252c5490214cf9aa3f0baab4b665c10")
 	p521m, _ = hex.DecodeString("02003f06f38131b2ba2600791e82488e8d20ab889af753a41806c5db18d37d85608cfae06b82e4a72cd744c719193562a653ea1f119eef9356907edc9b56979962d7aa")
 	p521n, _ = hex.DecodeString("0200c7924b9ec017f3094562894336a53c50167ba8c5963876880542bc669e494b2532d76c5b53dfb349fdf69154b9e0048c58a42e8ed04cef052a3bc349d95575cd25")
+	secp256k1m, _ = hex.DecodeString("020d84775420a4ebcb342570badf6ff339e48d7f94ed985a975985fdd4e98a9b3c")
+	secp256k1n, _ = hex.DecodeString("0242d52a1280eb42a3afbdd71d7f1607c601ab3089d0b4fdce7eca2b4fb6fcf18b")
+	bp256m, _ = hex.DecodeString("037dd60c3fb52425974c3fd98c14e3f57dce4ab1dd8596425ed30256902c9651b6")
+	bp256n, _ = hex.DecodeString("0375741023db8bfbb951ea6b82826dfd46eeb64a3bfc65684020d19c430693e974")
+	bp384m, _ = hex.DecodeString("0281a70da53dd27b8000809ca386685fbb51946cbd90bff23d5fccd48944834a37f4d5f774f3d59899e29c91c4fb6761e0")
+	bp384n, _ = hex.DecodeString("037ee6a87df77c5bd62d79eb1201528beb53e70e8ad344b5f4260181fb1b627489057368cdb77be9d6ebd33cb26776cbff")
+	bp512m, _ = hex.DecodeString("02523960c96b6512afa85a7fb3cbdad5bb1728fddadd882e30139208fc9188728ec3ccf3c9159af81c3f7adc5edd42f00fdd40fdb6cdeebd4b3460187ff592d852")
+	bp512n, _ = hex.DecodeString("0265f4085581b560b25528d24d372f58ccb5b21f0b023a9712b5d9c64c58ef170d932ab3abaeac5ca13a745558b16513e4c3217a03bb21e579352164c1d093e4c4")
 }
 
-func NewP256Sha256HkdfHmac(mhf suite.MHF) *EllipticSha256HkdfHmac {
-	suite := new(EllipticSha256HkdfHmac)
-	suite.curve.Curve = el.P256()
-	suite.curve.p = suite.curve.Params()
-	suite.curve.m = p256m
-	suite.curve.n = p256n
-	suite.mhf = mhf
-	return suite
+// newCurve sets up the group for c with the given M and N encodings.
+func newCurve(c el.Curve, m, n []byte) curve {
+	return curve{Curve: c, m: m, n: n, p: c.Params(), fixed: new(fixedPoints)}
+}
+
+func NewP256Sha256HkdfHmac(mhf suite.MHF) *suite.Suite {
+	return suite.NewHkdfHmac(newCurve(el.P256(), p256m, p256n), suite.SHA256, mhf)
 }
 
 // Go's standard P-384 isn't constant time at the time of writing.
-func NewP384Sha256HkdfHmac(mhf suite.MHF) *EllipticSha256HkdfHmac {
-	suite := new(EllipticSha256HkdfHmac)
-	suite.curve.Curve = P384()
-	suite.curve.p = suite.curve.Params()
-	suite.curve.m = p384m
-	suite.curve.n = p384n
-	suite.mhf = mhf
-	return suite
+func NewP384Sha256HkdfHmac(mhf suite.MHF) *suite.Suite {
+	return suite.NewHkdfHmac(newCurve(P384(), p384m, p384n), suite.SHA256, mhf)
+}
+
+func NewSecp256k1Sha256HkdfHmac(mhf suite.MHF) *suite.Suite {
+	return suite.NewHkdfHmac(newCurve(secp256k1, secp256k1m, secp256k1n), suite.SHA256, mhf)
+}
+
+func NewBrainpoolP256r1Sha256HkdfHmac(mhf suite.MHF) *suite.Suite {
+	return suite.NewHkdfHmac(newCurve(brainpoolP256r1, bp256m, bp256n), suite.SHA256, mhf)
 }
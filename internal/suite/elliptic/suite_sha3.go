@@ -0,0 +1,11 @@
+package elliptic
+
+import (
+	el "crypto/elliptic"
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// NewP256Sha3HkdfKmac is P-256 with SHA3-256, HKDF-SHA3-256 and KMAC128.
+func NewP256Sha3HkdfKmac(mhf suite.MHF) *suite.Suite {
+	return suite.New(newCurve(el.P256(), p256m, p256n), suite.SHA3_256, suite.HKDF(suite.SHA3_256), suite.KMAC128, mhf)
+}
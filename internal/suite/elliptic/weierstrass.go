@@ -0,0 +1,273 @@
+package elliptic
+
+import (
+	el "crypto/elliptic"
+	"crypto/subtle"
+	"math/big"
+)
+
+// weierstrass is a short Weierstrass curve y² = x³ + ax + b of prime order with any a.
+// crypto/elliptic's generic CurveParams code only handles a = -3, which rules out secp256k1 (a = 0)
+// and the Brainpool r1 curves. Points are added with the complete projective formulas of Renes,
+// Costello and Batina (2016, algorithm 1), which have no exceptional cases on prime-order curves,
+// over a constant-time Montgomery field, and scalar multiplication uses 4-bit windows read from
+// their tables in constant time, so neither branches nor indexes memory on the scalar. As in
+// crypto/elliptic, (0, 0) stands for the point at infinity, and coordinates are passed as big.Int,
+// which isn't constant time but only carries points, never scalars.
+type weierstrass struct {
+	params *el.CurveParams
+	a      *big.Int
+	f      *field
+	fa, b3 fieldElement // a and 3b, in Montgomery form
+}
+
+func newWeierstrass(params *el.CurveParams, a *big.Int) *weierstrass {
+	w := &weierstrass{params: params, a: a, f: newField(params.P)}
+	w.f.fromBig(&w.fa, a)
+	b3 := new(big.Int).Mul(params.B, big.NewInt(3))
+	w.f.fromBig(&w.b3, b3.Mod(b3, params.P))
+	return w
+}
+
+func (w *weierstrass) Params() *el.CurveParams {
+	return w.params
+}
+
+func (w *weierstrass) IsOnCurve(x, y *big.Int) bool {
+	p := w.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+	return y2.Cmp(w.polynomial(x)) == 0
+}
+
+// polynomial returns x³ + ax + b mod p.
+func (w *weierstrass) polynomial(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+
+	ax := new(big.Int).Mul(w.a, x)
+	x3.Add(x3, ax)
+	x3.Add(x3, w.params.B)
+	x3.Mod(x3, w.params.P)
+
+	return x3
+}
+
+// projective is a point (X : Y : Z), with the point at infinity (0 : 1 : 0).
+type projective struct {
+	x, y, z fieldElement
+}
+
+func (w *weierstrass) identity(p *projective) {
+	*p = projective{y: w.f.one}
+}
+
+// fromAffine sets p to (x, y), which is (0, 0) for the point at infinity.
+func (w *weierstrass) fromAffine(p *projective, x, y *big.Int) {
+	f := w.f
+	f.fromBig(&p.x, x)
+	f.fromBig(&p.y, y)
+	p.z = f.one
+	inf := f.isZero(&p.x) & f.isZero(&p.y)
+	f.selectElement(&p.y, &f.one, &p.y, inf)
+	f.selectElement(&p.z, &fieldElement{}, &p.z, inf)
+}
+
+// toAffine returns p in affine coordinates. The point at infinity has Z = 0, whose inverse is
+// zero, so it comes out as (0, 0) without a branch.
+func (w *weierstrass) toAffine(p *projective) (*big.Int, *big.Int) {
+	f := w.f
+	var zInv, x, y fieldElement
+	f.invert(&zInv, &p.z)
+	f.mul(&x, &p.x, &zInv)
+	f.mul(&y, &p.y, &zInv)
+	return f.toBig(&x), f.toBig(&y)
+}
+
+// add sets r = p + q for any p and q, doubling included. r may alias p or q.
+func (w *weierstrass) add(r, p, q *projective) {
+	f := w.f
+	var t0, t1, t2, t3, t4, t5, x3, y3, z3 fieldElement
+
+	f.mul(&t0, &p.x, &q.x)
+	f.mul(&t1, &p.y, &q.y)
+	f.mul(&t2, &p.z, &q.z)
+	f.add(&t3, &p.x, &p.y)
+	f.add(&t4, &q.x, &q.y)
+	f.mul(&t3, &t3, &t4)
+	f.add(&t4, &t0, &t1)
+	f.sub(&t3, &t3, &t4)
+	f.add(&t4, &p.x, &p.z)
+	f.add(&t5, &q.x, &q.z)
+	f.mul(&t4, &t4, &t5)
+	f.add(&t5, &t0, &t2)
+	f.sub(&t4, &t4, &t5)
+	f.add(&t5, &p.y, &p.z)
+	f.add(&x3, &q.y, &q.z)
+	f.mul(&t5, &t5, &x3)
+	f.add(&x3, &t1, &t2)
+	f.sub(&t5, &t5, &x3)
+	f.mul(&z3, &w.fa, &t4)
+	f.mul(&x3, &w.b3, &t2)
+	f.add(&z3, &x3, &z3)
+	f.sub(&x3, &t1, &z3)
+	f.add(&z3, &t1, &z3)
+	f.mul(&y3, &x3, &z3)
+	f.add(&t1, &t0, &t0)
+	f.add(&t1, &t1, &t0)
+	f.mul(&t2, &w.fa, &t2)
+	f.mul(&t4, &w.b3, &t4)
+	f.add(&t1, &t1, &t2)
+	f.sub(&t2, &t0, &t2)
+	f.mul(&t2, &w.fa, &t2)
+	f.add(&t4, &t4, &t2)
+	f.mul(&t0, &t1, &t4)
+	f.add(&y3, &y3, &t0)
+	f.mul(&t0, &t5, &t4)
+	f.mul(&x3, &t3, &x3)
+	f.sub(&x3, &x3, &t0)
+	f.mul(&t0, &t3, &t1)
+	f.mul(&z3, &t5, &z3)
+	f.add(&z3, &z3, &t0)
+
+	r.x, r.y, r.z = x3, y3, z3
+}
+
+func (w *weierstrass) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	var p, q projective
+	w.fromAffine(&p, x1, y1)
+	w.fromAffine(&q, x2, y2)
+	w.add(&p, &p, &q)
+	return w.toAffine(&p)
+}
+
+func (w *weierstrass) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	var p projective
+	w.fromAffine(&p, x1, y1)
+	w.add(&p, &p, &p)
+	return w.toAffine(&p)
+}
+
+// window is 0P to 15P, for 4-bit windows.
+type window [16]projective
+
+func (w *weierstrass) window(t *window, p *projective) {
+	w.identity(&t[0])
+	t[1] = *p
+	for i := 2; i < len(t); i++ {
+		w.add(&t[i], &t[i-1], p)
+	}
+}
+
+// lookup sets p to t[i], reading every entry so that the access pattern doesn't depend on i.
+func (w *weierstrass) lookup(p *projective, t *window, i byte) {
+	f := w.f
+	*p = projective{}
+	for j := range t {
+		c := uint64(subtle.ConstantTimeByteEq(byte(j), i))
+		f.selectElement(&p.x, &t[j].x, &p.x, c)
+		f.selectElement(&p.y, &t[j].y, &p.y, c)
+		f.selectElement(&p.z, &t[j].z, &p.z, c)
+	}
+}
+
+// ScalarMult walks the big-endian scalar k in 4-bit windows from the top: four doublings and the
+// addition of a table entry each, the entry for a zero window being the point at infinity.
+func (w *weierstrass) ScalarMult(bx, by *big.Int, k []byte) (*big.Int, *big.Int) {
+	return w.MultiScalarMult([]*big.Int{bx}, []*big.Int{by}, [][]byte{k})
+}
+
+func (w *weierstrass) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return w.ScalarMult(w.params.Gx, w.params.Gy, k)
+}
+
+// MultiScalarMult computes the sum of k[i]*(x[i], y[i]) with Straus' method: the big-endian
+// scalars are walked together in 4-bit windows, so all terms share one run of doublings. Shorter
+// scalars are left-padded with zeros; only their lengths may show in the timing.
+func (w *weierstrass) MultiScalarMult(x, y []*big.Int, k [][]byte) (*big.Int, *big.Int) {
+	n := 0
+	tables := make([]window, len(k))
+	for i := range k {
+		if len(k[i]) > n {
+			n = len(k[i])
+		}
+		var p projective
+		w.fromAffine(&p, x[i], y[i])
+		w.window(&tables[i], &p)
+	}
+
+	var r, q projective
+	w.identity(&r)
+	for b := 0; b < n; b++ {
+		for shift := 4; shift >= 0; shift -= 4 {
+			for d := 0; d < 4; d++ {
+				w.add(&r, &r, &r)
+			}
+			for i := range k {
+				var nib byte
+				if j := b - (n - len(k[i])); j >= 0 {
+					nib = k[i][j] >> uint(shift) & 15
+				}
+				w.lookup(&q, &tables[i], nib)
+				w.add(&r, &r, &q)
+			}
+		}
+	}
+
+	return w.toAffine(&r)
+}
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid curve parameter")
+	}
+	return n
+}
+
+// Curve parameters from SEC 2 (secp256k1) and RFC 5639 (Brainpool).
+var (
+	secp256k1 = newWeierstrass(&el.CurveParams{
+		Name:    "secp256k1",
+		BitSize: 256,
+		P:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F"),
+		N:       hexInt("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141"),
+		B:       big.NewInt(7),
+		Gx:      hexInt("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798"),
+		Gy:      hexInt("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8"),
+	}, big.NewInt(0))
+
+	brainpoolP256r1 = newWeierstrass(&el.CurveParams{
+		Name:    "brainpoolP256r1",
+		BitSize: 256,
+		P:       hexInt("A9FB57DBA1EEA9BC3E660A909D838D726E3BF623D52620282013481D1F6E5377"),
+		N:       hexInt("A9FB57DBA1EEA9BC3E660A909D838D718C397AA3B561A6F7901E0E82974856A7"),
+		B:       hexInt("26DC5C6CE94A4B44F330B5D9BBD77CBF958416295CF7E1CE6BCCDC18FF8C07B6"),
+		Gx:      hexInt("8BD2AEB9CB7E57CB2C4B482FFC81B7AFB9DE27E1E3BD23C23A4453BD9ACE3262"),
+		Gy:      hexInt("547EF835C3DAC4FD97F8461A14611DC9C27745132DED8E545C1D54C72F046997"),
+	}, hexInt("7D5A0975FC2C3057EEF67530417AFFE7FB8055C126DC5C6CE94A4B44F330B5D9"))
+
+	brainpoolP384r1 = newWeierstrass(&el.CurveParams{
+		Name:    "brainpoolP384r1",
+		BitSize: 384,
+		P:       hexInt("8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B412B1DA197FB71123ACD3A729901D1A71874700133107EC53"),
+		N:       hexInt("8CB91E82A3386D280F5D6F7E50E641DF152F7109ED5456B31F166E6CAC0425A7CF3AB6AF6B7FC3103B883202E9046565"),
+		B:       hexInt("04A8C7DD22CE28268B39B55416F0447C2FB77DE107DCD2A62E880EA53EEB62D57CB4390295DBC9943AB78696FA504C11"),
+		Gx:      hexInt("1D1C64F068CF45FFA2A63A81B7C13F6B8847A3E77EF14FE3DB7FCAFE0CBD10E8E826E03436D646AAEF87B2E247D4AF1E"),
+		Gy:      hexInt("8ABE1D7520F9C2A45CB1EB8E95CFD55262B70B29FEEC5864E19C054FF99129280E4646217791811142820341263C5315"),
+	}, hexInt("7BC382C63D8C150C3C72080ACE05AFA0C2BEA28E4FB22787139165EFBA91F90F8AA5814A503AD4EB04A8C7DD22CE2826"))
+
+	brainpoolP512r1 = newWeierstrass(&el.CurveParams{
+		Name:    "brainpoolP512r1",
+		BitSize: 512,
+		P:       hexInt("AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA703308717D4D9B009BC66842AECDA12AE6A380E62881FF2F2D82C68528AA6056583A48F3"),
+		N:       hexInt("AADD9DB8DBE9C48B3FD4E6AE33C9FC07CB308DB3B3C9D20ED6639CCA70330870553E5C414CA92619418661197FAC10471DB1D381085DDADDB58796829CA90069"),
+		B:       hexInt("3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CADC083E67984050B75EBAE5DD2809BD638016F723"),
+		Gx:      hexInt("81AEE4BDD82ED9645A21322E9C4C6A9385ED9F70B5D916C1B43B62EEF4D0098EFF3B1F78E2D0D48D50D1687B93B97D5F7C6D5047406A5E688B352209BCB9F822"),
+		Gy:      hexInt("7DDE385D566332ECC0EABFA9CF7822FDF209F70024A57B1AA000C55B881F8111B2DCDE494A5F485E5BCA4BD88A2763AED1CA2B2FA8F0540678CD1E0F3AD80892"),
+	}, hexInt("7830A3318B603B89E2327145AC234CC594CBDD8D3DF91610A83441CAEA9863BC2DED5D5AA8253AA10A2EF1C98B9AC8B57F1117A72BF2C7B9E7C1AC4D77FC94CA"))
+)
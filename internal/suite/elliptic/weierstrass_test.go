@@ -0,0 +1,148 @@
+package elliptic
+
+import (
+	el "crypto/elliptic"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+type weierstrassSuite struct {
+	w    *weierstrass
+	m, n []byte
+}
+
+// M and N are decoded in init, so the table can't be a package variable.
+func weierstrassSuites() []weierstrassSuite {
+	return []weierstrassSuite{
+		{secp256k1, secp256k1m, secp256k1n},
+		{brainpoolP256r1, bp256m, bp256n},
+		{brainpoolP384r1, bp384m, bp384n},
+		{brainpoolP512r1, bp512m, bp512n},
+	}
+}
+
+func TestWeierstrassGroupOrder(t *testing.T) {
+	for _, s := range weierstrassSuites() {
+		p := s.w.params
+		assert.True(t, s.w.IsOnCurve(p.Gx, p.Gy), p.Name)
+
+		x, y := s.w.ScalarBaseMult(p.N.Bytes())
+		assert.Zero(t, x.Sign(), p.Name)
+		assert.Zero(t, y.Sign(), p.Name)
+
+		// (n-1)G = -G
+		nm1 := new(big.Int).Sub(p.N, big.NewInt(1))
+		x, y = s.w.ScalarBaseMult(nm1.Bytes())
+		assert.Equal(t, p.Gx, x, p.Name)
+		assert.Equal(t, new(big.Int).Sub(p.P, p.Gy), y, p.Name)
+
+		x, y = s.w.Double(p.Gx, p.Gy)
+		x2, y2 := s.w.Add(p.Gx, p.Gy, p.Gx, p.Gy)
+		assert.Equal(t, x, x2, p.Name)
+		assert.Equal(t, y, y2, p.Name)
+	}
+}
+
+func TestField(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, s := range weierstrassSuites() {
+		p := s.w.params.P
+		f := s.w.f
+		values := []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Sub(p, big.NewInt(1))}
+		for i := 0; i < 20; i++ {
+			values = append(values, new(big.Int).Rand(rnd, p))
+		}
+		for _, x := range values {
+			for _, y := range values {
+				var fx, fy, z fieldElement
+				f.fromBig(&fx, x)
+				f.fromBig(&fy, y)
+				check := func(op string, want *big.Int) {
+					assert.Equal(t, want.Mod(want, p).String(), f.toBig(&z).String(), "%s: %v %s %v", s.w.params.Name, x, op, y)
+				}
+				f.add(&z, &fx, &fy)
+				check("+", new(big.Int).Add(x, y))
+				f.sub(&z, &fx, &fy)
+				check("-", new(big.Int).Sub(x, y))
+				f.mul(&z, &fx, &fy)
+				check("*", new(big.Int).Mul(x, y))
+			}
+			var fx, z fieldElement
+			f.fromBig(&fx, x)
+			f.invert(&z, &fx)
+			if x.Sign() == 0 {
+				assert.Zero(t, f.toBig(&z).Sign())
+			} else {
+				assert.Equal(t, new(big.Int).ModInverse(x, p).String(), f.toBig(&z).String())
+			}
+			assert.Equal(t, uint64(x.Sign()^1), f.isZero(&fx))
+		}
+	}
+}
+
+// With P-256's parameters, weierstrass must agree with the standard library.
+func TestWeierstrassP256(t *testing.T) {
+	std := el.P256()
+	params := *std.Params()
+	w := newWeierstrass(&params, new(big.Int).Sub(params.P, big.NewInt(3)))
+	rnd := rand.New(rand.NewSource(1))
+	k := make([]byte, 32)
+	for i := 0; i < 20; i++ {
+		rnd.Read(k)
+		x, y := w.ScalarBaseMult(k)
+		wx, wy := std.ScalarBaseMult(k)
+		assert.Equal(t, wx, x)
+		assert.Equal(t, wy, y)
+
+		rnd.Read(k)
+		x2, y2 := w.ScalarMult(x, y, k)
+		wx, wy = std.ScalarMult(x, y, k)
+		assert.Equal(t, wx, x2)
+		assert.Equal(t, wy, y2)
+
+		sx, sy := w.Add(x, y, x2, y2)
+		wx, wy = std.Add(x, y, x2, y2)
+		assert.Equal(t, wx, sx)
+		assert.Equal(t, wy, sy)
+		dx, dy := w.Double(x, y)
+		wx, wy = std.Double(x, y)
+		assert.Equal(t, wx, dx)
+		assert.Equal(t, wy, dy)
+
+		// P + P and P + -P go through the same formulas
+		sx, sy = w.Add(x, y, x, y)
+		assert.Equal(t, dx, sx)
+		assert.Equal(t, dy, sy)
+		sx, sy = w.Add(x, y, x, new(big.Int).Sub(params.P, y))
+		assert.Zero(t, sx.Sign())
+		assert.Zero(t, sy.Sign())
+	}
+}
+
+// TestWeierstrassMN derives M and N with the hash_to_curve of h2c_test.go, which is pinned to the
+// RFC's vectors.
+func TestWeierstrassMN(t *testing.T) {
+	suites := h2cSuites()
+	for _, s := range weierstrassSuites() {
+		name := s.w.params.Name
+		for id, h := range suites {
+			if !strings.HasPrefix(id, name+"_") {
+				continue
+			}
+			for _, e := range []struct {
+				name string
+				want []byte
+			}{{"M", s.m}, {"N", s.n}} {
+				P := h.hashToCurve([]byte(name+" point generation seed ("+e.name+")"), []byte("SPAKE2PLUS-V01-CS01-with-"+id))
+				enc := make([]byte, 1+h.c.coordLen())
+				enc[0] = 2 | byte(P.y.Bit(0))
+				P.x.FillBytes(enc[1:])
+				assert.Equal(t, hex.EncodeToString(e.want), hex.EncodeToString(enc), id+" "+e.name)
+			}
+		}
+	}
+}
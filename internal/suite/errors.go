@@ -0,0 +1,85 @@
+package suite
+
+// Reason classifies why an operation failed.
+type Reason int
+
+const (
+	ReasonUnknown Reason = iota
+	InvalidPoint
+	SmallOrderPoint
+	BadLength
+	InvalidScalar
+	ConfirmationMismatch
+	UnknownSuite
+	InvalidState
+	MalformedMessage
+	IntegrityCheckFailed
+	InvalidInput
+	RateLimited
+)
+
+func (r Reason) String() string {
+	switch r {
+	case InvalidPoint:
+		return "invalid point"
+	case SmallOrderPoint:
+		return "small order point"
+	case BadLength:
+		return "bad length"
+	case InvalidScalar:
+		return "invalid scalar"
+	case ConfirmationMismatch:
+		return "confirmation mismatch"
+	case UnknownSuite:
+		return "unknown suite"
+	case InvalidState:
+		return "invalid state"
+	case MalformedMessage:
+		return "malformed message"
+	case IntegrityCheckFailed:
+		return "integrity check failed"
+	case InvalidInput:
+		return "invalid input"
+	case RateLimited:
+		return "rate limited"
+	}
+	return "unknown error"
+}
+
+// Error is returned for every failure with a known cause. Use errors.As to read the Reason, or
+// errors.Is against the Err* sentinels, which match any Error with the same Reason.
+type Error struct {
+	Reason Reason
+	Msg    string
+}
+
+// NewError returns an Error with the given reason and message.
+func NewError(r Reason, msg string) error {
+	return &Error{r, msg}
+}
+
+func (e *Error) Error() string {
+	if e.Msg != "" {
+		return e.Msg
+	}
+	return e.Reason.String()
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Reason == e.Reason
+}
+
+var (
+	ErrInvalidPoint         error = &Error{Reason: InvalidPoint}
+	ErrSmallOrderPoint      error = &Error{Reason: SmallOrderPoint}
+	ErrBadLength            error = &Error{Reason: BadLength}
+	ErrInvalidScalar        error = &Error{Reason: InvalidScalar}
+	ErrConfirmationMismatch error = &Error{Reason: ConfirmationMismatch}
+	ErrUnknownSuite         error = &Error{Reason: UnknownSuite}
+	ErrInvalidState         error = &Error{Reason: InvalidState}
+	ErrMalformedMessage     error = &Error{Reason: MalformedMessage}
+	ErrIntegrityCheckFailed error = &Error{Reason: IntegrityCheckFailed}
+	ErrInvalidInput         error = &Error{Reason: InvalidInput}
+	ErrRateLimited          error = &Error{Reason: RateLimited}
+)
@@ -1,12 +1,23 @@
 package suite
 
 import (
+	"context"
 	"hash"
+	"io"
 	"math/big"
 )
 
-// memory-hard function, you can feed either an Scrypt or Argon2i/d (RFC uses scrypt but you may not want to.)
-type MHF func(password, salt []byte, len int) ([]byte, error)
+// memory-hard function, you can feed either an Scrypt, Argon2i/d, PBKDF2 or Balloon (RFC uses scrypt but you may not want to.)
+type MHF interface {
+	Key(password, salt []byte, len int) ([]byte, error)
+	String() string // parameter descriptor, to be stored with the verifier
+}
+
+// ContextMHF is an MHF that can be cancelled, e.g. one that calls out to a key-stretching service.
+type ContextMHF interface {
+	MHF
+	KeyContext(ctx context.Context, password, salt []byte, len int) ([]byte, error)
+}
 
 type CipherSuiteID int
 
@@ -14,18 +25,23 @@ type CipherSuite interface {
 	Hash() hash.Hash
 	Group() Group
 	Mhf([]byte, []byte) ([]byte, error)
+	MhfContext(context.Context, []byte, []byte) ([]byte, error)
 	HashDigest([]byte) []byte
 	HashSize() int
 	DeriveKey([]byte, []byte, []byte) []byte
 	Mac([]byte, []byte) []byte
 	MacEqual([]byte, []byte) bool
+	ID() string      // names the group, hash, KDF and MAC
+	Context() []byte // bound at the start of the transcript when not empty
+	MhfDescriptor() string
+	EncodedMN() ([]byte, []byte) // encodings of M and N, not to be modified
 }
 
 type Group interface {
 	M() Element
 	N() Element
-	RandomElement() (Element, error)
-	RandomScalar() (Scalar, error)
+	RandomElement(rand io.Reader) (Element, error) // rand may be nil, in which case crypto/rand is used
+	RandomScalar(rand io.Reader) (Scalar, error)
 	CofactorScalar() Scalar
 	ClearCofactor(Element) Element
 	Order() *big.Int
@@ -34,12 +50,16 @@ type Group interface {
 	Scalar() Scalar   // Create new scalar
 	ElementLen() int  // Max length of element in bytes
 	Element() Element // Create new element
+	// MultiScalarMult returns the sum of scalars[i]*elements[i], where a nil element stands for
+	// the generator. It's as constant time as ScalarMult.
+	MultiScalarMult(scalars []Scalar, elements []Element) Element
 }
 
 type Scalar interface {
 	FromBytes([]byte) error
 	Bytes() []byte
 	Negate(a Scalar) Scalar
+	Zero() // overwrite the value in place, for wiping secrets
 }
 
 type Element interface {
@@ -51,3 +71,68 @@ type Element interface {
 	Negate(a Element) Element
 	ScalarMult(s Scalar, p Element) Element
 }
+
+// MultiScalarMultSeparately computes MultiScalarMult term by term, for groups with nothing
+// faster.
+func MultiScalarMultSeparately(g Group, scalars []Scalar, elements []Element) Element {
+	if len(scalars) != len(elements) {
+		panic("suite: mismatched scalars and elements")
+	}
+	sum := g.Element().Identity()
+	for i := range scalars {
+		sum.Add(sum, g.Element().ScalarMult(scalars[i], elements[i]))
+	}
+	return sum
+}
+
+// BatchEncoder is implemented by groups that encode many elements faster than one at a time, e.g.
+// by sharing one field inversion between them.
+type BatchEncoder interface {
+	EncodeElements(elements []Element) [][]byte
+}
+
+// EncodeElements returns the Bytes of every element, in one batch if g is a BatchEncoder.
+func EncodeElements(g Group, elements []Element) [][]byte {
+	if b, ok := g.(BatchEncoder); ok {
+		return b.EncodeElements(elements)
+	}
+	out := make([][]byte, len(elements))
+	for i, e := range elements {
+		out[i] = e.Bytes()
+	}
+	return out
+}
+
+// ElementAppender is implemented by elements that can encode themselves into a caller's buffer.
+type ElementAppender interface {
+	AppendBytes(dst []byte) []byte
+}
+
+// AppendElement appends the encoding of e to dst, without allocating if e is an ElementAppender
+// and dst has room.
+func AppendElement(dst []byte, e Element) []byte {
+	if a, ok := e.(ElementAppender); ok {
+		return a.AppendBytes(dst)
+	}
+	return append(dst, e.Bytes()...)
+}
+
+// CofactorClearer is implemented by groups that can clear the cofactor into an existing element.
+type CofactorClearer interface {
+	ClearCofactorTo(dst, e Element) Element
+}
+
+// ClearCofactorTo returns g.ClearCofactor(e), written to dst where g allows. The result may be e
+// itself in a prime-order group, or a new element for groups that aren't CofactorClearers.
+func ClearCofactorTo(g Group, dst, e Element) Element {
+	if c, ok := g.(CofactorClearer); ok {
+		return c.ClearCofactorTo(dst, e)
+	}
+	return g.ClearCofactor(e)
+}
+
+// SmallOrderChecker is implemented by groups with a faster check than clearing the cofactor and
+// comparing with the identity. IsSmallOrder reports whether ClearCofactor(e) is the identity.
+type SmallOrderChecker interface {
+	IsSmallOrder(e Element) bool
+}
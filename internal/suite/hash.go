@@ -0,0 +1,107 @@
+package suite
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"hash"
+	"sync"
+)
+
+// Hash is the transcript hash of a cipher suite.
+type Hash interface {
+	New() hash.Hash
+	String() string
+}
+
+type hashFunc struct {
+	name  string
+	new   func() hash.Hash
+	size  int
+	state *sync.Pool // of *hashState, so that digests and MACs don't allocate hash states
+}
+
+// hashState is a hash with scratch space for HMAC.
+type hashState struct {
+	h   hash.Hash
+	pad []byte // one block
+	sum []byte // inner digest, or a hashed key
+}
+
+func newHashFunc(name string, new func() hash.Hash) hashFunc {
+	return hashFunc{name, new, new().Size(), &sync.Pool{New: func() interface{} {
+		h := new()
+		return &hashState{h, make([]byte, h.BlockSize()), make([]byte, 0, h.Size())}
+	}}}
+}
+
+func (h hashFunc) New() hash.Hash {
+	return h.new()
+}
+
+func (h hashFunc) String() string {
+	return h.name
+}
+
+func (h hashFunc) get() *hashState {
+	st := h.state.Get().(*hashState)
+	st.h.Reset()
+	return st
+}
+
+func (h hashFunc) put(st *hashState) {
+	wipe(st.pad)
+	wipe(st.sum[:cap(st.sum)])
+	h.state.Put(st)
+}
+
+var (
+	SHA256     Hash = newHashFunc("SHA256", sha256.New)
+	SHA512     Hash = newHashFunc("SHA512", sha512.New)
+	SHA3_256   Hash = newHashFunc("SHA3-256", sha3.New256)
+	SHAKE256   Hash = newHashFunc("SHAKE256", func() hash.Hash { return &shake{sha3.NewShake256(), 64} })
+	BLAKE2b512 Hash = newHashFunc("BLAKE2b-512", func() hash.Hash { return newBlake2b(nil) })
+)
+
+// digest hashes content in one go.
+func digest(h Hash, content []byte) []byte {
+	hf, ok := h.(hashFunc)
+	if !ok {
+		d := h.New()
+		d.Write(content)
+		return d.Sum(nil)
+	}
+	st := hf.get()
+	defer hf.put(st)
+	st.h.Write(content)
+	return st.h.Sum(make([]byte, 0, hf.size))
+}
+
+func newBlake2b(key []byte) hash.Hash {
+	h, err := blake2b.New512(key)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// shake turns SHAKE256 into a hash.Hash with a fixed output length.
+type shake struct {
+	sha3.ShakeHash
+	size int
+}
+
+func (s *shake) Sum(b []byte) []byte {
+	out := make([]byte, s.size)
+	s.Clone().Read(out)
+	return append(b, out...)
+}
+
+func (s *shake) Size() int {
+	return s.size
+}
+
+func (s *shake) BlockSize() int {
+	return 136
+}
@@ -0,0 +1,93 @@
+package suite
+
+import (
+	"encoding/binary"
+	"golang.org/x/crypto/sha3"
+)
+
+// KMAC (NIST SP 800-185) on top of cSHAKE.
+
+type kmac struct {
+	name    string
+	rate    int
+	size    int // MAC output length
+	cshake  func(N, S []byte) sha3.ShakeHash
+	saltLen int // length of the default KDF salt, the KMAC input block less 4 bytes
+}
+
+var (
+	KMAC128 = kmac{"KMAC128", 168, 32, sha3.NewCShake128, 164}
+	KMAC256 = kmac{"KMAC256", 136, 64, sha3.NewCShake256, 132}
+)
+
+func leftEncode(x uint64) []byte {
+	var buf [9]byte
+	binary.BigEndian.PutUint64(buf[1:], x)
+	i := 1
+	for i < 8 && buf[i] == 0 {
+		i++
+	}
+	buf[i-1] = byte(9 - i)
+	return buf[i-1:]
+}
+
+func rightEncode(x uint64) []byte {
+	var buf [9]byte
+	binary.BigEndian.PutUint64(buf[:8], x)
+	i := 0
+	for i < 7 && buf[i] == 0 {
+		i++
+	}
+	buf[8] = byte(8 - i)
+	return buf[i:]
+}
+
+// sum returns KMAC(key, content, L, custom) for an output of length bytes.
+func (k kmac) sum(key, content, custom []byte, length int) []byte {
+	h := k.cshake([]byte("KMAC"), custom)
+
+	// bytepad(encode_string(key), rate)
+	pad := append(leftEncode(uint64(k.rate)), leftEncode(uint64(len(key))*8)...)
+	pad = append(pad, key...)
+	if r := len(pad) % k.rate; r != 0 {
+		pad = append(pad, make([]byte, k.rate-r)...)
+	}
+	h.Write(pad)
+	wipe(pad)
+
+	h.Write(content)
+	h.Write(rightEncode(uint64(length) * 8))
+
+	out := make([]byte, length)
+	h.Read(out)
+	return out
+}
+
+func (k kmac) String() string {
+	return k.name
+}
+
+func (k kmac) Sum(content, key []byte) []byte {
+	return k.sum(key, content, nil, k.size)
+}
+
+// Derive is the one-step KDF of NIST SP 800-56C with KMAC as auxiliary function:
+// KMAC(salt, counter || ikm || info, L, "KDF") with a single counter block of 1.
+func (k kmac) Derive(salt, ikm, info []byte, length int) []byte {
+	if salt == nil {
+		salt = make([]byte, k.saltLen)
+	}
+	x := make([]byte, 4, 4+len(ikm)+len(info))
+	binary.BigEndian.PutUint32(x, 1)
+	x = append(x, ikm...)
+	x = append(x, info...)
+	defer wipe(x)
+
+	return k.sum(salt, x, []byte("KDF"), length)
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
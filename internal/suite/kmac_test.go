@@ -0,0 +1,37 @@
+package suite
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// NIST SP 800-185 KMAC samples.
+func TestKMAC(t *testing.T) {
+	key, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f")
+	data := []byte{0, 1, 2, 3}
+
+	for _, c := range []struct {
+		k      kmac
+		custom string
+		size   int
+		want   string
+	}{
+		{KMAC128, "", 32, "e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e"},
+		{KMAC128, "My Tagged Application", 32, "3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5"},
+		{KMAC256, "My Tagged Application", 64, "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd"},
+	} {
+		got := c.k.sum(key, data, []byte(c.custom), c.size)
+		assert.Equal(t, c.want, hex.EncodeToString(got), c.k.name)
+	}
+
+	assert.Equal(t, KMAC128.sum(key, data, nil, 32), KMAC128.Sum(data, key))
+}
+
+func TestKMACEncodings(t *testing.T) {
+	assert.Equal(t, []byte{1, 0}, leftEncode(0))
+	assert.Equal(t, []byte{1, 168}, leftEncode(168))
+	assert.Equal(t, []byte{2, 1, 0}, leftEncode(256))
+	assert.Equal(t, []byte{0, 1}, rightEncode(0))
+	assert.Equal(t, []byte{1, 0, 2}, rightEncode(256))
+}
@@ -0,0 +1,140 @@
+package suite
+
+import (
+	"crypto/hmac"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// KDF derives key confirmation keys from Ka.
+type KDF interface {
+	Derive(salt, ikm, info []byte, length int) []byte
+	String() string
+}
+
+// MAC computes key confirmation messages.
+type MAC interface {
+	Sum(content, key []byte) []byte
+	String() string
+}
+
+type hkdfFunc struct {
+	h Hash
+}
+
+// HKDF returns HKDF (RFC 5869) over h.
+func HKDF(h Hash) KDF {
+	return hkdfFunc{h}
+}
+
+// hkdfCounter[i] is i, for the block counter of HKDF-Expand.
+var hkdfCounter = func() (c [256]byte) {
+	for i := range c {
+		c[i] = byte(i)
+	}
+	return
+}()
+
+func (k hkdfFunc) Derive(salt, ikm, info []byte, length int) []byte {
+	if h, ok := k.h.(hashFunc); ok {
+		return h.hkdf(salt, ikm, info, length)
+	}
+	r := hkdf.New(k.h.New, ikm, salt, info)
+	key := make([]byte, length)
+	if _, err := io.ReadFull(r, key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func (k hkdfFunc) String() string {
+	return "HKDF-" + k.h.String()
+}
+
+type hmacFunc struct {
+	h Hash
+}
+
+// HMAC returns HMAC over h.
+func HMAC(h Hash) MAC {
+	return hmacFunc{h}
+}
+
+func (m hmacFunc) Sum(content, key []byte) []byte {
+	if h, ok := m.h.(hashFunc); ok {
+		return h.hmac(nil, key, content)
+	}
+	mac := hmac.New(m.h.New, key)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+func (m hmacFunc) String() string {
+	return "HMAC-" + m.h.String()
+}
+
+// hmac appends HMAC(key, content) (RFC 2104) to dst, using a pooled hash state instead of the
+// two fresh ones crypto/hmac sets up.
+func (h hashFunc) hmac(dst, key []byte, content ...[]byte) []byte {
+	st := h.get()
+	defer h.put(st)
+
+	if len(key) > len(st.pad) {
+		st.h.Write(key)
+		key = st.h.Sum(st.sum[:0])
+		st.h.Reset()
+	}
+	copy(st.pad, key)
+	for i := range st.pad {
+		st.pad[i] ^= 0x36
+	}
+	st.h.Write(st.pad)
+	for _, c := range content {
+		st.h.Write(c)
+	}
+	inner := st.h.Sum(st.sum[:0])
+
+	st.h.Reset()
+	for i := range st.pad {
+		st.pad[i] ^= 0x36 ^ 0x5c
+	}
+	st.h.Write(st.pad)
+	st.h.Write(inner)
+	return st.h.Sum(dst)
+}
+
+// hkdf is HKDF (RFC 5869) on top of hmac.
+func (h hashFunc) hkdf(salt, ikm, info []byte, length int) []byte {
+	size := h.size
+	if length > 255*size {
+		panic("suite: HKDF output too long")
+	}
+
+	prk := h.hmac(make([]byte, 0, size), salt, ikm)
+	defer wipe(prk)
+
+	out := make([]byte, 0, length+size)
+	var prev []byte
+	for i := 1; len(out) < length; i++ {
+		n := len(out)
+		out = h.hmac(out, prk, prev, info, hkdfCounter[i:i+1])
+		prev = out[n:]
+	}
+	wipe(out[length:])
+	return out[:length:length]
+}
+
+type blake2bMAC struct{}
+
+// BLAKE2bMAC is BLAKE2b-512 in keyed mode (RFC 7693). Keys may be at most 64 bytes long.
+var BLAKE2bMAC MAC = blake2bMAC{}
+
+func (blake2bMAC) Sum(content, key []byte) []byte {
+	h := newBlake2b(key)
+	h.Write(content)
+	return h.Sum(nil)
+}
+
+func (blake2bMAC) String() string {
+	return "BLAKE2b-512-MAC"
+}
@@ -0,0 +1,31 @@
+package suite
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"testing"
+)
+
+// The pooled HMAC and HKDF must agree with crypto/hmac and x/crypto/hkdf, including for keys
+// longer than a block and outputs of several blocks.
+func TestPooledHMACAndHKDF(t *testing.T) {
+	for _, h := range []Hash{SHA256, SHA512, SHA3_256, SHAKE256, BLAKE2b512} {
+		for _, keyLen := range []int{0, 16, 200} {
+			key := bytes.Repeat([]byte{0x0b}, keyLen)
+			content := []byte("Hi There")
+
+			mac := hmac.New(h.New, key)
+			mac.Write(content)
+			assert.Equal(t, mac.Sum(nil), HMAC(h).Sum(content, key), h.String())
+
+			for _, length := range []int{16, 64, 150} {
+				want := make([]byte, length)
+				io.ReadFull(hkdf.New(h.New, content, key, []byte("info")), want)
+				assert.Equal(t, want, HKDF(h).Derive(key, content, []byte("info"), length), h.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package suite
+
+import (
+	"context"
+	"crypto/hmac"
+	"hash"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Suite is a CipherSuite put together from a group, a transcript hash, a KDF and a MAC. All lengths
+// follow from the group: the MHF output and the KDF output are as long as a scalar, and so is
+// HashSize, which splits the MHF output into w0 and w1.
+type Suite struct {
+	group   Group
+	hash    Hash
+	kdf     KDF
+	mac     MAC
+	mhf     MHF
+	context []byte
+	fixed   *fixedEncodings
+}
+
+// fixedEncodings holds the encodings of M and N, which go into every transcript.
+type fixedEncodings struct {
+	once sync.Once
+	m, n []byte
+}
+
+// New composes a cipher suite. Its ID is bound into every transcript as the context, so two
+// suites that differ in any component never agree on a key. It panics if KcA and KcB, half of the
+// KDF output each, are too short for a MAC with a fixed key size.
+func New(group Group, hash Hash, kdf KDF, mac MAC, mhf MHF) *Suite {
+	if ks, ok := mac.(interface{ KeySize() int }); ok && group.ScalarLen()/2 < ks.KeySize() {
+		panic("suite: " + mac.String() + " needs longer confirmation keys than " + group.String() + " provides")
+	}
+	s := &Suite{group: group, hash: hash, kdf: kdf, mac: mac, mhf: mhf, fixed: new(fixedEncodings)}
+	s.context = []byte(s.ID())
+	return s
+}
+
+// NewHkdfHmac is New with HKDF and HMAC over hash and no context, the shape of the draft's
+// suites. The suites that predate New are built this way and keep their transcripts.
+func NewHkdfHmac(group Group, hash Hash, mhf MHF) *Suite {
+	return New(group, hash, HKDF(hash), HMAC(hash), mhf).WithContext(nil)
+}
+
+// WithContext returns a copy of s that binds context into the transcript instead of its ID.
+func (s *Suite) WithContext(context []byte) *Suite {
+	c := *s
+	c.context = append([]byte(nil), context...)
+	return &c
+}
+
+func (s *Suite) ID() string {
+	return strings.Join([]string{s.group.String(), s.hash.String(), s.kdf.String(), s.mac.String()}, "_")
+}
+
+func (s *Suite) Context() []byte {
+	return s.context
+}
+
+// EncodedMN returns the encodings of M and N, computed once. Callers must not modify them.
+func (s *Suite) EncodedMN() ([]byte, []byte) {
+	s.fixed.once.Do(func() {
+		s.fixed.m = s.group.M().Bytes()
+		s.fixed.n = s.group.N().Bytes()
+	})
+	return s.fixed.m, s.fixed.n
+}
+
+func (s *Suite) Group() Group {
+	return s.group
+}
+
+func (s *Suite) Hash() hash.Hash {
+	return s.hash.New()
+}
+
+func (s *Suite) HashDigest(content []byte) []byte {
+	return digest(s.hash, content)
+}
+
+func (s *Suite) HashSize() int {
+	return s.group.ScalarLen()
+}
+
+func (s *Suite) DeriveKey(salt, ikm, info []byte) []byte {
+	return s.kdf.Derive(salt, ikm, info, s.group.ScalarLen())
+}
+
+func (s *Suite) Mac(content, secret []byte) []byte {
+	return s.mac.Sum(content, secret)
+}
+
+func (s *Suite) MacEqual(a, b []byte) bool {
+	return hmac.Equal(a, b)
+}
+
+func (s *Suite) Mhf(password, salt []byte) ([]byte, error) {
+	return s.MhfContext(context.Background(), password, salt)
+}
+
+// MhfContext is Mhf with cancellation for a ContextMHF. Any other MHF can't be stopped once
+// started, so ctx is only checked before.
+func (s *Suite) MhfContext(ctx context.Context, password, salt []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	var err error
+	if c, ok := s.mhf.(ContextMHF); ok {
+		buf, err = c.KeyContext(ctx, password, salt, s.group.ScalarLen())
+	} else {
+		buf, err = s.mhf.Key(password, salt, s.group.ScalarLen())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	oversized := new(big.Int)
+	oversized.SetBytes(buf)
+	return oversized.Mod(oversized, s.group.Order()).Bytes(), nil
+}
+
+// MhfDescriptor returns the descriptor of the MHF, or "" if there is none.
+func (s *Suite) MhfDescriptor() string {
+	if s.mhf == nil {
+		return ""
+	}
+	return s.mhf.String()
+}
@@ -0,0 +1,196 @@
+package kerberos
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"hash"
+	"io"
+)
+
+// Encryption type numbers.
+const (
+	AES128CTSHMACSHA256128 int32 = 19
+	AES256CTSHMACSHA384192 int32 = 20
+)
+
+var errIntegrity = suite.NewError(suite.IntegrityCheckFailed, "ciphertext integrity check failed")
+
+// Enctype is the part of a Kerberos encryption type the SPAKE mechanism
+// needs: a PRF for deriving keys from the initial reply key, and
+// authenticated encryption for the second factor data.
+type Enctype interface {
+	Number() int32
+	KeySize() int
+	PRF(key, input []byte) []byte
+	Encrypt(key []byte, usage uint32, plaintext []byte) ([]byte, error)
+	Decrypt(key []byte, usage uint32, ciphertext []byte) ([]byte, error)
+}
+
+// aesSHA2 implements the RFC 8009 encryption types.
+type aesSHA2 struct {
+	number  int32
+	keySize int
+	macSize int
+	hash    func() hash.Hash
+}
+
+// AES128SHA256 returns aes128-cts-hmac-sha256-128.
+func AES128SHA256() Enctype {
+	return aesSHA2{AES128CTSHMACSHA256128, 16, 16, sha256.New}
+}
+
+// AES256SHA384 returns aes256-cts-hmac-sha384-192.
+func AES256SHA384() Enctype {
+	return aesSHA2{AES256CTSHMACSHA384192, 32, 24, sha512.New384}
+}
+
+func (e aesSHA2) Number() int32 {
+	return e.number
+}
+
+func (e aesSHA2) KeySize() int {
+	return e.keySize
+}
+
+// kdf is KDF-HMAC-SHA2(key, label, context, k) with k expressed in bytes.
+func (e aesSHA2) kdf(key, label, context []byte, k int) []byte {
+	var buf [4]byte
+	mac := hmac.New(e.hash, key)
+	binary.BigEndian.PutUint32(buf[:], 1)
+	mac.Write(buf[:])
+	mac.Write(label)
+	mac.Write([]byte{0})
+	mac.Write(context)
+	binary.BigEndian.PutUint32(buf[:], uint32(k*8))
+	mac.Write(buf[:])
+	return mac.Sum(nil)[:k]
+}
+
+func (e aesSHA2) usageKeys(key []byte, usage uint32) (ke, ki []byte) {
+	label := make([]byte, 5)
+	binary.BigEndian.PutUint32(label, usage)
+	label[4] = 0xAA
+	ke = e.kdf(key, label, nil, e.keySize)
+	label[4] = 0x55
+	ki = e.kdf(key, label, nil, e.macSize)
+	return
+}
+
+// PRF is KDF-HMAC-SHA2(key, "prf", input, 256) for aes128-cts-hmac-sha256-128 and 384 for
+// aes256-cts-hmac-sha384-192, as RFC 8009, section 5 defines it.
+func (e aesSHA2) PRF(key, input []byte) []byte {
+	return e.kdf(key, []byte("prf"), input, e.hash().Size())
+}
+
+func (e aesSHA2) Encrypt(key []byte, usage uint32, plaintext []byte) ([]byte, error) {
+	ke, ki := e.usageKeys(key, usage)
+
+	block, err := aes.NewCipher(ke)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, aes.BlockSize+len(plaintext))
+	if _, err := io.ReadFull(rand.Reader, data[:aes.BlockSize]); err != nil {
+		return nil, err
+	}
+	copy(data[aes.BlockSize:], plaintext)
+
+	c := ctsEncrypt(block, data)
+
+	mac := hmac.New(e.hash, ki)
+	mac.Write(make([]byte, aes.BlockSize))
+	mac.Write(c)
+
+	return append(c, mac.Sum(nil)[:e.macSize]...), nil
+}
+
+func (e aesSHA2) Decrypt(key []byte, usage uint32, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize+e.macSize {
+		return nil, errIntegrity
+	}
+	ke, ki := e.usageKeys(key, usage)
+
+	c, h := ciphertext[:len(ciphertext)-e.macSize], ciphertext[len(ciphertext)-e.macSize:]
+
+	mac := hmac.New(e.hash, ki)
+	mac.Write(make([]byte, aes.BlockSize))
+	mac.Write(c)
+	if !hmac.Equal(h, mac.Sum(nil)[:e.macSize]) {
+		return nil, errIntegrity
+	}
+
+	block, err := aes.NewCipher(ke)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctsDecrypt(block, c)[aes.BlockSize:], nil
+}
+
+// ctsEncrypt is CBC mode with ciphertext stealing (the RFC 3962 variant, which
+// always swaps the final two blocks) and an all-zero IV. len(in) must be at
+// least one block.
+func ctsEncrypt(block cipher.Block, in []byte) []byte {
+	bs := block.BlockSize()
+	if len(in) == bs {
+		out := make([]byte, bs)
+		block.Encrypt(out, in)
+		return out
+	}
+
+	n := (len(in) + bs - 1) / bs
+	r := len(in) - (n-1)*bs
+
+	padded := make([]byte, n*bs)
+	copy(padded, in)
+	cipher.NewCBCEncrypter(block, make([]byte, bs)).CryptBlocks(padded, padded)
+
+	out := make([]byte, len(in))
+	copy(out, padded[:(n-2)*bs])
+	copy(out[(n-2)*bs:], padded[(n-1)*bs:])
+	copy(out[(n-1)*bs:], padded[(n-2)*bs:(n-2)*bs+r])
+	return out
+}
+
+func ctsDecrypt(block cipher.Block, in []byte) []byte {
+	bs := block.BlockSize()
+	out := make([]byte, len(in))
+	if len(in) == bs {
+		block.Decrypt(out, in)
+		return out
+	}
+
+	n := (len(in) + bs - 1) / bs
+	r := len(in) - (n-1)*bs
+
+	iv := make([]byte, bs)
+	if n > 2 {
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, in[:(n-2)*bs])
+		iv = in[(n-3)*bs : (n-2)*bs]
+	}
+
+	// The full block stolen from the penultimate position was encrypted last.
+	x := make([]byte, bs)
+	block.Decrypt(x, in[(n-2)*bs:(n-1)*bs])
+
+	penultimate := make([]byte, bs)
+	copy(penultimate, in[(n-1)*bs:])
+	copy(penultimate[r:], x[r:])
+	for i := 0; i < r; i++ {
+		out[(n-1)*bs+i] = x[i] ^ penultimate[i]
+	}
+
+	block.Decrypt(out[(n-2)*bs:(n-1)*bs], penultimate)
+	for i := 0; i < bs; i++ {
+		out[(n-2)*bs+i] ^= iv[i]
+	}
+
+	return out
+}
@@ -0,0 +1,111 @@
+package kerberos
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+// Fuzz targets for the PA-SPAKE messages and the code that handles them. Without -fuzz they run
+// as tests over the seeds.
+
+// messageSeeds returns a real support, challenge and response for every group, and a few
+// truncations of each.
+func messageSeeds(f *testing.F) [][]byte {
+	var seeds [][]byte
+	e := AES128SHA256()
+	for _, g := range testGroups {
+		key := make([]byte, e.KeySize())
+		client, kdc := NewClient(key, e, g), NewKDC(key, e, g)
+		support, err := client.Support()
+		if err != nil {
+			f.Fatal(err)
+		}
+		challenge, err := kdc.Challenge(support)
+		if err != nil {
+			f.Fatal(err)
+		}
+		response, _, err := client.Respond(challenge, reqBody)
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, m := range [][]byte{support, challenge, response} {
+			seeds = append(seeds, m, m[:len(m)/2], m[:len(m)-1], append(m, 0))
+		}
+	}
+	return append(seeds, nil, []byte{0xa0, 0x80}, []byte{0xa1, 0x84, 0xff, 0xff, 0xff, 0xff})
+}
+
+func FuzzParsePASPAKE(f *testing.F) {
+	for _, m := range messageSeeds(f) {
+		f.Add(m)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		m, err := ParsePASPAKE(b)
+		if err != nil {
+			return
+		}
+		enc, err := m.Marshal()
+		if err != nil {
+			t.Fatalf("%x parses, but doesn't marshal: %v", b, err)
+		}
+		if _, err := ParsePASPAKE(enc); err != nil {
+			t.Fatalf("%x parses, but its encoding %x doesn't: %v", b, enc, err)
+		}
+	})
+}
+
+func FuzzKDCChallenge(f *testing.F) {
+	for _, m := range messageSeeds(f) {
+		f.Add(m)
+	}
+	f.Fuzz(func(t *testing.T, support []byte) {
+		e := AES128SHA256()
+		NewKDC(make([]byte, e.KeySize()), e, testGroups...).Challenge(support)
+	})
+}
+
+func FuzzClientRespond(f *testing.F) {
+	for _, m := range messageSeeds(f) {
+		f.Add(m)
+	}
+	f.Fuzz(func(t *testing.T, challenge []byte) {
+		e := AES128SHA256()
+		NewClient(make([]byte, e.KeySize()), e, testGroups...).Respond(challenge, reqBody)
+	})
+}
+
+func FuzzKDCVerify(f *testing.F) {
+	for _, m := range messageSeeds(f) {
+		for g := range testGroups {
+			f.Add(uint8(g), m)
+		}
+	}
+	f.Fuzz(func(t *testing.T, which uint8, response []byte) {
+		e := AES128SHA256()
+		kdc := NewKDC(make([]byte, e.KeySize()), e, testGroups[int(which)%len(testGroups)])
+		if _, err := kdc.Challenge(nil); err != nil {
+			t.Fatal(err)
+		}
+		kdc.Verify(response, reqBody)
+	})
+}
+
+// ctsDecrypt sees whatever passes the MAC, so it must handle any length of at least one block.
+func FuzzCTS(f *testing.F) {
+	for _, n := range []int{16, 17, 31, 32, 33, 48, 100} {
+		f.Add(bytes.Repeat([]byte{byte(n)}, n))
+	}
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Fuzz(func(t *testing.T, in []byte) {
+		if len(in) < aes.BlockSize {
+			return
+		}
+		if out := ctsEncrypt(block, ctsDecrypt(block, in)); !bytes.Equal(out, in) {
+			t.Fatalf("%x does not round-trip: %x", in, out)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package kerberos
+
+import (
+	el "crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"github.com/jtejido/spake2plus/internal/suite/ed25519"
+	"github.com/jtejido/spake2plus/internal/suite/elliptic"
+	"hash"
+	"math/big"
+)
+
+// Group numbers as assigned by the Kerberos SPAKE Groups registry.
+const (
+	Edwards25519 int32 = 1
+	P256         int32 = 2
+	P384         int32 = 3
+	P521         int32 = 4
+)
+
+var errUnknownGroup = suite.NewError(suite.UnknownSuite, "unknown SPAKE group")
+
+// group ties a registry number to one of this library's groups, along with
+// the transcript hash and the byte order used when reducing the multiplier.
+// The NIST groups carry their curve, as their elements go on the wire in the
+// compressed form of SEC 1 rather than the library's uncompressed one.
+type group struct {
+	number       int32
+	g            suite.Group
+	hash         func() hash.Hash
+	littleEndian bool
+	curve        el.Curve
+	cofactor     int64
+}
+
+func lookupGroup(number int32) (*group, error) {
+	switch number {
+	case Edwards25519:
+		return &group{number, ed25519.NewEdwards25519Sha256HkdfHmac(nil).Group(), sha256.New, true, nil, 8}, nil
+	case P256:
+		return &group{number, elliptic.NewP256Sha256HkdfHmac(nil).Group(), sha256.New, false, el.P256(), 1}, nil
+	case P384:
+		return &group{number, elliptic.NewP384Sha512HkdfHmac(nil).Group(), sha512.New384, false, elliptic.P384(), 1}, nil
+	case P521:
+		return &group{number, elliptic.NewP521Sha512HkdfHmac(nil).Group(), sha512.New, false, el.P521(), 1}, nil
+	}
+
+	return nil, errUnknownGroup
+}
+
+// encode returns the wire encoding of e.
+func (g *group) encode(e suite.Element) []byte {
+	b := e.Bytes()
+	if g.curve == nil {
+		return b
+	}
+	size := (len(b) - 1) / 2
+	return append([]byte{2 | b[len(b)-1]&1}, b[1:1+size]...)
+}
+
+// decode parses the wire encoding of an element.
+func (g *group) decode(b []byte) (suite.Element, error) {
+	e := g.g.Element()
+	if g.curve != nil {
+		x, y := el.UnmarshalCompressed(g.curve, b)
+		if x == nil {
+			return nil, errInvalidElement
+		}
+		b = el.Marshal(g.curve, x, y)
+	}
+	if err := e.FromBytes(b); err != nil {
+		return nil, errInvalidElement
+	}
+	return e, nil
+}
+
+// clearCofactor returns h*e, or e itself in the groups of prime order.
+func (g *group) clearCofactor(e suite.Element) suite.Element {
+	if g.cofactor == 1 {
+		return e
+	}
+	return g.g.ClearCofactor(e)
+}
+
+// multiplierLen is the number of PRF+ output bytes used to derive w.
+func (g *group) multiplierLen() int {
+	return g.g.ScalarLen()
+}
+
+// multiplier reduces the PRF+ output modulo the group order, following the
+// byte order of the group's scalar encoding.
+func (g *group) multiplier(wBytes []byte) (suite.Scalar, error) {
+	b := wBytes
+	if g.littleEndian {
+		b = reverse(wBytes)
+	}
+	return g.scalar(new(big.Int).SetBytes(b))
+}
+
+// scalar reduces n modulo the group order. The library's scalars are all
+// encoded big-endian.
+func (g *group) scalar(n *big.Int) (suite.Scalar, error) {
+	n.Mod(n, g.g.Order())
+
+	buf := make([]byte, g.g.ScalarLen())
+	n.FillBytes(buf)
+
+	sc := g.g.Scalar()
+	if err := sc.FromBytes(buf); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func reverse(p []byte) []byte {
+	q := make([]byte, len(p))
+	for i := 0; 2*i < len(p); i++ {
+		j := len(p) - 1 - i
+		q[i], q[j] = p[j], p[i]
+	}
+	return q
+}
@@ -0,0 +1,159 @@
+package kerberos
+
+import (
+	"encoding/asn1"
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// Second factor types.
+const (
+	SFNone int32 = 1
+)
+
+// PA-SPAKE choice tags.
+const (
+	tagSupport   = 0
+	tagChallenge = 1
+	tagResponse  = 2
+	tagEncData   = 3
+)
+
+var errMalformedMessage = suite.NewError(suite.MalformedMessage, "malformed PA-SPAKE message")
+
+//	SPAKESupport ::= SEQUENCE {
+//	    groups      [0] SEQUENCE (SIZE(1..MAX)) OF Int32,
+//	    ...
+//	}
+type Support struct {
+	Groups []int32 `asn1:"explicit,tag:0"`
+}
+
+//	SPAKESecondFactor ::= SEQUENCE {
+//	    type        [0] Int32,
+//	    data        [1] OCTET STRING OPTIONAL
+//	}
+type SecondFactor struct {
+	Type int32  `asn1:"explicit,tag:0"`
+	Data []byte `asn1:"optional,explicit,tag:1"`
+}
+
+//	SPAKEChallenge ::= SEQUENCE {
+//	    group       [0] Int32,
+//	    pubkey      [1] OCTET STRING,
+//	    factors     [2] SEQUENCE (SIZE(1..MAX)) OF SPAKESecondFactor,
+//	    ...
+//	}
+type Challenge struct {
+	Group   int32          `asn1:"explicit,tag:0"`
+	PubKey  []byte         `asn1:"explicit,tag:1"`
+	Factors []SecondFactor `asn1:"explicit,tag:2"`
+}
+
+//	EncryptedData ::= SEQUENCE {
+//	    etype       [0] Int32,
+//	    kvno        [1] UInt32 OPTIONAL,
+//	    cipher      [2] OCTET STRING
+//	}
+type EncryptedData struct {
+	Etype  int32  `asn1:"explicit,tag:0"`
+	Kvno   int64  `asn1:"optional,explicit,tag:1"`
+	Cipher []byte `asn1:"explicit,tag:2"`
+}
+
+//	SPAKEResponse ::= SEQUENCE {
+//	    pubkey      [0] OCTET STRING,
+//	    factor      [1] EncryptedData,
+//	    ...
+//	}
+type Response struct {
+	PubKey []byte        `asn1:"explicit,tag:0"`
+	Factor EncryptedData `asn1:"explicit,tag:1"`
+}
+
+//	PASPAKE ::= CHOICE {
+//	    support     [0] SPAKESupport,
+//	    challenge   [1] SPAKEChallenge,
+//	    response    [2] SPAKEResponse,
+//	    encdata     [3] EncryptedData,
+//	    ...
+//	}
+//
+// Exactly one field is set.
+type PASPAKE struct {
+	Support   *Support
+	Challenge *Challenge
+	Response  *Response
+	EncData   *EncryptedData
+}
+
+// Marshal returns the DER encoding of the message.
+func (m *PASPAKE) Marshal() ([]byte, error) {
+	var (
+		tag int
+		v   interface{}
+		n   int
+	)
+	if m.Support != nil {
+		tag, v, n = tagSupport, *m.Support, n+1
+	}
+	if m.Challenge != nil {
+		tag, v, n = tagChallenge, *m.Challenge, n+1
+	}
+	if m.Response != nil {
+		tag, v, n = tagResponse, *m.Response, n+1
+	}
+	if m.EncData != nil {
+		tag, v, n = tagEncData, *m.EncData, n+1
+	}
+	if n != 1 {
+		return nil, errMalformedMessage
+	}
+
+	inner, err := asn1.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        tag,
+		IsCompound: true,
+		Bytes:      inner,
+	})
+}
+
+// ParsePASPAKE decodes a DER encoded PA-SPAKE message. Anything else is a
+// MalformedMessage error.
+func ParsePASPAKE(b []byte) (*PASPAKE, error) {
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(b, &raw)
+	if err != nil || len(rest) != 0 || raw.Class != asn1.ClassContextSpecific || !raw.IsCompound {
+		return nil, errMalformedMessage
+	}
+
+	m := new(PASPAKE)
+	var v interface{}
+	switch raw.Tag {
+	case tagSupport:
+		m.Support = new(Support)
+		v = m.Support
+	case tagChallenge:
+		m.Challenge = new(Challenge)
+		v = m.Challenge
+	case tagResponse:
+		m.Response = new(Response)
+		v = m.Response
+	case tagEncData:
+		m.EncData = new(EncryptedData)
+		v = m.EncData
+	default:
+		return nil, errMalformedMessage
+	}
+
+	rest, err = asn1.Unmarshal(raw.Bytes, v)
+	if err != nil || len(rest) != 0 {
+		return nil, errMalformedMessage
+	}
+
+	return m, nil
+}
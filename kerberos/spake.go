@@ -0,0 +1,355 @@
+// Package kerberos implements the SPAKE pre-authentication mechanism for
+// Kerberos on top of the groups used by spake2plus.
+//
+// The client proves knowledge of the initial reply key (the long-term key
+// derived from the password) through a SPAKE2 exchange, so a captured AS
+// exchange cannot be used for an offline dictionary attack. Only the SF-NONE
+// second factor is supported.
+//
+// Group elements are encoded as RFC 9568 specifies: edwards25519 elements as
+// in RFC 8032, and those of the NIST groups in the compressed form of SEC 1.
+package kerberos
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"io"
+)
+
+// KeyUsageSPAKE is the key usage number for the encrypted second factor.
+const KeyUsageSPAKE uint32 = 65
+
+var (
+	errUnexpectedMessage = suite.NewError(suite.InvalidState, "unexpected PA-SPAKE message")
+	errNoCommonGroup     = suite.NewError(suite.UnknownSuite, "no common SPAKE group")
+	errInvalidElement    = suite.NewError(suite.InvalidPoint, "invalid SPAKE element")
+	errSmallOrder        = suite.NewError(suite.SmallOrderPoint, "peer's SPAKE element is of small order")
+	errFactorMismatch    = suite.NewError(suite.InvalidInput, "unsupported second factor")
+)
+
+// exchange holds what both sides track across the PA-SPAKE round trips.
+type exchange struct {
+	enctype    Enctype
+	key        []byte
+	group      *group
+	wBytes     []byte
+	w          suite.Scalar
+	private    suite.Scalar // r, of the private key x = h*r
+	transcript []byte
+	rand       io.Reader // for the private key; nil means crypto/rand
+}
+
+func newExchange(enctype Enctype, key []byte) *exchange {
+	return &exchange{enctype: enctype, key: key}
+}
+
+func (e *exchange) setGroup(number int32) error {
+	g, err := lookupGroup(number)
+	if err != nil {
+		return err
+	}
+
+	var num [4]byte
+	binary.BigEndian.PutUint32(num[:], uint32(number))
+	wBytes := prfPlus(e.enctype, e.key, append([]byte("SPAKEsecret"), num[:]...), g.multiplierLen())
+
+	w, err := g.multiplier(wBytes)
+	if err != nil {
+		return err
+	}
+
+	e.group, e.wBytes, e.w = g, wBytes, w
+	if e.transcript == nil {
+		e.transcript = make([]byte, g.hash().Size())
+	}
+	return nil
+}
+
+// updateTranscript sets the transcript hash to H(transcript || msgs...). It
+// must only be called once the group, and with it the hash, is known.
+func (e *exchange) updateTranscript(msgs ...[]byte) {
+	h := e.group.hash()
+	h.Write(e.transcript)
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	e.transcript = h.Sum(nil)
+}
+
+// public computes x*G + w*blind, with x = h*r for a random r. h multiplies r*G rather than r, since
+// h*r reduced modulo the group order is no longer a multiple of h.
+func (e *exchange) public(blind suite.Element) ([]byte, error) {
+	g := e.group.g
+	r, err := g.RandomScalar(e.rand)
+	if err != nil {
+		return nil, err
+	}
+	e.private = r
+
+	P := e.group.clearCofactor(g.Element().ScalarMult(r, nil))
+	P.Add(P, g.Element().ScalarMult(e.w, blind))
+	return e.group.encode(P), nil
+}
+
+// shared computes x*(peer - w*blind) as r*(h*(peer - w*blind)), which drops
+// any small-order component the peer added.
+func (e *exchange) shared(peer []byte, blind suite.Element) ([]byte, error) {
+	g := e.group.g
+	P, err := e.group.decode(peer)
+	if err != nil {
+		return nil, err
+	}
+	if e.group.clearCofactor(P).Equal(g.Element().Identity()) {
+		return nil, errSmallOrder
+	}
+
+	tmp := g.Element().ScalarMult(g.Scalar().Negate(e.w), blind)
+	tmp.Add(P, tmp)
+
+	K := g.Element().ScalarMult(e.private, e.group.clearCofactor(tmp))
+	return e.group.encode(K), nil
+}
+
+// deriveKey computes K'[n] from the shared element K and the request body:
+// KRB-FX-CF2(initial reply key, tmp, "SPAKE", "keyderiv"), where tmp is the
+// hash of the exchange's inputs, counter-extended to the enctype's key size.
+func (e *exchange) deriveKey(K, reqBody []byte, n uint32) []byte {
+	var group, enctype, num [4]byte
+	binary.BigEndian.PutUint32(group[:], uint32(e.group.number))
+	binary.BigEndian.PutUint32(enctype[:], uint32(e.enctype.Number()))
+	binary.BigEndian.PutUint32(num[:], n)
+
+	size := e.enctype.KeySize()
+	tmp := make([]byte, 0, size+e.group.hash().Size())
+	for i := byte(1); len(tmp) < size; i++ {
+		h := e.group.hash()
+		for _, b := range [][]byte{[]byte("SPAKEkey"), group[:], enctype[:], e.wBytes, K, e.transcript, reqBody, num[:], {i}} {
+			h.Write(b)
+		}
+		tmp = h.Sum(tmp)
+	}
+
+	// random-to-key is the identity function for the supported enctypes.
+	return fxCF2(e.enctype, e.key, tmp[:size], []byte("SPAKE"), []byte("keyderiv"))
+}
+
+// fxCF2 is KRB-FX-CF2 from RFC 6113: PRF+(k1, pepper1) XOR PRF+(k2, pepper2).
+func fxCF2(enctype Enctype, k1, k2, pepper1, pepper2 []byte) []byte {
+	out := prfPlus(enctype, k1, pepper1, enctype.KeySize())
+	for i, b := range prfPlus(enctype, k2, pepper2, len(out)) {
+		out[i] ^= b
+	}
+	return out
+}
+
+// prfPlus is PRF+ from RFC 6113: PRF(key, 1 || input) || PRF(key, 2 || input) || ...
+func prfPlus(enctype Enctype, key, input []byte, length int) []byte {
+	out := make([]byte, 0, length)
+	in := make([]byte, 1+len(input))
+	copy(in[1:], input)
+	for i := byte(1); len(out) < length; i++ {
+		in[0] = i
+		out = append(out, enctype.PRF(key, in)...)
+	}
+	return out[:length]
+}
+
+// Client is the client side of a single SPAKE pre-authentication exchange.
+type Client struct {
+	*exchange
+	groups  []int32
+	support []byte
+}
+
+// NewClient creates a Client for the given initial reply key. groups lists the
+// group numbers the client is willing to use.
+func NewClient(key []byte, enctype Enctype, groups ...int32) *Client {
+	return &Client{newExchange(enctype, key), groups, nil}
+}
+
+// Support returns the encoded PA-SPAKE support message. Sending it is
+// optional; a KDC may issue a challenge without it.
+func (c *Client) Support() ([]byte, error) {
+	msg, err := (&PASPAKE{Support: &Support{Groups: c.groups}}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	c.support = msg
+	return msg, nil
+}
+
+// Respond processes the KDC's challenge and returns the encoded PA-SPAKE
+// response together with the new reply key K'[0]. reqBody is the DER encoding
+// of the KDC-REQ-BODY of the request carrying the response.
+func (c *Client) Respond(challenge, reqBody []byte) ([]byte, []byte, error) {
+	m, err := ParsePASPAKE(challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.Challenge == nil {
+		return nil, nil, errUnexpectedMessage
+	}
+
+	if !containsGroup(c.groups, m.Challenge.Group) {
+		return nil, nil, errNoCommonGroup
+	}
+	if !containsFactor(m.Challenge.Factors, SFNone) {
+		return nil, nil, errFactorMismatch
+	}
+	if err := c.setGroup(m.Challenge.Group); err != nil {
+		return nil, nil, err
+	}
+
+	// the support message, if any, and the challenge are hashed in one go
+	c.updateTranscript(c.support, challenge)
+
+	pub, err := c.public(c.group.g.N())
+	if err != nil {
+		return nil, nil, err
+	}
+	c.updateTranscript(pub)
+
+	K, err := c.shared(m.Challenge.PubKey, c.group.g.M())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	factor, err := asn1.Marshal(SecondFactor{Type: SFNone})
+	if err != nil {
+		return nil, nil, err
+	}
+	encrypted, err := c.enctype.Encrypt(c.deriveKey(K, reqBody, 1), KeyUsageSPAKE, factor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := (&PASPAKE{Response: &Response{
+		PubKey: pub,
+		Factor: EncryptedData{Etype: c.enctype.Number(), Cipher: encrypted},
+	}}).Marshal()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, c.deriveKey(K, reqBody, 0), nil
+}
+
+// KDC is the KDC side of a single SPAKE pre-authentication exchange.
+type KDC struct {
+	*exchange
+	groups []int32
+}
+
+// NewKDC creates a KDC for the principal's initial reply key. groups lists the
+// permitted group numbers in order of preference.
+func NewKDC(key []byte, enctype Enctype, groups ...int32) *KDC {
+	return &KDC{newExchange(enctype, key), groups}
+}
+
+// Challenge returns the encoded PA-SPAKE challenge. support is the client's
+// encoded support message, or nil to challenge optimistically with the most
+// preferred group.
+func (k *KDC) Challenge(support []byte) ([]byte, error) {
+	if len(k.groups) == 0 {
+		return nil, errNoCommonGroup
+	}
+	number := k.groups[0]
+
+	if support != nil {
+		m, err := ParsePASPAKE(support)
+		if err != nil {
+			return nil, err
+		}
+		if m.Support == nil {
+			return nil, errUnexpectedMessage
+		}
+
+		number = -1
+		for _, g := range k.groups {
+			if _, err := lookupGroup(g); err == nil && containsGroup(m.Support.Groups, g) {
+				number = g
+				break
+			}
+		}
+		if number < 0 {
+			return nil, errNoCommonGroup
+		}
+	}
+
+	if err := k.setGroup(number); err != nil {
+		return nil, err
+	}
+
+	pub, err := k.public(k.group.g.M())
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := (&PASPAKE{Challenge: &Challenge{
+		Group:   number,
+		PubKey:  pub,
+		Factors: []SecondFactor{{Type: SFNone}},
+	}}).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	k.updateTranscript(support, msg)
+
+	return msg, nil
+}
+
+// Verify checks the client's encoded response and returns the reply key K'[0]
+// the KDC must use for the AS-REP.
+func (k *KDC) Verify(response, reqBody []byte) ([]byte, error) {
+	if k.group == nil {
+		return nil, errUnexpectedMessage
+	}
+
+	m, err := ParsePASPAKE(response)
+	if err != nil {
+		return nil, err
+	}
+	if m.Response == nil || m.Response.Factor.Etype != k.enctype.Number() {
+		return nil, errUnexpectedMessage
+	}
+	k.updateTranscript(m.Response.PubKey)
+
+	K, err := k.shared(m.Response.PubKey, k.group.g.N())
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := k.enctype.Decrypt(k.deriveKey(K, reqBody, 1), KeyUsageSPAKE, m.Response.Factor.Cipher)
+	if err != nil {
+		return nil, err
+	}
+
+	var factor SecondFactor
+	if rest, err := asn1.Unmarshal(plain, &factor); err != nil || len(rest) != 0 {
+		return nil, errMalformedMessage
+	}
+	if factor.Type != SFNone {
+		return nil, errFactorMismatch
+	}
+
+	return k.deriveKey(K, reqBody, 0), nil
+}
+
+func containsGroup(groups []int32, g int32) bool {
+	for _, v := range groups {
+		if v == g {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFactor(factors []SecondFactor, t int32) bool {
+	for _, f := range factors {
+		if f.Type == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,359 @@
+package kerberos
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var testGroups = []int32{Edwards25519, P256, P384, P521}
+
+var testEnctypes = []Enctype{AES128SHA256(), AES256SHA384()}
+
+var reqBody = []byte("KDC-REQ-BODY")
+
+func randomKey(t *testing.T, e Enctype) []byte {
+	key := make([]byte, e.KeySize())
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	return key
+}
+
+// kdcExchange plays a local stand-in KDC: the client's support message, the
+// KDC's challenge and the client's response, returning both reply keys.
+func kdcExchange(t *testing.T, client *Client, kdc *KDC, sendSupport bool) ([]byte, []byte, error) {
+	var support []byte
+	var err error
+	if sendSupport {
+		support, err = client.Support()
+		if !assert.NoError(t, err) {
+			return nil, nil, err
+		}
+	}
+
+	challenge, err := kdc.Challenge(support)
+	if !assert.NoError(t, err) {
+		return nil, nil, err
+	}
+
+	response, clientKey, err := client.Respond(challenge, reqBody)
+	if !assert.NoError(t, err) {
+		return nil, nil, err
+	}
+
+	kdcKey, err := kdc.Verify(response, reqBody)
+	return clientKey, kdcKey, err
+}
+
+func TestSPAKEPreauth(t *testing.T) {
+	for _, e := range testEnctypes {
+		for _, g := range testGroups {
+			key := randomKey(t, e)
+			clientKey, kdcKey, err := kdcExchange(t, NewClient(key, e, g), NewKDC(key, e, g), true)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			assert.Len(t, clientKey, e.KeySize())
+			assert.Equal(t, clientKey, kdcKey)
+			assert.NotEqual(t, key, clientKey)
+		}
+	}
+}
+
+func TestSPAKEPreauthOptimisticChallenge(t *testing.T) {
+	e := AES256SHA384()
+	key := randomKey(t, e)
+	clientKey, kdcKey, err := kdcExchange(t, NewClient(key, e, testGroups...), NewKDC(key, e, P256, Edwards25519), false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, clientKey, kdcKey)
+	}
+}
+
+func TestSPAKEPreauthGroupNegotiation(t *testing.T) {
+	e := AES128SHA256()
+	key := randomKey(t, e)
+
+	client := NewClient(key, e, P384, Edwards25519)
+	support, err := client.Support()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	challenge, err := NewKDC(key, e, P256, Edwards25519, P384).Challenge(support)
+	if !assert.NoError(t, err) {
+		return
+	}
+	m, err := ParsePASPAKE(challenge)
+	if assert.NoError(t, err) {
+		assert.Equal(t, Edwards25519, m.Challenge.Group)
+	}
+
+	_, err = NewKDC(key, e, P521).Challenge(support)
+	assert.Error(t, err)
+}
+
+func TestSPAKEPreauthWrongKey(t *testing.T) {
+	e := AES128SHA256()
+	for _, g := range testGroups {
+		_, _, err := kdcExchange(t, NewClient(randomKey(t, e), e, g), NewKDC(randomKey(t, e), e, g), true)
+		assert.Error(t, err)
+	}
+}
+
+func TestSPAKEPreauthRequestBodyBinding(t *testing.T) {
+	e := AES128SHA256()
+	key := randomKey(t, e)
+	client, kdc := NewClient(key, e, Edwards25519), NewKDC(key, e, Edwards25519)
+
+	challenge, err := kdc.Challenge(nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	response, _, err := client.Respond(challenge, reqBody)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = kdc.Verify(response, []byte("another KDC-REQ-BODY"))
+	assert.Error(t, err)
+}
+
+// TestTorsionComponent adds a point of order 8 to each side's public key in turn: the other side
+// must clear it and still compute the same K.
+func TestTorsionComponent(t *testing.T) {
+	e := AES128SHA256()
+	key := randomKey(t, e)
+	torsion, err := hex.DecodeString("26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05")
+	assert.NoError(t, err)
+
+	for i := 0; i < 16; i++ {
+		client, kdc := newExchange(e, key), newExchange(e, key)
+		assert.NoError(t, client.setGroup(Edwards25519))
+		assert.NoError(t, kdc.setGroup(Edwards25519))
+		g := client.group
+		T8, err := g.decode(torsion)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		T, err := client.public(g.g.N())
+		assert.NoError(t, err)
+		S, err := kdc.public(g.g.M())
+		assert.NoError(t, err)
+		withTorsion := func(b []byte) []byte {
+			P, err := g.decode(b)
+			assert.NoError(t, err)
+			return g.encode(P.Add(P, T8))
+		}
+
+		K, err := client.shared(S, g.g.M())
+		assert.NoError(t, err)
+		KT, err := kdc.shared(withTorsion(T), g.g.N())
+		if assert.NoError(t, err) {
+			assert.Equal(t, K, KT)
+		}
+		KS, err := client.shared(withTorsion(S), g.g.M())
+		if assert.NoError(t, err) {
+			assert.Equal(t, K, KS)
+		}
+	}
+}
+
+func TestPASPAKEEncoding(t *testing.T) {
+	messages := []*PASPAKE{
+		{Support: &Support{Groups: []int32{Edwards25519, P256}}},
+		{Challenge: &Challenge{Group: P384, PubKey: []byte{1, 2, 3}, Factors: []SecondFactor{{Type: SFNone}, {Type: 2, Data: []byte{4}}}}},
+		{Response: &Response{PubKey: []byte{5, 6}, Factor: EncryptedData{Etype: AES128CTSHMACSHA256128, Kvno: 3, Cipher: []byte{7}}}},
+		{EncData: &EncryptedData{Etype: AES256CTSHMACSHA384192, Cipher: []byte{8, 9}}},
+	}
+
+	for i, m := range messages {
+		b, err := m.Marshal()
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, byte(0xa0+i), b[0])
+
+		parsed, err := ParsePASPAKE(b)
+		if assert.NoError(t, err) {
+			assert.Equal(t, m, parsed)
+		}
+	}
+
+	_, err := (&PASPAKE{}).Marshal()
+	assert.Error(t, err)
+	for _, b := range [][]byte{nil, {0xa7, 0x02, 0x30, 0x00}, {0xa0, 0x03, 0x30, 0x01}, {0xa1, 0x02, 0x04, 0x00}, {0xa0, 0x02, 0x30, 0x00, 0x00}} {
+		_, err = ParsePASPAKE(b)
+		assert.True(t, errors.Is(err, suite.ErrMalformedMessage), err)
+	}
+}
+
+func TestErrorReasons(t *testing.T) {
+	e := AES128SHA256()
+	key := randomKey(t, e)
+
+	// a small-order element in place of the client's
+	kdc := NewKDC(key, e, Edwards25519)
+	_, err := kdc.Challenge(nil)
+	assert.NoError(t, err)
+	identity := make([]byte, 32)
+	identity[0] = 1
+	response, err := (&PASPAKE{Response: &Response{PubKey: identity, Factor: EncryptedData{Etype: e.Number()}}}).Marshal()
+	assert.NoError(t, err)
+	_, err = kdc.Verify(response, reqBody)
+	assert.True(t, errors.Is(err, suite.ErrSmallOrderPoint), err)
+
+	// elements that don't decode
+	for _, g := range testGroups {
+		kdc := NewKDC(key, e, g)
+		_, err := kdc.Challenge(nil)
+		assert.NoError(t, err)
+		for _, pub := range [][]byte{nil, {2, 3}} {
+			response, err := (&PASPAKE{Response: &Response{PubKey: pub, Factor: EncryptedData{Etype: e.Number()}}}).Marshal()
+			assert.NoError(t, err)
+			_, err = kdc.Verify(response, reqBody)
+			assert.True(t, errors.Is(err, suite.ErrInvalidPoint), err)
+		}
+	}
+
+	// a challenge without SF-NONE
+	challenge, err := (&PASPAKE{Challenge: &Challenge{Group: Edwards25519, PubKey: identity, Factors: []SecondFactor{{Type: 2}}}}).Marshal()
+	assert.NoError(t, err)
+	_, _, err = NewClient(key, e, Edwards25519).Respond(challenge, reqBody)
+	assert.True(t, errors.Is(err, suite.ErrInvalidInput), err)
+}
+
+func TestUnknownGroup(t *testing.T) {
+	e := AES128SHA256()
+	_, err := NewKDC(randomKey(t, e), e, 99).Challenge(nil)
+	assert.Error(t, err)
+}
+
+func TestAESCTS(t *testing.T) {
+	for _, e := range testEnctypes {
+		key := randomKey(t, e)
+		for _, n := range []int{0, 1, 15, 16, 17, 31, 32, 33, 100} {
+			plaintext := bytes.Repeat([]byte{byte(n)}, n)
+			c, err := e.Encrypt(key, KeyUsageSPAKE, plaintext)
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			p, err := e.Decrypt(key, KeyUsageSPAKE, c)
+			if assert.NoError(t, err) {
+				assert.Equal(t, plaintext, p)
+			}
+
+			c[len(c)-1] ^= 1
+			_, err = e.Decrypt(key, KeyUsageSPAKE, c)
+			assert.Error(t, err)
+		}
+	}
+}
+
+// exchangeVectors pin the KDC's side of recorded exchanges, so that a change to w, the element
+// encodings, the transcript or the K'[n] derivation shows. They are regression vectors, not
+// interoperability ones. The KDC's private key is drawn from kdcRand repeated four times, and the
+// client's response is over the KDC-REQ-BODY reqBody, after the support message vectorSupport when
+// support is set. result is K, transcript the final transcript hash and replyKey K'[0].
+var exchangeVectors = []struct {
+	enctype    Enctype
+	group      int32
+	key        string
+	kdcRand    string
+	support    bool
+	challenge  string
+	response   string
+	result     string
+	transcript string
+	replyKey   string
+}{
+	{
+		enctype:    AES128SHA256(),
+		group:      Edwards25519,
+		key:        "3683fd2871c79231768b86135b371263",
+		kdcRand:    "c4541c834d2ac598092a41afcdc1469daaeb74de58f413109350ddf259484787",
+		support:    false,
+		challenge:  "a1363034a003020101a12204202040c5295b043b01c5fa7e20a4ad1e1b52d50d799e6d328ab0b7d970f81fed3ca20930073005a003020101",
+		response:   "a25a3058a022042032836872c44f523a1076e2e620c4db5a1a27e05130f7495401ad0f4bfc186979a1323030a003020113a229042741529e13968a5b9e5f6572e301d8c8e5946066f6c56ff6c73418f3f03335eb0bf5415b8e3c22c2",
+		result:     "47229f4654f77d1c8104a5cb827540238acc92ff8b54e847b4a1eb4cef1fdc1b",
+		transcript: "e59a86c1f6e49d72996c88a6109e88767e8a9b6225bba7eea98ee5b3c1afdd2f",
+		replyKey:   "86bdb56e15d549a91591722bd1a84e22",
+	},
+	{
+		enctype:    AES256SHA384(),
+		group:      P256,
+		key:        "1a6647951c542a4520f33df2858e5cbcf935bcd93dcdc5bd52da3b35c3e7d35a",
+		kdcRand:    "c8a94328bc008213e97b277309f289f99287125b93cb21062c620c931991501c",
+		support:    true,
+		challenge:  "a1373035a003020102a12304210325f0c0e95fb3c244c103894304f0f103ea174ed7da8fe171128dced79140c5ffa20930073005a003020101",
+		response:   "a2633061a02304210223b43ecad6535a1ea7561948f5e6003bbad8ae63b729e7ab95c210d2b910afd9a13a3038a003020114a231042f8848e209c1a64e1c03009aefb48a020e675981ece70cbb76f839abf1926f73819bed1ddf4213a035bd1b4bb8fb5623",
+		result:     "0307b87d27a889a213dbb357046e3b0ea79f5c2e9a7520f1014a4d6a6cece25601",
+		transcript: "b63d9ac58c309834e6470d43d2656458b6f54cf064a456dd2db8d76fae466175",
+		replyKey:   "0e973cbfb512c553659898cb951da3cd60b2d3e73bb441d90a9e39794df90989",
+	},
+	{
+		enctype:    AES128SHA256(),
+		group:      P384,
+		key:        "84a692a74ee8bade24ab69b224882819",
+		kdcRand:    "b778e8d017e5ac29cac9d7b4f684e8c37e4529e6e0a1eca2f28b7e809b496f25",
+		support:    true,
+		challenge:  "a1473045a003020103a13304310238b61557842ed27819e8b0c03c27d82fb52e3df9dae10f2845765bba08d741ea1fa5e38070d26e23b42befa13ea329d1a20930073005a003020101",
+		response:   "a26b3069a0330431025f1aa99983a0187dcaf76ff3c2a485d99a79935fe21fc92f0d1df623a77507ff13b27abea10e1e5f00c59cf332d6956ba1323030a003020113a2290427445024423857f915e7937f7bdf70e7c9450b79b144bd02c8e1c0b3ddd37db72982a3e853babcf9",
+		result:     "02bcfbab9d1d8b945cf3da88c88e1bfa7463d5e0c641344f4e2aeb1322d9359eed82d837e057f229501e7029506827c5ec",
+		transcript: "e0fb0dee8e28358f9c7208ef5e70037e49e9bc699c06a2bb2b1dbf032b0d93a0696b2073b96186db1434bd1e02f8a090",
+		replyKey:   "31266c8fa6730d7bb70f0bb647941bb5",
+	},
+	{
+		enctype:    AES256SHA384(),
+		group:      P521,
+		key:        "39adbd08a375565c6e3f65b9f0d93891ee33c4e7d88783631e3706fd22f14838",
+		kdcRand:    "da9b4608a8f5e585869264e7668815c68c2bbf8ae7a1b9cfaa97aea63dec7ef5",
+		support:    false,
+		challenge:  "a1593057a003020104a14504430301bc70762aba6e03af732393bac047b2ab9b63abdd5cd95a0617802e2de54b5c20cca7faaee838b7a1bb365dd28aaf1f9dc8a21cea91f1e68a98fe26e458c883b571a20930073005a003020101",
+		response:   "a28186308183a04504430200681f3c9e33f8690dc5e4a23c02ddfb2261e34b156be6c41e5738cdcdf80a6635942b1e86953d7af38f132085a57e37c71457dca2cd4c2a8971607c7f03cade5c3ca13a3038a003020114a231042f03b052852a22e9f3cc7f5b6fcae4d9a116b9f275e8a96fa03a0bcd6072f8c9530992fb61ec5e6cc8293cbc3db4fb87",
+		result:     "0200bbe3a4d811acb3bf5b789fd6b1ed3efdc3ff00723ca32a0f6b467fc346074355b475b143b3e2344ff5179070fa5cef1bfa214a651fbe5c508bce8ea1eadb37ac50",
+		transcript: "a6d9b343cb7e39f1137bcae71e24179b698956c904c866756f015a82e9bf30350bf0b117b67b17a7534d27bc005ca1af16aad1d3af72dcf8af1048f9382c210a",
+		replyKey:   "af9ecb132f65345a3be6bdd5a25a1da206a9d8e9393ce0a41f583ccc71a7ed50",
+	},
+}
+
+// vectorSupport is a support message for all four groups.
+const vectorSupport = "a0123010a00e300c020101020102020103020104"
+
+func TestExchangeVectors(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		assert.NoError(t, err)
+		return b
+	}
+	for _, v := range exchangeVectors {
+		kdc := NewKDC(unhex(v.key), v.enctype, v.group)
+		kdc.rand = bytes.NewReader(bytes.Repeat(unhex(v.kdcRand), 4))
+		var support []byte
+		if v.support {
+			support = unhex(vectorSupport)
+		}
+
+		challenge, err := kdc.Challenge(support)
+		assert.NoError(t, err, v.group)
+		assert.Equal(t, v.challenge, hex.EncodeToString(challenge), v.group)
+
+		replyKey, err := kdc.Verify(unhex(v.response), reqBody)
+		if !assert.NoError(t, err, v.group) {
+			continue
+		}
+		assert.Equal(t, v.replyKey, hex.EncodeToString(replyKey), v.group)
+		assert.Equal(t, v.transcript, hex.EncodeToString(kdc.transcript), v.group)
+
+		m, err := ParsePASPAKE(unhex(v.response))
+		assert.NoError(t, err)
+		K, err := kdc.shared(m.Response.PubKey, kdc.group.g.N())
+		assert.NoError(t, err)
+		assert.Equal(t, v.result, hex.EncodeToString(K), v.group)
+	}
+}
@@ -0,0 +1,221 @@
+package spake2plus
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+	"sync"
+	"time"
+)
+
+var errRateLimited = suite.NewError(suite.RateLimited, "too many attempts")
+
+// AttemptLimiter throttles online guessing, which SPAKE2+ can't prevent by itself. A Server built
+// WithAttemptLimiter asks it before every handshake, for the identity and for the source the
+// handshake came from (nil when unknown, see Server.HandshakeFrom), and reports the handshakes
+// whose confirmation verified.
+//
+// The server sends its confirmation first, so a client can test a guess against it and never send
+// its own: a handshake is a failed attempt until Succeeded says otherwise. Identities are passed
+// whether they are registered or not, before the Lookup is consulted, so that a limiter treats
+// unknown identities exactly like registered ones.
+//
+// Implementations must be safe for concurrent use.
+type AttemptLimiter interface {
+	// Attempt reports whether a handshake may go ahead, and counts it if so.
+	Attempt(identity, source []byte) bool
+	// Succeeded reports that the client's confirmation of a counted handshake verified.
+	Succeeded(identity, source []byte)
+}
+
+// TokenBucketLimiter is an in-memory AttemptLimiter with a token bucket per identity and per
+// source. A bucket holds up to burst attempts and gets one back every interval. Once one is
+// empty, an attempt locks it out for interval, and every further lockout before the bucket has
+// refilled doubles that, up to maxBackoff; buckets don't refill while locked out. A success gives
+// its attempt back to both buckets, but only lifts the identity's lockout: otherwise a client with
+// an account of its own could log in to it between guesses at others and never run out of them.
+//
+// Buckets that have refilled are forgotten, so the memory used follows the identities and sources
+// seen within the last burst intervals or so.
+type TokenBucketLimiter struct {
+	burst      float64
+	interval   time.Duration
+	maxBackoff time.Duration
+	now        func() time.Time
+
+	mu         sync.Mutex
+	identities map[string]*bucket
+	sources    map[string]*bucket
+	sweepAt    int // the number of buckets at which to forget the full ones
+}
+
+type bucket struct {
+	tokens  float64
+	last    time.Time // when tokens was last brought up to date
+	strikes uint      // lockouts since the bucket was last full
+	until   time.Time // end of the current lockout
+}
+
+const minSweep = 1024
+
+// NewTokenBucketLimiter returns a TokenBucketLimiter allowing burst attempts at once and one more
+// every interval, with lockouts of up to maxBackoff.
+func NewTokenBucketLimiter(burst int, interval, maxBackoff time.Duration) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		burst:      float64(burst),
+		interval:   interval,
+		maxBackoff: maxBackoff,
+		now:        time.Now,
+		identities: make(map[string]*bucket),
+		sources:    make(map[string]*bucket),
+		sweepAt:    minSweep,
+	}
+}
+
+// Attempt takes a token from the identity's bucket and the source's, if both have one.
+func (l *TokenBucketLimiter) Attempt(identity, source []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	id := l.bucket(l.identities, identity, now)
+	ok := id.allow(now)
+	var src *bucket
+	if source != nil {
+		src = l.bucket(l.sources, source, now)
+		ok = src.allow(now) && ok
+	}
+	if !ok {
+		// neither bucket pays for an attempt that didn't happen, but both start a lockout if empty
+		if id.tokens < 1 {
+			l.lock(id, now)
+		}
+		if src != nil && src.tokens < 1 {
+			l.lock(src, now)
+		}
+		return false
+	}
+
+	id.tokens--
+	if src != nil {
+		src.tokens--
+	}
+	return true
+}
+
+// Succeeded gives the attempt back to both buckets, and lifts the identity's lockout.
+func (l *TokenBucketLimiter) Succeeded(identity, source []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	l.bucket(l.identities, identity, now).reset(l.burst, now)
+	if source != nil {
+		l.bucket(l.sources, source, now).refund(l.burst)
+	}
+}
+
+// Wait returns how long until Attempt may allow identity from source again, e.g. for a
+// Retry-After header. It doesn't count as an attempt.
+func (l *TokenBucketLimiter) Wait(identity, source []byte) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+
+	wait := l.wait(l.identities[string(identity)], now)
+	if source != nil {
+		if w := l.wait(l.sources[string(source)], now); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
+
+// bucket returns the bucket of key, brought up to date, creating a full one if there's none.
+func (l *TokenBucketLimiter) bucket(m map[string]*bucket, key []byte, now time.Time) *bucket {
+	b, ok := m[string(key)]
+	if !ok {
+		if len(l.identities)+len(l.sources) >= l.sweepAt {
+			l.sweep(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		m[string(key)] = b
+		return b
+	}
+	l.refill(b, now)
+	return b
+}
+
+func (l *TokenBucketLimiter) refill(b *bucket, now time.Time) {
+	if now.Before(b.until) || !now.After(b.last) {
+		return
+	}
+	b.tokens += float64(now.Sub(b.last)) / float64(l.interval)
+	b.last = now
+	if b.tokens >= l.burst {
+		b.tokens = l.burst
+		b.strikes = 0
+	}
+}
+
+// lock starts a lockout, unless one is running.
+func (l *TokenBucketLimiter) lock(b *bucket, now time.Time) {
+	if now.Before(b.until) {
+		return
+	}
+	backoff := l.maxBackoff
+	if b.strikes < 63 && l.interval<<b.strikes > 0 && l.interval<<b.strikes < backoff {
+		backoff = l.interval << b.strikes
+	}
+	b.strikes++
+	b.until = now.Add(backoff)
+	b.last = b.until
+}
+
+func (l *TokenBucketLimiter) wait(b *bucket, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	if now.Before(b.until) {
+		return b.until.Sub(now)
+	}
+	l.refill(b, now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(l.interval))
+}
+
+// sweep forgets the buckets that are as good as new, and sets when to sweep next.
+func (l *TokenBucketLimiter) sweep(now time.Time) {
+	for _, m := range []map[string]*bucket{l.identities, l.sources} {
+		for k, b := range m {
+			l.refill(b, now)
+			if b.tokens >= l.burst && !now.Before(b.until) {
+				delete(m, k)
+			}
+		}
+	}
+	l.sweepAt = 2 * (len(l.identities) + len(l.sources))
+	if l.sweepAt < minSweep {
+		l.sweepAt = minSweep
+	}
+}
+
+func (b *bucket) allow(now time.Time) bool {
+	return !now.Before(b.until) && b.tokens >= 1
+}
+
+// refund gives back the token of an attempt.
+func (b *bucket) refund(burst float64) {
+	if b.tokens++; b.tokens > burst {
+		b.tokens = burst
+	}
+}
+
+// reset refunds an attempt and lifts the lockouts.
+func (b *bucket) reset(burst float64, now time.Time) {
+	b.refund(burst)
+	b.strikes = 0
+	if now.Before(b.until) {
+		b.until, b.last = now, now
+	}
+}
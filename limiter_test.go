@@ -0,0 +1,174 @@
+package spake2plus
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(burst int) (*TokenBucketLimiter, *time.Time) {
+	l := NewTokenBucketLimiter(burst, time.Second, time.Minute)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	l, now := newTestLimiter(3)
+	id, src := []byte("alice"), []byte("10.0.0.1")
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Attempt(id, src), i)
+	}
+	assert.False(t, l.Attempt(id, src))
+	assert.Equal(t, time.Second, l.Wait(id, src))
+
+	// the lockouts double while the bucket stays empty, and don't refill it
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		*now = now.Add(backoff - time.Millisecond)
+		assert.False(t, l.Attempt(id, src))
+		*now = now.Add(time.Millisecond)
+		assert.False(t, l.Attempt(id, src))
+	}
+	assert.Equal(t, 8*time.Second, l.Wait(id, src))
+
+	// the identity is locked from any source, the source for any identity
+	assert.False(t, l.Attempt(id, []byte("10.0.0.2")))
+	assert.False(t, l.Attempt(id, nil))
+	assert.False(t, l.Attempt([]byte("bob"), src))
+	assert.True(t, l.Attempt([]byte("bob"), []byte("10.0.0.2")))
+
+	// a success lifts the identity's lockout, but not the source's
+	l.Succeeded(id, src)
+	assert.Zero(t, l.Wait(id, nil))
+	assert.Equal(t, 8*time.Second, l.Wait(id, src))
+	assert.False(t, l.Attempt(id, src))
+	assert.True(t, l.Attempt(id, []byte("10.0.0.3")))
+	assert.False(t, l.Attempt(id, []byte("10.0.0.3")))
+	assert.Equal(t, time.Second, l.Wait(id, nil))
+}
+
+// TestTokenBucketLimiterInterleavedLogins has a source start a login to an account of its own, lock
+// itself out guessing at other identities, then confirm the login: the source stays locked out.
+func TestTokenBucketLimiterInterleavedLogins(t *testing.T) {
+	l, now := newTestLimiter(3)
+	own, src := []byte("mallory"), []byte("10.0.0.1")
+
+	assert.True(t, l.Attempt(own, src))
+	guesses := 0
+	for i := 0; i < 20; i++ {
+		if l.Attempt([]byte(fmt.Sprint("victim", i)), src) {
+			guesses++
+		}
+		*now = now.Add(100 * time.Millisecond)
+	}
+	assert.Equal(t, 2, guesses)
+	wait := l.Wait([]byte("victim"), src)
+	assert.NotZero(t, wait)
+
+	l.Succeeded(own, src)
+	assert.Zero(t, l.Wait(own, nil))
+	assert.Equal(t, wait, l.Wait([]byte("victim"), src))
+	assert.False(t, l.Attempt([]byte("victim"), src))
+}
+
+func TestTokenBucketLimiterRefills(t *testing.T) {
+	l, now := newTestLimiter(2)
+	id := []byte("alice")
+
+	assert.True(t, l.Attempt(id, nil))
+	assert.True(t, l.Attempt(id, nil))
+	assert.False(t, l.Attempt(id, nil))
+	// locked for a second, then a token every second
+	*now = now.Add(2500 * time.Millisecond)
+	assert.True(t, l.Attempt(id, nil))
+	assert.Equal(t, 500*time.Millisecond, l.Wait(id, nil))
+
+	// full buckets are forgotten
+	for i := 0; i < minSweep+minSweep/2; i++ {
+		l.Attempt([]byte(fmt.Sprint("a", i)), nil)
+	}
+	*now = now.Add(time.Minute)
+	for i := 0; i < minSweep/2; i++ {
+		l.Attempt([]byte(fmt.Sprint("b", i)), nil)
+	}
+	assert.Less(t, len(l.identities), minSweep)
+}
+
+func TestServerAttemptLimiter(t *testing.T) {
+	cs := Ed25519Sha256HkdfHmac(mhfScrypt)
+	db := NewMapLookup()
+	l, _ := newTestLimiter(2)
+	server, err := NewServer(cs, db, []byte("server"), WithAttemptLimiter(l))
+	assert.NoError(t, err)
+
+	login := func(identity, password string, confirm bool) error {
+		client, err := NewClient(cs, []byte(identity), []byte("server"), []byte(password), []byte("NaCl"))
+		assert.NoError(t, err)
+		A, err := client.EphemeralPublic()
+		assert.NoError(t, err)
+		m, sB, err := server.HandshakeFrom([]byte("source of "+identity), []byte(identity), A)
+		if err != nil {
+			return err
+		}
+		sA, err := client.CompleteHandshake(m)
+		if err != nil || !confirm {
+			return err
+		}
+		if err := sB.Verify(sA.Confirmation()); err != nil {
+			return err
+		}
+		return sB.Verify(sA.Confirmation())
+	}
+	client, err := NewClient(cs, []byte("alice"), []byte("server"), []byte("password"), []byte("NaCl"))
+	assert.NoError(t, err)
+	v, err := client.Verifier()
+	assert.NoError(t, err)
+	db.Add([]byte("alice"), v)
+
+	// confirmed logins give their attempt back, even when Verify is called twice
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, login("alice", "password", true))
+	}
+
+	// wrong passwords and unconfirmed handshakes both count, on registered and unknown identities alike
+	for _, identity := range []string{"alice", "mallory"} {
+		assert.True(t, errors.Is(login(identity, "guess", true), ErrConfirmationMismatch))
+		assert.NoError(t, login(identity, "guess", false))
+		err := login(identity, "password", true)
+		assert.True(t, errors.Is(err, ErrRateLimited), identity)
+		assert.Equal(t, "too many attempts", err.Error())
+	}
+
+	// as do sessions
+	ss, err := server.NewSession()
+	assert.NoError(t, err)
+	_, err = ss.HandshakeFrom(nil, []byte("alice"), v.Verifier.V2)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.Equal(t, StateFailed, ss.State())
+}
+
+func TestTokenBucketLimiterConcurrent(t *testing.T) {
+	l := NewTokenBucketLimiter(100, time.Hour, time.Hour)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if l.Attempt([]byte("alice"), []byte(fmt.Sprint(i))) {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 100, allowed)
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"github.com/jtejido/spake2plus"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests, by \\ path.", "path")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1})
+	c.Inc(`/a"b`)
+	c.Add(2, "/")
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(3)
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+	assert.Equal(t, `# HELP requests_total Requests, by \\ path.
+# TYPE requests_total counter
+requests_total{path="/"} 2
+requests_total{path="/a\"b"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.15
+latency_seconds_count 3
+`, b.String())
+
+	assert.Panics(t, func() { r.NewCounter("requests_total", "") })
+	assert.Panics(t, func() { c.Inc() })
+	assert.Panics(t, func() { c.Add(-1, "/") })
+}
+
+func TestObserver(t *testing.T) {
+	r := NewRegistry()
+	o := NewObserver(r)
+	cs := spake2plus.Ed25519Sha256HkdfHmac(spake2plus.Scrypt(16, 1, 1))
+	server, err := spake2plus.NewServer(cs, spake2plus.NewMapLookup(), []byte("server"), spake2plus.WithObserver(o))
+	assert.NoError(t, err)
+	client, err := spake2plus.NewClient(cs, []byte("mallory"), []byte("server"), []byte("guess"), []byte("NaCl"), spake2plus.WithObserver(o))
+	assert.NoError(t, err)
+
+	A, err := client.EphemeralPublic()
+	assert.NoError(t, err)
+	m, sB, err := server.Handshake([]byte("mallory"), A)
+	assert.NoError(t, err)
+	sA, err := client.CompleteHandshake(m)
+	assert.NoError(t, err)
+	assert.Error(t, sB.Verify(sA.Confirmation()))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	text := w.Body.String()
+	assert.Contains(t, w.Header().Get("Content-Type"), "version=0.0.4")
+	id := cs.ID()
+	assert.Contains(t, text, `spake2plus_handshakes_total{side="server",suite="`+id+`",result="ok"} 1`)
+	assert.Contains(t, text, `spake2plus_handshakes_total{side="client",suite="`+id+`",result="ok"} 1`)
+	assert.Contains(t, text, `spake2plus_confirmations_total{side="server",suite="`+id+`",result="confirmation_mismatch"} 1`)
+	assert.Contains(t, text, `spake2plus_handshake_duration_seconds_count{side="server",suite="`+id+`"} 1`)
+	assert.NotContains(t, text, "mallory")
+	assert.NotContains(t, text, "found")
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"github.com/jtejido/spake2plus"
+	"strings"
+)
+
+// Observer counts and times handshakes and confirmations, labelled by side, suite and result. The
+// result is "ok", or the Reason of the failure in snake case, e.g. "invalid_point". Whether a
+// server found the user isn't exported, lest the metrics enumerate them.
+type Observer struct {
+	started       *Counter
+	handshakes    *Counter
+	duration      *Histogram
+	confirmations *Counter
+	elapsed       *Histogram
+}
+
+// NewObserver registers the following metrics with r, and returns an Observer updating them:
+//
+//	spake2plus_handshakes_started_total{side,suite}
+//	spake2plus_handshakes_total{side,suite,result}
+//	spake2plus_handshake_duration_seconds{side,suite}
+//	spake2plus_confirmations_total{side,suite,result}
+//	spake2plus_confirmation_elapsed_seconds{side,suite}, from the handshake to the confirmation
+func NewObserver(r *Registry) *Observer {
+	return &Observer{
+		started:       r.NewCounter("spake2plus_handshakes_started_total", "Handshakes started.", "side", "suite"),
+		handshakes:    r.NewCounter("spake2plus_handshakes_total", "Handshakes finished, by result.", "side", "suite", "result"),
+		duration:      r.NewHistogram("spake2plus_handshake_duration_seconds", "Time to process the peer's share.", nil, "side", "suite"),
+		confirmations: r.NewCounter("spake2plus_confirmations_total", "Confirmations verified, by result.", "side", "suite", "result"),
+		elapsed:       r.NewHistogram("spake2plus_confirmation_elapsed_seconds", "Time from the handshake to the peer's confirmation.", nil, "side", "suite"),
+	}
+}
+
+func (o *Observer) HandshakeStarted(e spake2plus.HandshakeEvent) {
+	o.started.Inc(e.Side.String(), e.Suite)
+}
+
+func (o *Observer) HandshakeFinished(e spake2plus.HandshakeEvent) {
+	o.handshakes.Inc(e.Side.String(), e.Suite, result(e.Failed, e.Reason))
+	o.duration.Observe(e.Duration.Seconds(), e.Side.String(), e.Suite)
+}
+
+func (o *Observer) Confirmed(e spake2plus.ConfirmationEvent) {
+	o.confirmations.Inc(e.Side.String(), e.Suite, result(e.Failed, e.Reason))
+	o.elapsed.Observe(e.Elapsed.Seconds(), e.Side.String(), e.Suite)
+}
+
+func result(failed bool, r spake2plus.Reason) string {
+	if !failed {
+		return "ok"
+	}
+	return strings.ReplaceAll(r.String(), " ", "_")
+}
@@ -0,0 +1,367 @@
+package spake2plus
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// MHFs. Each one's String is a descriptor in the style of the PHC string format, without salt or
+// hash, e.g. "$argon2id$v=19$m=65536,t=1,p=4". Verifier carries it, and ParseMHF turns it back into
+// the MHF.
+
+type scryptMHF struct {
+	N, r, p int
+}
+
+func Scrypt(N, r, p int) suite.MHF {
+	return scryptMHF{N, r, p}
+}
+
+func (m scryptMHF) Key(password, salt []byte, len int) ([]byte, error) {
+	return scrypt.Key(password, salt, m.N, m.r, m.p, len)
+}
+
+// The descriptor holds log2(N), as scrypt only accepts powers of two.
+func (m scryptMHF) String() string {
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d", bits.Len(uint(m.N))-1, m.r, m.p)
+}
+
+type argon2MHF struct {
+	id           bool
+	time, memory uint32
+	threads      uint8
+}
+
+// Argon2id returns Argon2id with memory in KiB. Its Key fails with InvalidInput unless time and
+// threads are at least one.
+func Argon2id(time, memory uint32, threads uint8) suite.MHF {
+	return argon2MHF{true, time, memory, threads}
+}
+
+// Argon2i returns Argon2i with memory in KiB. Its Key fails with InvalidInput unless time and
+// threads are at least one.
+func Argon2i(time, memory uint32, threads uint8) suite.MHF {
+	return argon2MHF{false, time, memory, threads}
+}
+
+// Deprecated: Argon2 has always been Argon2i. Use Argon2i, or Argon2id for new deployments.
+func Argon2(time, memory uint32, threads uint8) suite.MHF {
+	return Argon2i(time, memory, threads)
+}
+
+var errArgon2Cost = suite.NewError(suite.InvalidInput, "Argon2 needs a time cost and parallelism of at least one")
+
+func (m argon2MHF) Key(password, salt []byte, len int) ([]byte, error) {
+	// x/crypto/argon2 panics on either
+	if m.time < 1 || m.threads < 1 {
+		return nil, errArgon2Cost
+	}
+	if m.id {
+		return argon2.IDKey(password, salt, m.time, m.memory, m.threads, uint32(len)), nil
+	}
+	return argon2.Key(password, salt, m.time, m.memory, m.threads, uint32(len)), nil
+}
+
+func (m argon2MHF) String() string {
+	name := "argon2i"
+	if m.id {
+		name = "argon2id"
+	}
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d", name, argon2.Version, m.memory, m.time, m.threads)
+}
+
+// Hashes accepted by PBKDF2, all FIPS approved.
+var pbkdf2Hashes = map[crypto.Hash]string{
+	crypto.SHA256:     "sha256",
+	crypto.SHA384:     "sha384",
+	crypto.SHA512:     "sha512",
+	crypto.SHA512_256: "sha512-256",
+}
+
+type pbkdf2MHF struct {
+	h    crypto.Hash
+	iter int
+}
+
+// PBKDF2 returns PBKDF2-HMAC (RFC 8018) over h, one of SHA-256, SHA-384, SHA-512 and SHA-512/256.
+// It isn't memory hard; use it only where FIPS 140 rules out the others.
+func PBKDF2(h crypto.Hash, iter int) suite.MHF {
+	return pbkdf2MHF{h, iter}
+}
+
+func (m pbkdf2MHF) Key(password, salt []byte, len int) ([]byte, error) {
+	if _, ok := pbkdf2Hashes[m.h]; !ok || !m.h.Available() {
+		return nil, suite.NewError(suite.UnknownSuite, "unsupported PBKDF2 hash "+m.h.String())
+	}
+	if m.iter < 1 {
+		return nil, suite.NewError(suite.InvalidInput, "PBKDF2 needs at least one iteration")
+	}
+	return pbkdf2.Key(password, salt, m.iter, len, m.h.New), nil
+}
+
+func (m pbkdf2MHF) String() string {
+	name, ok := pbkdf2Hashes[m.h]
+	if !ok {
+		name = strings.ToLower(m.h.String())
+	}
+	return fmt.Sprintf("$pbkdf2-%s$i=%d", name, m.iter)
+}
+
+type balloonMHF struct {
+	space, time uint32
+}
+
+// Balloon returns Balloon hashing (Boneh, Corrigan-Gibbs and Schechter, 2016) over SHA-256 with a
+// buffer of space 32-byte blocks, time rounds and delta = 3, as the reference implementation
+// computes it. The last block is stretched to the requested length with HKDF-Expand.
+func Balloon(space, time uint32) suite.MHF {
+	return balloonMHF{space, time}
+}
+
+const balloonDelta = 3
+
+var errBalloonCost = suite.NewError(suite.InvalidInput, "Balloon needs a space and time cost of at least one")
+
+func (m balloonMHF) Key(password, salt []byte, length int) ([]byte, error) {
+	if m.space < 1 || m.time < 1 {
+		return nil, errBalloonCost
+	}
+
+	block := balloon(password, salt, m.space, m.time)
+	defer wipe(block)
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, block, []byte("balloon")), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// balloon returns the last block of the paper's Algorithm 1, as its reference implementation runs
+// it: integers are hashed as 8 little-endian bytes, and the index block of round t, block i and
+// neighbour j is H(t || i || j), without the counter.
+func balloon(password, salt []byte, space, time uint32) []byte {
+	var cnt uint64
+	hash := func(out []byte, parts ...[]byte) {
+		h := sha256.New()
+		var c [8]byte
+		binary.LittleEndian.PutUint64(c[:], cnt)
+		cnt++
+		h.Write(c[:])
+		for _, p := range parts {
+			h.Write(p)
+		}
+		h.Sum(out[:0])
+	}
+
+	buf := make([][]byte, space)
+	for i := range buf {
+		buf[i] = make([]byte, sha256.Size)
+	}
+	defer func() {
+		for _, b := range buf {
+			wipe(b)
+		}
+	}()
+
+	// expand
+	hash(buf[0], password, salt)
+	for i := 1; i < len(buf); i++ {
+		hash(buf[i], buf[i-1])
+	}
+
+	// mix
+	n := new(big.Int).SetUint64(uint64(space))
+	var idx [24]byte
+	other := make([]byte, sha256.Size)
+	v := new(big.Int)
+	for t := uint64(0); t < uint64(time); t++ {
+		for i := range buf {
+			prev := buf[(i+len(buf)-1)%len(buf)]
+			hash(buf[i], prev, buf[i])
+
+			for j := uint64(0); j < balloonDelta; j++ {
+				binary.LittleEndian.PutUint64(idx[0:], t)
+				binary.LittleEndian.PutUint64(idx[8:], uint64(i))
+				binary.LittleEndian.PutUint64(idx[16:], j)
+				idxBlock := sha256.Sum256(idx[:])
+				hash(other, salt, idxBlock[:])
+
+				// the digest is read as a little-endian integer
+				for k := 0; k < sha256.Size/2; k++ {
+					other[k], other[sha256.Size-1-k] = other[sha256.Size-1-k], other[k]
+				}
+				hash(buf[i], buf[i], buf[v.Mod(v.SetBytes(other), n).Uint64()])
+			}
+		}
+	}
+
+	return append([]byte(nil), buf[len(buf)-1]...)
+}
+
+func (m balloonMHF) String() string {
+	return fmt.Sprintf("$balloon-sha256$s=%d,t=%d,d=%d", m.space, m.time, balloonDelta)
+}
+
+var errBadDescriptor = suite.NewError(suite.MalformedMessage, "malformed MHF descriptor")
+
+// maxMHFMemory bounds the memory, in bytes, that a parsed descriptor may ask for, so that a
+// verifier record can't make the server allocate without limit.
+const maxMHFMemory = 4 << 30
+
+var errMHFMemory = suite.NewError(suite.InvalidInput, "MHF descriptor asks for more than 4 GiB")
+
+// maxMHFPasses bounds the bytes that scrypt and Argon2 go through over all their rounds and lanes,
+// and maxMHFIterations the iterations of PBKDF2 and the block hashes of Balloon, space*time, so
+// that a verifier record can't make the server or client compute without limit either.
+const (
+	maxMHFPasses     = 16 << 30
+	maxMHFIterations = 1 << 24
+)
+
+var errMHFWork = suite.NewError(suite.InvalidInput, "MHF descriptor asks for too much work")
+
+// ParseMHF returns the MHF that a descriptor, as returned by its String method, stands for. Costs
+// that the MHF rejects, or that ask for more than 4 GiB or more than maxMHFPasses or
+// maxMHFIterations allow, fail with InvalidInput.
+func ParseMHF(descriptor string) (suite.MHF, error) {
+	fields := strings.Split(descriptor, "$")
+	if len(fields) < 3 || fields[0] != "" {
+		return nil, errBadDescriptor
+	}
+	alg := fields[1]
+	params, err := parseParams(fields[len(fields)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	switch alg {
+	case "scrypt":
+		if len(fields) != 3 || !hasParams(params, "ln", "r", "p") || params["ln"] >= bits.UintSize-1 {
+			return nil, errBadDescriptor
+		}
+		// scrypt's buffer is 128*r*N bytes
+		if params["r"] > maxMHFMemory/128>>params["ln"] {
+			return nil, errMHFMemory
+		}
+		if mem := 128 * params["r"] << params["ln"]; mem > 0 && params["p"] > maxMHFPasses/mem {
+			return nil, errMHFWork
+		}
+		return Scrypt(1<<params["ln"], int(params["r"]), int(params["p"])), nil
+	case "argon2i", "argon2id":
+		if len(fields) != 4 || fields[2] != "v="+strconv.Itoa(argon2.Version) || !hasParams(params, "m", "t", "p") ||
+			params["m"] > 1<<32-1 || params["t"] > 1<<32-1 || params["p"] > 255 {
+			return nil, errBadDescriptor
+		}
+		if params["t"] < 1 || params["p"] < 1 {
+			return nil, errArgon2Cost
+		}
+		if params["m"] > maxMHFMemory/1024 {
+			return nil, errMHFMemory
+		}
+		if params["t"]*params["m"] > maxMHFPasses/1024 {
+			return nil, errMHFWork
+		}
+		m := argon2MHF{alg == "argon2id", uint32(params["t"]), uint32(params["m"]), uint8(params["p"])}
+		return m, nil
+	case "balloon-sha256":
+		if len(fields) != 3 || !hasParams(params, "s", "t", "d") || params["d"] != balloonDelta ||
+			params["s"] > 1<<32-1 || params["t"] > 1<<32-1 {
+			return nil, errBadDescriptor
+		}
+		if params["s"] < 1 || params["t"] < 1 {
+			return nil, errBalloonCost
+		}
+		if params["s"] > maxMHFMemory/sha256.Size {
+			return nil, errMHFMemory
+		}
+		if params["s"]*params["t"] > maxMHFIterations {
+			return nil, errMHFWork
+		}
+		return Balloon(uint32(params["s"]), uint32(params["t"])), nil
+	}
+
+	if strings.HasPrefix(alg, "pbkdf2-") {
+		for h, name := range pbkdf2Hashes {
+			if alg == "pbkdf2-"+name {
+				if len(fields) != 3 || !hasParams(params, "i") {
+					return nil, errBadDescriptor
+				}
+				if params["i"] > maxMHFIterations {
+					return nil, errMHFWork
+				}
+				return PBKDF2(h, int(params["i"])), nil
+			}
+		}
+	}
+
+	return nil, suite.NewError(suite.UnknownSuite, "unknown MHF "+alg)
+}
+
+// parseParams parses "k=v,k=v" with non-negative integer values.
+func parseParams(s string) (map[string]uint64, error) {
+	params := map[string]uint64{}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, errBadDescriptor
+		}
+		n, err := strconv.ParseUint(v, 10, 63)
+		if err != nil {
+			return nil, errBadDescriptor
+		}
+		if _, dup := params[k]; dup {
+			return nil, errBadDescriptor
+		}
+		params[k] = n
+	}
+	return params, nil
+}
+
+func hasParams(params map[string]uint64, keys ...string) bool {
+	if len(params) != len(keys) {
+		return false
+	}
+	for _, k := range keys {
+		if _, ok := params[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type externalMHF struct {
+	descriptor string
+	f          func(ctx context.Context, password, salt []byte, len int) ([]byte, error)
+}
+
+// ExternalMHF returns an MHF that derives through f, e.g. a call to a local key-stretching daemon
+// that gives up when ctx is done. descriptor is what Verifier records; ParseMHF doesn't know it.
+func ExternalMHF(descriptor string, f func(ctx context.Context, password, salt []byte, len int) ([]byte, error)) suite.MHF {
+	return externalMHF{descriptor, f}
+}
+
+func (m externalMHF) Key(password, salt []byte, len int) ([]byte, error) {
+	return m.f(context.Background(), password, salt, len)
+}
+
+func (m externalMHF) KeyContext(ctx context.Context, password, salt []byte, len int) ([]byte, error) {
+	return m.f(ctx, password, salt, len)
+}
+
+func (m externalMHF) String() string {
+	return m.descriptor
+}
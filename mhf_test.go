@@ -0,0 +1,188 @@
+package spake2plus
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestMHFDescriptors(t *testing.T) {
+	for _, m := range []struct {
+		mhf  interface{ String() string }
+		want string
+	}{
+		{Scrypt(16, 1, 1), "$scrypt$ln=4,r=1,p=1"},
+		{Argon2id(1, 64, 4), "$argon2id$v=19$m=64,t=1,p=4"},
+		{Argon2i(3, 32, 1), "$argon2i$v=19$m=32,t=3,p=1"},
+		{PBKDF2(crypto.SHA512, 1000), "$pbkdf2-sha512$i=1000"},
+		{Balloon(16, 2), "$balloon-sha256$s=16,t=2,d=3"},
+	} {
+		assert.Equal(t, m.want, m.mhf.String())
+
+		parsed, err := ParseMHF(m.want)
+		if assert.NoError(t, err, m.want) {
+			assert.Equal(t, m.mhf, parsed)
+		}
+	}
+
+	for _, bad := range []string{"", "scrypt", "$scrypt$ln=4,r=1", "$scrypt$ln=4,r=1,p=1,p=2", "$argon2id$v=16$m=64,t=1,p=4",
+		"$argon2id$m=64,t=1,p=4", "$pbkdf2-sha256$i=x", "$balloon-sha256$s=16,t=2,d=4"} {
+		_, err := ParseMHF(bad)
+		assert.True(t, errors.Is(err, ErrMalformedMessage), bad)
+	}
+	_, err := ParseMHF("$bcrypt$c=10")
+	assert.True(t, errors.Is(err, ErrUnknownSuite))
+
+	// costs the MHF would panic on or reject, or that ask for too much memory or work
+	for _, bad := range []string{"$argon2id$v=19$m=64,t=0,p=4", "$argon2i$v=19$m=64,t=1,p=0", "$argon2id$v=19$m=4194305,t=1,p=4",
+		"$argon2id$v=19$m=4194304,t=5,p=4", "$scrypt$ln=22,r=256,p=1", "$scrypt$ln=62,r=1,p=1", "$scrypt$ln=22,r=8,p=5",
+		"$scrypt$ln=1,r=1,p=9223372036854775807", "$balloon-sha256$s=0,t=1,d=3", "$balloon-sha256$s=134217729,t=1,d=3",
+		"$balloon-sha256$s=16777217,t=1,d=3", "$balloon-sha256$s=1024,t=16385,d=3", "$pbkdf2-sha256$i=16777217"} {
+		_, err := ParseMHF(bad)
+		assert.True(t, errors.Is(err, ErrInvalidInput), bad)
+	}
+	for _, ok := range []string{"$argon2id$v=19$m=4194304,t=4,p=4", "$scrypt$ln=22,r=8,p=4", "$balloon-sha256$s=16777216,t=1,d=3",
+		"$balloon-sha256$s=1024,t=16384,d=3", "$pbkdf2-sha256$i=16777216"} {
+		_, err := ParseMHF(ok)
+		assert.NoError(t, err, ok)
+	}
+}
+
+func TestArgon2Costs(t *testing.T) {
+	for _, m := range []interface {
+		Key(password, salt []byte, len int) ([]byte, error)
+	}{Argon2id(0, 64, 1), Argon2i(1, 64, 0), Argon2(0, 64, 0)} {
+		_, err := m.Key([]byte("password"), []byte("somesalt"), 32)
+		assert.True(t, errors.Is(err, ErrInvalidInput))
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914, section 11
+	key, err := PBKDF2(crypto.SHA256, 1).Key([]byte("passwd"), []byte("salt"), 64)
+	assert.NoError(t, err)
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783", hex.EncodeToString(key))
+
+	_, err = PBKDF2(crypto.MD5, 1).Key([]byte("passwd"), []byte("salt"), 32)
+	assert.True(t, errors.Is(err, ErrUnknownSuite))
+}
+
+func TestBalloon(t *testing.T) {
+	key := func(m interface {
+		Key(password, salt []byte, len int) ([]byte, error)
+	}, password, salt string) []byte {
+		k, err := m.Key([]byte(password), []byte(salt), 40)
+		assert.NoError(t, err)
+		return k
+	}
+
+	k := key(Balloon(16, 2), "password", "NaCl")
+	assert.Len(t, k, 40)
+	assert.Equal(t, k, key(Balloon(16, 2), "password", "NaCl"))
+	assert.NotEqual(t, k, key(Balloon(16, 2), "passwore", "NaCl"))
+	assert.NotEqual(t, k, key(Balloon(16, 2), "password", "NaCm"))
+	assert.NotEqual(t, k, key(Balloon(17, 2), "password", "NaCl"))
+	assert.NotEqual(t, k, key(Balloon(16, 3), "password", "NaCl"))
+
+	_, err := Balloon(0, 1).Key([]byte("password"), []byte("NaCl"), 32)
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+
+	// the test vectors published with the nachonavarro/balloon-hashing implementation
+	for _, v := range []struct {
+		password, salt string
+		space, time    uint32
+		want           string
+	}{
+		{"hunter42", "examplesalt", 1024, 3, "716043dff777b44aa7b88dcbab12c078abecfac9d289c5b5195967aa63440dfb"},
+		{"", "salt", 3, 3, "5f02f8206f9cd212485c6bdf85527b698956701ad0852106f94b94ee94577378"},
+		{"password", "", 3, 3, "20aa99d7fe3f4df4bd98c655c5480ec98b143107a331fd491deda885c4d6a6cc"},
+		{"\x00", "\x00", 3, 3, "4fc7e302ffa29ae0eac31166cee7a552d1d71135f4e0da66486fb68a749b73a4"},
+		{"password", "salt", 1, 1, "eefda4a8a75b461fa389c1dcfaf3e9dfacbc26f81f22e6f280d15cc18c417545"},
+	} {
+		block := balloon([]byte(v.password), []byte(v.salt), v.space, v.time)
+		assert.Equal(t, v.want, hex.EncodeToString(block), v.password)
+
+		want := make([]byte, 40)
+		_, err := io.ReadFull(hkdf.Expand(sha256.New, block, []byte("balloon")), want)
+		assert.NoError(t, err)
+		assert.Equal(t, want, key(Balloon(v.space, v.time), v.password, v.salt), v.password)
+	}
+}
+
+func TestArgon2IsArgon2i(t *testing.T) {
+	a, err := Argon2(1, 64, 1).Key([]byte("password"), []byte("somesalt"), 32)
+	assert.NoError(t, err)
+	b, err := Argon2i(1, 64, 1).Key([]byte("password"), []byte("somesalt"), 32)
+	assert.NoError(t, err)
+	c, err := Argon2id(1, 64, 1).Key([]byte("password"), []byte("somesalt"), 32)
+	assert.NoError(t, err)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestVerifierCarriesMHF(t *testing.T) {
+	mhf := Argon2id(1, 64, 1)
+	client, err := NewClient(P256Sha256HkdfHmac(mhf), []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	assert.NoError(t, err)
+	v, err := client.Verifier()
+	assert.NoError(t, err)
+	assert.Equal(t, mhf.String(), v.MHF)
+
+	parsed, err := ParseMHF(v.MHF)
+	assert.NoError(t, err)
+	assert.Equal(t, mhf, parsed)
+}
+
+func TestNewClientContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewClientContext(ctx, P256Sha256HkdfHmac(mhfScrypt), []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// a daemon that never answers
+	stuck := ExternalMHF("$daemon$v=1", func(ctx context.Context, password, salt []byte, len int) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = NewClientContext(ctx, P256Sha256HkdfHmac(stuck), []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	remote := ExternalMHF("$daemon$v=1", func(ctx context.Context, password, salt []byte, len int) ([]byte, error) {
+		return mhfScrypt.Key(password, salt, len)
+	})
+	for _, s := range []Suite{P256Sha256HkdfHmac, Ed25519Sha256HkdfHmac} {
+		testSPAKE2PlusScrypt(t, s, remote)
+	}
+}
+
+func TestNewClientWithW0W1(t *testing.T) {
+	for _, s := range testSuites {
+		cs := s(mhfScrypt)
+		w0, w1, err := computeW0W1(context.Background(), cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+		assert.NoError(t, err)
+
+		newPair := func(s Suite, db *MapLookup) (*Client, *Server, error) {
+			server, err := NewServer(cs, db, []byte("server"))
+			if err != nil {
+				return nil, nil, err
+			}
+			client, err := NewClientWithW0W1(cs, []byte("client"), []byte("server"), w0, w1)
+			return client, server, err
+		}
+		runFixedHandshake(t, s, newPair)
+
+		_, err = NewClientWithW0W1(cs, []byte("client"), []byte("server"), nil, w1)
+		assert.True(t, errors.Is(err, ErrBadLength))
+		_, err = NewClientWithW0W1(cs, []byte("client"), []byte("server"), w0, make([]byte, cs.Group().ScalarLen()+1))
+		assert.True(t, errors.Is(err, ErrBadLength))
+	}
+}
@@ -0,0 +1,111 @@
+package spake2plus
+
+import (
+	"context"
+	"errors"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"time"
+)
+
+// Side tells an event of a Client from one of a Server.
+type Side int
+
+const (
+	ClientSide Side = iota
+	ServerSide
+)
+
+func (s Side) String() string {
+	if s == ServerSide {
+		return "server"
+	}
+	return "client"
+}
+
+// Observer receives an event for every handshake and confirmation, e.g. to count them or time
+// them; see WithObserver. Events carry no identity, source, share or key, only the side, the suite
+// and the outcome, so they can be logged and exported as they are, with the exception of
+// HandshakeEvent.UserFound.
+//
+// Methods are called synchronously, from whichever goroutine runs the handshake, so they must be
+// quick and safe for concurrent use.
+type Observer interface {
+	// HandshakeStarted is called when a server gets a client's share, or a client the server's.
+	HandshakeStarted(e HandshakeEvent)
+	// HandshakeFinished is called once that share has been processed, or has failed to be.
+	HandshakeFinished(e HandshakeEvent)
+	// Confirmed is called on every SharedSecret.Verify.
+	Confirmed(e ConfirmationEvent)
+}
+
+// HandshakeEvent describes a handshake. Duration, Failed and Reason are only set by
+// HandshakeFinished.
+type HandshakeEvent struct {
+	Side     Side
+	Suite    string // the CipherSuite's ID
+	Start    time.Time
+	Duration time.Duration
+	Failed   bool
+	Reason   Reason // why it failed, when it did
+
+	// Context is the one passed to Server.HandshakeContext, Server.HandshakeFromContext or
+	// Client.CompleteHandshakeContext, e.g. to parent a span; other entry points give
+	// context.Background().
+	Context context.Context
+
+	// UserFound reports, on a server, whether the Lookup had a verifier for the identity; it's
+	// false when the handshake failed before the Lookup was consulted. The server goes out of its
+	// way to hide this from clients, so it must stay inside the server: never log it next to
+	// anything that identifies the handshake, and never export it. The adapters of this module
+	// ignore it.
+	UserFound bool
+}
+
+// ConfirmationEvent describes a SharedSecret.Verify. Elapsed is the time since the handshake
+// started.
+type ConfirmationEvent struct {
+	Side    Side
+	Suite   string
+	Start   time.Time
+	Elapsed time.Duration
+	Failed  bool
+	Reason  Reason
+	Context context.Context // that of SharedSecret.VerifyContext, or else of the handshake
+}
+
+// NopObserver ignores every event. Embed it to implement only some of Observer's methods.
+type NopObserver struct{}
+
+func (NopObserver) HandshakeStarted(HandshakeEvent)  {}
+func (NopObserver) HandshakeFinished(HandshakeEvent) {}
+func (NopObserver) Confirmed(ConfirmationEvent)      {}
+
+// observation is what a SharedSecret needs to report its confirmations.
+type observation struct {
+	observer Observer
+	side     Side
+	suite    string
+	start    time.Time
+	ctx      context.Context // the handshake's
+}
+
+// confirmed reports a Verify in ctx, or in the handshake's context when ctx is nil.
+func (o *observation) confirmed(ctx context.Context, err error) {
+	if ctx == nil {
+		ctx = o.ctx
+	}
+	e := ConfirmationEvent{Side: o.side, Suite: o.suite, Start: o.start, Elapsed: time.Since(o.start), Context: ctx}
+	if err != nil {
+		e.Failed, e.Reason = true, reasonOf(err)
+	}
+	o.observer.Confirmed(e)
+}
+
+// reasonOf returns the Reason of err, or ReasonUnknown for an error without one.
+func reasonOf(err error) Reason {
+	var e *suite.Error
+	if errors.As(err, &e) {
+		return e.Reason
+	}
+	return ReasonUnknown
+}
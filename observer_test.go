@@ -0,0 +1,99 @@
+package spake2plus
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type recorder struct {
+	mu            sync.Mutex
+	started       []HandshakeEvent
+	finished      []HandshakeEvent
+	confirmations []ConfirmationEvent
+}
+
+func (r *recorder) HandshakeStarted(e HandshakeEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, e)
+}
+
+func (r *recorder) HandshakeFinished(e HandshakeEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, e)
+}
+
+func (r *recorder) Confirmed(e ConfirmationEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.confirmations = append(r.confirmations, e)
+}
+
+func TestObserver(t *testing.T) {
+	cs := Ed25519Sha256HkdfHmac(mhfScrypt)
+	db := NewMapLookup()
+	serverEvents, clientEvents := new(recorder), new(recorder)
+	server, err := NewServer(cs, db, []byte("server"), WithObserver(serverEvents))
+	assert.NoError(t, err)
+
+	login := func(identity, password string) {
+		client, err := NewClient(cs, []byte(identity), []byte("server"), []byte(password), []byte("NaCl"), WithObserver(clientEvents))
+		assert.NoError(t, err)
+		if identity == "alice" && password == "password" {
+			v, err := client.Verifier()
+			assert.NoError(t, err)
+			db.Add([]byte(identity), v)
+		}
+		A, err := client.EphemeralPublic()
+		assert.NoError(t, err)
+		m, sB, err := server.Handshake([]byte(identity), A)
+		assert.NoError(t, err)
+		sA, err := client.CompleteHandshake(m)
+		assert.NoError(t, err)
+		if sA.Verify(sB.Confirmation()) == nil {
+			sB.Verify(sA.Confirmation())
+		}
+	}
+	login("alice", "password")
+	login("alice", "guess")
+	login("mallory", "guess")
+	_, _, err = server.Handshake([]byte("alice"), []byte("short"))
+	assert.Error(t, err)
+
+	id := cs.ID()
+	assert.Len(t, serverEvents.started, 4)
+	if assert.Len(t, serverEvents.finished, 4) {
+		for i, found := range []bool{true, true, false, true} {
+			e := serverEvents.finished[i]
+			assert.Equal(t, ServerSide, e.Side)
+			assert.Equal(t, id, e.Suite)
+			assert.Equal(t, found, e.UserFound, i)
+			assert.Equal(t, serverEvents.started[i].Start, e.Start)
+			assert.Positive(t, e.Duration)
+			assert.Equal(t, context.Background(), e.Context)
+		}
+		assert.True(t, serverEvents.finished[3].Failed)
+		assert.Equal(t, BadLength, serverEvents.finished[3].Reason)
+	}
+
+	// the client gives up on a wrong server confirmation, so only alice's login reaches the server's
+	assert.Equal(t, []bool{false, true, true}, failures(clientEvents.confirmations))
+	assert.Equal(t, []bool{false}, failures(serverEvents.confirmations))
+	assert.Equal(t, ConfirmationMismatch, clientEvents.confirmations[1].Reason)
+	for _, e := range clientEvents.finished {
+		assert.Equal(t, ClientSide, e.Side)
+		assert.False(t, e.Failed)
+		assert.False(t, e.UserFound)
+	}
+}
+
+func failures(events []ConfirmationEvent) []bool {
+	var failed []bool
+	for _, e := range events {
+		failed = append(failed, e.Failed)
+	}
+	return failed
+}
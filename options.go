@@ -0,0 +1,75 @@
+package spake2plus
+
+import (
+	"io"
+)
+
+// Option configures a Client or a Server. Options that don't apply to the side being built are ignored.
+type Option func(*options)
+
+type options struct {
+	rand          io.Reader
+	precis        bool
+	verifierCache int
+	limiter       AttemptLimiter
+	observer      Observer
+	audit         AuditSink
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRand sets the source of randomness for ephemeral scalars and simulated verifiers.
+// It defaults to crypto/rand; anything else is only meant for reproducible tests.
+func WithRand(r io.Reader) Option {
+	return func(o *options) {
+		o.rand = r
+	}
+}
+
+// WithPRECIS prepares passwords with PreparePassword and identities with PrepareIdentity before
+// they are used. Clients and servers have to agree on it, as it changes verifiers and transcripts,
+// and verifiers have to be stored under their prepared identity, Verifier.I.
+func WithPRECIS() Option {
+	return func(o *options) {
+		o.precis = true
+	}
+}
+
+// WithVerifierCache makes a Server keep the decoded verifiers of the size most recently seen
+// identities, so repeated logins skip parsing L and w0 and computing w0*M and w0*N. An entry is
+// used only while the Lookup still returns the same verifier. The cache holds secrets as
+// sensitive as the verifiers themselves; Destroy empties it.
+func WithVerifierCache(size int) Option {
+	return func(o *options) {
+		o.verifierCache = size
+	}
+}
+
+// WithAttemptLimiter makes a Server ask l before every handshake, and tell it which ones the
+// client confirmed. Handshakes it refuses fail with ErrRateLimited.
+func WithAttemptLimiter(l AttemptLimiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
+
+// WithObserver reports the handshakes and confirmations of a Client or a Server to o.
+func WithObserver(o Observer) Option {
+	return func(opts *options) {
+		opts.observer = o
+	}
+}
+
+// WithAuditSink makes a Server record every login attempt with a, from the handshake to the
+// confirmation, or to the SharedSecret's Destroy when there was none.
+func WithAuditSink(a AuditSink) Option {
+	return func(o *options) {
+		o.audit = a
+	}
+}
@@ -0,0 +1,88 @@
+package spake2plus
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+type transcript struct {
+	A, B, cA, cB, ke []byte
+}
+
+func fixedRand() io.Reader {
+	return bytes.NewReader(bytes.Repeat([]byte{0x07}, 4096))
+}
+
+func runFixedHandshake(t *testing.T, testSuite Suite, newPair func(s Suite, db *MapLookup) (*Client, *Server, error)) *transcript {
+	db := NewMapLookup()
+	client, server, err := newPair(testSuite, db)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	v, err := client.Verifier()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	db.Add([]byte("client"), v)
+
+	A, err := client.EphemeralPublic()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	m, sB, err := server.Handshake([]byte("client"), A)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	sA, err := client.CompleteHandshake(m)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	assert.NoError(t, sA.Verify(sB.Confirmation()))
+	assert.NoError(t, sB.Verify(sA.Confirmation()))
+
+	return &transcript{A, m.B, sA.Confirmation(), sB.Confirmation(), sA.Bytes()}
+}
+
+func TestWithRandIsReproducible(t *testing.T) {
+	newPair := func(s Suite, db *MapLookup) (*Client, *Server, error) {
+		cs := s(mhfScrypt)
+		server, err := NewServer(cs, db, []byte("server"), WithRand(fixedRand()))
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err := NewClient(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"), WithRand(fixedRand()))
+		return client, server, err
+	}
+
+	for _, s := range testSuites {
+		first := runFixedHandshake(t, s, newPair)
+		second := runFixedHandshake(t, s, newPair)
+		assert.Equal(t, first, second)
+	}
+}
+
+func TestWithScalar(t *testing.T) {
+	for _, s := range testSuites {
+		g := s(mhfScrypt).Group()
+		x, err := g.RandomScalar(nil)
+		assert.NoError(t, err)
+		y, err := g.RandomScalar(nil)
+		assert.NoError(t, err)
+
+		newPair := func(s Suite, db *MapLookup) (*Client, *Server, error) {
+			cs := s(mhfScrypt)
+			server, err := NewServerWithScalar(cs, db, []byte("server"), y.Bytes())
+			if err != nil {
+				return nil, nil, err
+			}
+			client, err := NewClientWithScalar(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"), x.Bytes())
+			return client, server, err
+		}
+
+		first := runFixedHandshake(t, s, newPair)
+		second := runFixedHandshake(t, s, newPair)
+		assert.Equal(t, first, second)
+	}
+}
@@ -0,0 +1,44 @@
+package spake2plus
+
+import (
+	"github.com/jtejido/spake2plus/internal/suite"
+	"golang.org/x/text/secure/precis"
+)
+
+// PreparePassword applies the OpaqueString profile of RFC 8265 to a UTF-8 password: it maps
+// non-ASCII spaces to ASCII space and normalizes to NFC, so that "café" typed in NFC and in NFD
+// derive the same verifier. Empty passwords and ones with control characters are rejected.
+func PreparePassword(password []byte) ([]byte, error) {
+	out, err := precis.OpaqueString.Bytes(password)
+	if err != nil {
+		return nil, suite.NewError(suite.InvalidInput, "password rejected by the PRECIS OpaqueString profile: "+err.Error())
+	}
+	return out, nil
+}
+
+// PrepareIdentity applies the UsernameCaseMapped profile of RFC 8265 to a UTF-8 identity: it maps
+// full-width characters, lower-cases and normalizes to NFC. Identities with spaces, symbols or
+// unassigned code points are rejected.
+func PrepareIdentity(identity []byte) ([]byte, error) {
+	out, err := precis.UsernameCaseMapped.Bytes(identity)
+	if err != nil {
+		return nil, suite.NewError(suite.InvalidInput, "identity rejected by the PRECIS UsernameCaseMapped profile: "+err.Error())
+	}
+	return out, nil
+}
+
+// prepareIdentities applies PrepareIdentity to every non-empty identity, as empty ones are left
+// out of the transcript.
+func prepareIdentities(ids ...*[]byte) error {
+	for _, id := range ids {
+		if len(*id) == 0 {
+			continue
+		}
+		p, err := PrepareIdentity(*id)
+		if err != nil {
+			return err
+		}
+		*id = p
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package spake2plus
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPRECIS(t *testing.T) {
+	s := P256Sha256HkdfHmac(mhfScrypt)
+	nfc := []byte("caf\u00e9")
+	nfd := []byte("cafe\u0301")
+
+	verifier := func(identity, password []byte, opts ...Option) *Verifier {
+		c, err := NewClient(s, identity, []byte("server"), password, []byte("NaCl"), opts...)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		v, err := c.Verifier()
+		assert.NoError(t, err)
+		return v
+	}
+
+	assert.NotEqual(t, verifier([]byte("alice"), nfc).Verifier, verifier([]byte("alice"), nfd).Verifier)
+	assert.Equal(t, verifier([]byte("alice"), nfc, WithPRECIS()), verifier([]byte("Alice"), nfd, WithPRECIS()))
+
+	for _, c := range []struct{ identity, password string }{
+		{"alice", ""},
+		{"alice", "pass\u0007word"},
+		{"alice bob", "password"},
+		{"alice☃", "password"},
+	} {
+		_, err := NewClient(s, []byte(c.identity), []byte("server"), []byte(c.password), []byte("NaCl"), WithPRECIS())
+		assert.True(t, errors.Is(err, ErrInvalidInput), c)
+	}
+}
+
+func TestPRECISHandshake(t *testing.T) {
+	s := P256Sha256HkdfHmac(mhfScrypt)
+	db := NewMapLookup()
+
+	registering, err := NewClient(s, []byte("Alice"), []byte("Server"), []byte("caf\u00e9"), []byte("NaCl"), WithPRECIS())
+	assert.NoError(t, err)
+	v, err := registering.Verifier()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("alice"), v.I)
+	db.Add(v.I, v)
+
+	server, err := NewServer(s, db, []byte("server"), WithPRECIS())
+	assert.NoError(t, err)
+	client, err := NewClient(s, []byte("ALICE"), []byte("server"), []byte("cafe\u0301"), []byte("NaCl"), WithPRECIS())
+	assert.NoError(t, err)
+
+	A, err := client.EphemeralPublic()
+	assert.NoError(t, err)
+	m, sB, err := server.Handshake([]byte("ALICE"), A)
+	assert.NoError(t, err)
+	sA, err := client.CompleteHandshake(m)
+	assert.NoError(t, err)
+	assert.NoError(t, sA.Verify(sB.Confirmation()))
+	assert.NoError(t, sB.Verify(sA.Confirmation()))
+
+	_, _, err = server.Handshake([]byte("alice bob"), A)
+	assert.True(t, errors.Is(err, ErrInvalidInput))
+}
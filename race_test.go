@@ -0,0 +1,7 @@
+//go:build race
+
+package spake2plus
+
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,67 @@
+#!/bin/sh
+# Runs the benchmarks in a benchstat-friendly form.
+#
+#   scripts/bench.sh groups [regexp]        compare the suites side by side
+#   scripts/bench.sh compare OLD [NEW]      compare two commits (NEW defaults to the work tree)
+#   scripts/bench.sh profile [regexp]       write CPU and memory profiles to $OUT/bench.{cpu,mem}
+#
+# BENCH (default: the per-step benchmarks), COUNT (default 10) and BENCHTIME are passed on to go
+# test. Results are kept in $OUT (default ./bench-out). benchstat comes from
+# golang.org/x/perf/cmd/benchstat; without it the raw results are printed.
+set -eu
+
+BENCH=${BENCH:-'^Benchmark(EphemeralPublic|Handshake|HandshakeCached|CompleteHandshake|Confirmation)$'}
+COUNT=${COUNT:-10}
+BENCHTIME=${BENCHTIME:-1s}
+OUT=${OUT:-bench-out}
+root=$(git rev-parse --show-toplevel)
+mkdir -p "$OUT"
+OUT=$(cd "$OUT" && pwd)
+
+# run DIR FILE [ARGS...] runs the benchmarks of the module checked out in DIR.
+run() {
+	dir=$1 file=$2
+	shift 2
+	(cd "$dir" && go test -run '^$' -bench "$BENCH" -count "$COUNT" -benchtime "$BENCHTIME" "$@" .) | tee "$file"
+}
+
+stat() {
+	if command -v benchstat >/dev/null 2>&1; then
+		benchstat "$@"
+	else
+		echo "benchstat not found: go install golang.org/x/perf/cmd/benchstat@latest" >&2
+	fi
+}
+
+case ${1:-} in
+groups)
+	[ $# -ge 2 ] && BENCH=$2
+	run "$root" "$OUT/groups.txt" >/dev/null
+	stat -col /suite "$OUT/groups.txt"
+	;;
+compare)
+	[ $# -ge 2 ] || { echo "usage: $0 compare OLD [NEW]" >&2; exit 2; }
+	old=$2 new=${3:-}
+	tree=$(mktemp -d)
+	trap 'git -C "$root" worktree remove --force "$tree/old" >/dev/null 2>&1 || true; [ -z "$new" ] || git -C "$root" worktree remove --force "$tree/new" >/dev/null 2>&1 || true; rm -rf "$tree"' EXIT
+	git -C "$root" worktree add --detach "$tree/old" "$old" >/dev/null
+	newdir=$root
+	if [ -n "$new" ]; then
+		git -C "$root" worktree add --detach "$tree/new" "$new" >/dev/null
+		newdir=$tree/new
+	fi
+	run "$tree/old" "$OUT/old.txt" >/dev/null
+	run "$newdir" "$OUT/new.txt" >/dev/null
+	stat "$OUT/old.txt" "$OUT/new.txt"
+	;;
+profile)
+	[ $# -ge 2 ] && BENCH=$2
+	COUNT=1
+	run "$root" "$OUT/profile.txt" -cpuprofile "$OUT/bench.cpu" -memprofile "$OUT/bench.mem"
+	echo "go tool pprof $OUT/bench.cpu (or $OUT/bench.mem)"
+	;;
+*)
+	sed -n '2,10p' "$0" | sed 's/^# \{0,1\}//'
+	exit 2
+	;;
+esac
@@ -1,10 +1,12 @@
 package spake2plus
 
 import (
-	"errors"
+	"context"
 	"github.com/jtejido/spake2plus/internal/suite"
 )
 
+var errDestroyed = suite.NewError(suite.InvalidState, "shared secret destroyed")
+
 type SharedSecret struct {
 	suite                                  suite.CipherSuite
 	msg, remoteMsg                         []byte
@@ -12,10 +14,13 @@ type SharedSecret struct {
 	keySecret                              []byte
 	keyConfirmation, remoteKeyConfirmation []byte
 	confirmation, remoteConfirmation       []byte
+	verified                               func() // called on the first successful Verify
+	observation                            *observation
+	audit                                  *auditTrail
 }
 
 func newSharedSecret(sharedSecret, keySecret, msg, remoteMsg, keyConfirmation, remoteKeyConfirmation []byte, s suite.CipherSuite) *SharedSecret {
-	return &SharedSecret{s, msg, remoteMsg, sharedSecret, keySecret, keyConfirmation, remoteKeyConfirmation, nil, nil}
+	return &SharedSecret{s, msg, remoteMsg, sharedSecret, keySecret, keyConfirmation, remoteKeyConfirmation, nil, nil, nil, nil, nil}
 }
 
 func (s *SharedSecret) generateConfirmations() {
@@ -25,6 +30,9 @@ func (s *SharedSecret) generateConfirmations() {
 
 // send this to server once verification is complete
 func (s *SharedSecret) Confirmation() []byte {
+	if s.keySecret == nil {
+		return nil
+	}
 	if s.confirmation == nil {
 		s.generateConfirmations()
 	}
@@ -34,11 +42,39 @@ func (s *SharedSecret) Confirmation() []byte {
 
 // Verify verifies an incoming confirmation message.
 func (s *SharedSecret) Verify(incomingConfirmation []byte) error {
+	return s.verifyIn(nil, incomingConfirmation)
+}
+
+// VerifyContext is Verify reporting to the Observer in ctx rather than in the handshake's context.
+func (s *SharedSecret) VerifyContext(ctx context.Context, incomingConfirmation []byte) error {
+	return s.verifyIn(ctx, incomingConfirmation)
+}
+
+func (s *SharedSecret) verifyIn(ctx context.Context, incomingConfirmation []byte) error {
+	err := s.verify(incomingConfirmation)
+	if s.observation != nil {
+		s.observation.confirmed(ctx, err)
+	}
+	if s.audit != nil {
+		s.audit.record(AuditConfirmation, err)
+		s.audit.confirmed = true
+	}
+	return err
+}
+
+func (s *SharedSecret) verify(incomingConfirmation []byte) error {
+	if s.keySecret == nil {
+		return errDestroyed
+	}
 	if s.remoteConfirmation == nil {
 		s.generateConfirmations()
 	}
 	if !s.suite.MacEqual(incomingConfirmation, s.remoteConfirmation) {
-		return errors.New("Verification Failed")
+		return suite.NewError(suite.ConfirmationMismatch, "Verification Failed")
+	}
+	if s.verified != nil {
+		s.verified()
+		s.verified = nil
 	}
 	return nil
 }
@@ -46,3 +82,16 @@ func (s *SharedSecret) Verify(incomingConfirmation []byte) error {
 func (s SharedSecret) Bytes() []byte {
 	return s.sharedSecret
 }
+
+// Destroy overwrites Ke, Ka and the confirmation keys. Slices returned by Bytes share memory with Ke and
+// are wiped too, so copy Ke out first if it has to outlive the SharedSecret.
+func (s *SharedSecret) Destroy() {
+	if s.audit != nil && !s.audit.confirmed && s.keySecret != nil {
+		s.audit.record(AuditAbandoned, nil)
+	}
+	wipe(s.sharedSecret)
+	wipe(s.keySecret)
+	wipe(s.keyConfirmation)
+	wipe(s.remoteKeyConfirmation)
+	s.sharedSecret, s.keySecret, s.keyConfirmation, s.remoteKeyConfirmation = nil, nil, nil, nil
+}
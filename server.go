@@ -1,8 +1,10 @@
 package spake2plus
 
 import (
-	"errors"
+	"context"
 	"github.com/jtejido/spake2plus/internal/suite"
+	"io"
+	"time"
 )
 
 type Server struct {
@@ -10,87 +12,270 @@ type Server struct {
 	suite          suite.CipherSuite
 	y              suite.Scalar
 	serverIdentity []byte
+	rand           io.Reader
+	precis         bool
+	cache          *verifierCache // nil unless WithVerifierCache
+	simulatedL     []byte         // the L of identities without a verifier
+	limiter        AttemptLimiter // nil unless WithAttemptLimiter
+	observer       Observer       // nil unless WithObserver
+	audit          AuditSink      // nil unless WithAuditSink
+	suiteID        string
 }
 
-func NewServer(s suite.CipherSuite, lookup Lookup, serverIdentity []byte) (*Server, error) {
-	y, err := s.Group().RandomScalar()
+func NewServer(s suite.CipherSuite, lookup Lookup, serverIdentity []byte, opts ...Option) (*Server, error) {
+	o := newOptions(opts)
+	y, err := s.Group().RandomScalar(o.rand)
 	if err != nil {
 		return nil, err
 	}
-	return newServer(s, lookup, serverIdentity, y)
+	return newServer(s, lookup, serverIdentity, y, o)
 }
 
-func newServer(s suite.CipherSuite, lookup Lookup, serverIdentity []byte, y suite.Scalar) (*Server, error) {
-	return &Server{lookup, s, y, serverIdentity}, nil
+// NewServerWithScalar is NewServer with a caller-chosen ephemeral scalar y, encoded as the group's
+// Scalar().Bytes() would. It exists for test vectors and interop testing; y must never be reused.
+func NewServerWithScalar(s suite.CipherSuite, lookup Lookup, serverIdentity, y []byte, opts ...Option) (*Server, error) {
+	sc := s.Group().Scalar()
+	if err := sc.FromBytes(y); err != nil {
+		return nil, err
+	}
+	return newServer(s, lookup, serverIdentity, sc, newOptions(opts))
 }
 
-func (s *Server) Handshake(identity, A []byte) (*ServerMaterial, *SharedSecret, error) {
-	// Load the verifier from DB
-	info, ok := s.db.Fetch(identity)
-	incomingElement := s.suite.Group().Element()
-	err := incomingElement.FromBytes(A)
+func newServer(s suite.CipherSuite, lookup Lookup, serverIdentity []byte, y suite.Scalar, o *options) (*Server, error) {
+	if o.precis {
+		if err := prepareIdentities(&serverIdentity); err != nil {
+			return nil, err
+		}
+	}
+	L, err := s.Group().RandomElement(o.rand)
 	if err != nil {
+		return nil, err
+	}
+	return &Server{lookup, s, y, serverIdentity, o.rand, o.precis, newVerifierCache(o.verifierCache), L.Bytes(), o.limiter, o.observer, o.audit, s.ID()}, nil
+}
+
+// Destroy overwrites the ephemeral y used by Handshake and empties the verifier cache. Sessions created
+// with NewSession have their own scalars and are destroyed separately.
+func (s *Server) Destroy() {
+	s.y.Zero()
+	if s.cache != nil {
+		s.cache.purge()
+	}
+}
+
+func (s *Server) Handshake(identity, A []byte) (*ServerMaterial, *SharedSecret, error) {
+	return s.handshake(context.Background(), s.y, nil, identity, A)
+}
+
+// HandshakeFrom is Handshake for a client at source, e.g. its IP address, which the server's
+// AttemptLimiter keeps count of along with the identity.
+func (s *Server) HandshakeFrom(source, identity, A []byte) (*ServerMaterial, *SharedSecret, error) {
+	return s.handshake(context.Background(), s.y, source, identity, A)
+}
+
+// HandshakeContext is Handshake passing ctx to the Observer's events, e.g. to trace the handshake
+// within a request. The handshake itself doesn't stop when ctx is done.
+func (s *Server) HandshakeContext(ctx context.Context, identity, A []byte) (*ServerMaterial, *SharedSecret, error) {
+	return s.handshake(ctx, s.y, nil, identity, A)
+}
+
+// HandshakeFromContext is HandshakeFrom passing ctx to the Observer's events.
+func (s *Server) HandshakeFromContext(ctx context.Context, source, identity, A []byte) (*ServerMaterial, *SharedSecret, error) {
+	return s.handshake(ctx, s.y, source, identity, A)
+}
+
+func (s *Server) handshake(ctx context.Context, y suite.Scalar, source, identity, A []byte) (*ServerMaterial, *SharedSecret, error) {
+	h := &serverHandshake{ws: newWorkspace(s.suite.Group()), ctx: ctx}
+	if err := s.startHandshake(h, y, source, identity, A); err != nil {
 		return nil, nil, err
 	}
+	B := h.Y.Bytes()
+	Z, V := h.ws.encodeZV()
+	return &ServerMaterial{B: B}, s.finishHandshake(h, B, Z, V), nil
+}
 
-	if isElementSmall(s.suite, incomingElement) {
-		return nil, nil, errors.New("Corrupt Message")
+// serverHandshake is a handshake up to Y, Z and V, before they are encoded.
+type serverHandshake struct {
+	source      []byte
+	identity, A []byte
+	e           *verifierEntry
+	ws          *workspace
+	Y           suite.Element
+	found       bool      // whether the Lookup had a verifier, for the Observer
+	start       time.Time // set when there's an Observer or an AuditSink
+	ctx         context.Context
+}
+
+// context returns the context of the handshake, context.Background() for the entry points without
+// one.
+func (h *serverHandshake) context() context.Context {
+	if h.ctx == nil {
+		return context.Background()
 	}
+	return h.ctx
+}
 
-	if !ok {
-		// simulate computations to avoid user enumeration
-		sc, _ := s.suite.Group().RandomScalar()
-		elem, _ := s.suite.Group().RandomElement()
-		info = &UserInfo{
-			Verifier: &Verifier{
-				Verifier: VerifierPair{
-					V1: sc.Bytes(),
-					V2: elem.Bytes(),
-				},
-			},
+// startHandshake fills in h, reusing its workspace and Y when it has them.
+func (s *Server) startHandshake(h *serverHandshake, y suite.Scalar, source, identity, A []byte) error {
+	h.found, h.identity = false, nil
+	if s.observer == nil && s.audit == nil {
+		return s.computeHandshake(h, y, source, identity, A)
+	}
+
+	h.start = time.Now()
+	if s.observer != nil {
+		s.observer.HandshakeStarted(HandshakeEvent{Side: ServerSide, Suite: s.suiteID, Start: h.start, Context: h.context()})
+	}
+	err := s.computeHandshake(h, y, source, identity, A)
+	if err == nil {
+		return nil
+	}
+	if s.observer != nil {
+		s.observer.HandshakeFinished(HandshakeEvent{
+			Side: ServerSide, Suite: s.suiteID, Start: h.start, Duration: time.Since(h.start),
+			Failed: true, Reason: reasonOf(err), Context: h.context(), UserFound: h.found,
+		})
+	}
+	if s.audit != nil {
+		if h.identity == nil {
+			h.identity = identity // it couldn't be prepared
+		}
+		s.audit.Audit(AuditEvent{
+			Kind: AuditHandshake, Time: h.start, Suite: s.suiteID, Identity: h.identity, Source: source,
+			Failed: true, Reason: reasonOf(err),
+		})
+	}
+	return err
+}
+
+// computeHandshake is startHandshake without the Observer and the AuditSink.
+func (s *Server) computeHandshake(h *serverHandshake, y suite.Scalar, source, identity, A []byte) error {
+	// a rejected identity can't have been registered, so this tells nothing about the database
+	if s.precis {
+		if err := prepareIdentities(&identity); err != nil {
+			return err
 		}
 	}
+	h.identity = identity
 
-	v1 := info.Verifier.Verifier.V1
-	v2 := info.Verifier.Verifier.V2
-	w0 := s.suite.Group().Scalar()
-	err = w0.FromBytes(padScalarBytes(v1, s.suite.Group().ScalarLen()))
+	// nor does the limiter, which is asked before the database
+	if s.limiter != nil && !s.limiter.Attempt(identity, source) {
+		return errRateLimited
+	}
+	ws := h.ws
+
+	// Load the verifier from DB
+	info, ok := s.db.Fetch(identity)
+	h.found = ok
+	err := ws.incoming.FromBytes(A)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
 
-	// Y=y*P+w0*N
-	Y := s.suite.Group().Element().ScalarMult(s.y, nil)
-	Y.Add(Y, s.suite.Group().Element().ScalarMult(w0, s.suite.Group().N()))
-	YBytes := Y.Bytes()
+	if ws.isElementSmall(ws.incoming) {
+		return suite.NewError(suite.SmallOrderPoint, "Corrupt Message")
+	}
 
-	LElement := s.suite.Group().Element()
-	err = LElement.FromBytes(v2)
+	e, err := s.verifier(identity, info, ok)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
+	h.source, h.identity, h.A, h.e = source, identity, A, e
 
-	// B computes Z as h*y*(X-w0*M) and V as h*y*L.
-	tmp := s.suite.Group().Element().ScalarMult(s.suite.Group().Scalar().Negate(w0), s.suite.Group().M())
-	tmp.Add(incomingElement, tmp)
+	// Y=y*P+w0*N
+	if h.Y == nil {
+		h.Y = s.suite.Group().Element()
+	}
+	h.Y.ScalarMult(y, nil)
+	h.Y.Add(h.Y, e.w0N)
 
-	ZElement := s.suite.Group().Element().ScalarMult(s.y, tmp)
-	ZElement = s.suite.Group().ClearCofactor(ZElement)
-	ZBytes := ZElement.Bytes()
+	// B computes Z as h*y*(X-w0*M) and V as h*y*L.
+	ws.tmp.Add(ws.incoming, e.minusW0M)
+	ws.rawZ.ScalarMult(y, ws.tmp)
+	ws.rawV.ScalarMult(y, e.L)
+	ws.clearCofactors()
 
-	VElement := s.suite.Group().Element().ScalarMult(s.y, LElement)
-	VElement = s.suite.Group().ClearCofactor(VElement)
-	VBytes := VElement.Bytes()
+	return nil
+}
 
+// finishHandshake derives the keys from the encoded Y, Z and V, and wipes Z and V.
+func (s *Server) finishHandshake(h *serverHandshake, YBytes, ZBytes, VBytes []byte) *SharedSecret {
 	// You better store it elsewhere, regardless if valid user or not, as you'll be checking multiple users, that's why I'm returning it.
-	sharedSecret := newServerSharedSecret(identity, s.serverIdentity, A, YBytes, ZBytes, VBytes, v1, s.suite)
-
-	return &ServerMaterial{
-		B: YBytes,
-	}, sharedSecret, nil
+	secret := h.ws.sharedSecret(s.suite, h.identity, s.serverIdentity, h.A, YBytes, ZBytes, VBytes, h.e.v1, false)
+	if s.limiter != nil || s.audit != nil {
+		// copied, as the caller may reuse them
+		identity, source := append([]byte(nil), h.identity...), h.source
+		if source != nil {
+			source = append([]byte(nil), source...)
+		}
+		if s.limiter != nil {
+			secret.verified = func() { s.limiter.Succeeded(identity, source) }
+		}
+		if s.audit != nil {
+			e := AuditEvent{Kind: AuditHandshake, Time: h.start, Suite: s.suiteID, Identity: identity, Source: source}
+			s.audit.Audit(e)
+			secret.audit = &auditTrail{sink: s.audit, event: e}
+		}
+	}
+	if s.observer != nil {
+		s.observer.HandshakeFinished(HandshakeEvent{
+			Side: ServerSide, Suite: s.suiteID, Start: h.start, Duration: time.Since(h.start), Context: h.context(),
+			UserFound: h.found,
+		})
+		secret.observation = &observation{s.observer, ServerSide, s.suiteID, h.start, h.context()}
+	}
+	return secret
 }
 
-func newServerSharedSecret(idA, idB, X, Y, Z, V, w0 []byte, s suite.CipherSuite) *SharedSecret {
-	Ke, Ka, kcA, kcB := generateSharedSecrets(s, idA, idB, X, Y, Z, V, w0)
-	return newSharedSecret(Ke, Ka, Y, X, kcB, kcA, s)
+// verifier returns the decoded verifier of identity, from the cache when there's a current entry.
+// Identities without a verifier get a simulated one so they can't be told apart. The cache keeps
+// those too, which makes a repeated attempt on an unknown identity as fast and as consistent as
+// one on a registered identity.
+func (s *Server) verifier(identity []byte, info *UserInfo, ok bool) (*verifierEntry, error) {
+	var v1, v2 []byte
+	if ok {
+		v1, v2 = info.Verifier.Verifier.V1, info.Verifier.Verifier.V2
+	}
+	if s.cache != nil {
+		if e := s.cache.get(identity); e != nil && e.current(ok, v1, v2) {
+			return e, nil
+		}
+	}
+
+	// simulate computations to avoid user enumeration: w0 is drawn either way, and L is the
+	// server's, so that both cases take the same time
+	sc, err := s.suite.Group().RandomScalar(s.rand)
+	if err != nil {
+		return nil, err
+	}
+	defer sc.Zero()
+	if !ok {
+		v1, v2 = sc.Bytes(), s.simulatedL
+	}
+
+	g := s.suite.Group()
+	w0 := g.Scalar()
+	if err := w0.FromBytes(padScalarBytes(v1, g.ScalarLen())); err != nil {
+		return nil, err
+	}
+	defer w0.Zero()
+
+	L := g.Element()
+	if err := L.FromBytes(v2); err != nil {
+		return nil, err
+	}
+
+	e := &verifierEntry{
+		identity:  string(identity),
+		v1:        append([]byte(nil), v1...),
+		v2:        append([]byte(nil), v2...),
+		simulated: !ok,
+		L:         L,
+		w0N:       g.Element().ScalarMult(w0, g.N()),
+		minusW0M:  g.Element().ScalarMult(g.Scalar().Negate(w0), g.M()),
+	}
+	if s.cache != nil {
+		s.cache.put(e)
+	}
+	return e, nil
 }
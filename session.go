@@ -0,0 +1,289 @@
+package spake2plus
+
+import (
+	"context"
+	"github.com/jtejido/spake2plus/internal/suite"
+)
+
+// SessionState is the position of a ClientSession or ServerSession in the protocol.
+type SessionState int
+
+const (
+	// StateStart means nothing has been sent yet.
+	StateStart SessionState = iota
+	// StateSentShare means the session's public share (X or Y) has been produced.
+	StateSentShare
+	// StateSentConfirm means the server has produced its confirmation and waits for the client's.
+	StateSentConfirm
+	// StateDone means the peer's confirmation checked out and the shared secret may be used.
+	StateDone
+	// StateFailed ends the handshake. Only Reset, which starts a new one, leaves it.
+	StateFailed
+)
+
+var errInvalidState = suite.NewError(suite.InvalidState, "invalid session state")
+
+func (s SessionState) String() string {
+	switch s {
+	case StateStart:
+		return "Start"
+	case StateSentShare:
+		return "SentShare"
+	case StateSentConfirm:
+		return "SentConfirm"
+	case StateDone:
+		return "Done"
+	case StateFailed:
+		return "Failed"
+	}
+	return "Unknown"
+}
+
+// ServerSession is a single handshake with a single client, with its own ephemeral y.
+//
+// The server moves through StateStart -> StateSentShare (Handshake) -> StateSentConfirm (Confirmation)
+// -> StateDone (Verify). Calling a method out of order returns an error without changing the state,
+// and any failure moves the session to StateFailed, which ends the handshake.
+//
+// Reset starts the next handshake on the same session from any state, StateFailed included,
+// reusing its elements and buffers, which keeps a busy server from allocating much per handshake.
+type ServerSession struct {
+	server *Server
+	y      suite.Scalar
+	state  SessionState
+	secret *SharedSecret
+	h      serverHandshake
+}
+
+// NewSession starts a handshake with a fresh ephemeral scalar.
+func (s *Server) NewSession() (*ServerSession, error) {
+	y, err := s.suite.Group().RandomScalar(s.rand)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerSession{server: s, y: y, h: serverHandshake{ws: newWorkspace(s.suite.Group())}}, nil
+}
+
+// Reset overwrites the keys of the current handshake and starts a new one with a fresh ephemeral
+// scalar. Slices returned by AppendHandshake and SharedSecret must not be used afterwards.
+func (ss *ServerSession) Reset() error {
+	ss.wipe()
+	y, err := ss.server.suite.Group().RandomScalar(ss.server.rand)
+	if err != nil {
+		ss.state = StateFailed
+		return err
+	}
+	ss.y = y
+	ss.state = StateStart
+	return nil
+}
+
+// State returns the current state of the session.
+func (ss *ServerSession) State() SessionState {
+	return ss.state
+}
+
+// Handshake processes the client's identity and share A, and returns B.
+func (ss *ServerSession) Handshake(identity, A []byte) (*ServerMaterial, error) {
+	B, err := ss.AppendHandshake(nil, identity, A)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerMaterial{B: B}, nil
+}
+
+// HandshakeFrom is Handshake for a client at source, as with Server.HandshakeFrom.
+func (ss *ServerSession) HandshakeFrom(source, identity, A []byte) (*ServerMaterial, error) {
+	B, err := ss.AppendHandshakeFrom(nil, source, identity, A)
+	if err != nil {
+		return nil, err
+	}
+	return &ServerMaterial{B: B}, nil
+}
+
+// AppendHandshake is Handshake appending B to dst. A is copied, so the caller may reuse it.
+func (ss *ServerSession) AppendHandshake(dst, identity, A []byte) ([]byte, error) {
+	return ss.AppendHandshakeFrom(dst, nil, identity, A)
+}
+
+// AppendHandshakeFrom is HandshakeFrom appending B to dst.
+func (ss *ServerSession) AppendHandshakeFrom(dst, source, identity, A []byte) ([]byte, error) {
+	if ss.state != StateStart {
+		return nil, errInvalidState
+	}
+
+	h, ws := &ss.h, ss.h.ws
+	ws.x = append(ws.x[:0], A...)
+	if err := ss.server.startHandshake(h, ss.y, source, identity, ws.x); err != nil {
+		ss.state = StateFailed
+		return nil, err
+	}
+	ws.y = suite.AppendElement(ws.y[:0], h.Y)
+	Z, V := ws.encodeZV()
+
+	ss.secret = ss.server.finishHandshake(h, ws.y, Z, V)
+	ss.state = StateSentShare
+	return append(dst, ws.y...), nil
+}
+
+// Confirmation returns the server's confirmation message cB, sent along with B.
+func (ss *ServerSession) Confirmation() ([]byte, error) {
+	if ss.state != StateSentShare {
+		return nil, errInvalidState
+	}
+
+	ss.state = StateSentConfirm
+	return ss.secret.Confirmation(), nil
+}
+
+// Verify checks the client's confirmation message cA. The first failure ends the handshake.
+func (ss *ServerSession) Verify(incomingConfirmation []byte) error {
+	if ss.state != StateSentConfirm {
+		return errInvalidState
+	}
+
+	if err := ss.secret.Verify(incomingConfirmation); err != nil {
+		ss.secret.Destroy()
+		ss.state = StateFailed
+		return err
+	}
+
+	ss.state = StateDone
+	return nil
+}
+
+// SharedSecret returns Ke once the session is done.
+func (ss *ServerSession) SharedSecret() ([]byte, error) {
+	if ss.state != StateDone {
+		return nil, errInvalidState
+	}
+	return ss.secret.Bytes(), nil
+}
+
+// Destroy overwrites y and the session's keys, and moves the session to StateFailed.
+func (ss *ServerSession) Destroy() {
+	ss.wipe()
+	ss.state = StateFailed
+}
+
+// wipe overwrites y and the session's keys.
+func (ss *ServerSession) wipe() {
+	ss.y.Zero()
+	if ss.secret != nil {
+		ss.secret.Destroy()
+		ss.secret = nil
+	}
+}
+
+// ClientSession is a single handshake with the server, with its own ephemeral x.
+//
+// The client moves through StateStart -> StateSentShare (Share) -> StateDone (Confirm). Since the
+// client's confirmation is the last message of the protocol, it is only released after the server's
+// confirmation checks out. Calling a method out of order returns an error without changing the state,
+// and any failure moves the session to StateFailed, which ends the handshake.
+//
+// Like a ServerSession, a ClientSession can be Reset for the next handshake from any state.
+type ClientSession struct {
+	client *Client
+	x      suite.Scalar
+	state  SessionState
+	secret *SharedSecret
+	ws     *workspace
+}
+
+// NewSession starts a handshake with a fresh ephemeral scalar.
+func (c *Client) NewSession() (*ClientSession, error) {
+	x, err := c.suite.Group().RandomScalar(c.rand)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientSession{client: c, x: x, ws: newWorkspace(c.suite.Group())}, nil
+}
+
+// Reset overwrites the keys of the current handshake and starts a new one with a fresh ephemeral
+// scalar. Slices returned by AppendShare, Confirm and SharedSecret must not be used afterwards.
+func (cs *ClientSession) Reset() error {
+	cs.wipe()
+	x, err := cs.client.suite.Group().RandomScalar(cs.client.rand)
+	if err != nil {
+		cs.state = StateFailed
+		return err
+	}
+	cs.x = x
+	cs.state = StateStart
+	return nil
+}
+
+// State returns the current state of the session.
+func (cs *ClientSession) State() SessionState {
+	return cs.state
+}
+
+// Share returns A, to be sent to the server along with the client's identity.
+func (cs *ClientSession) Share() ([]byte, error) {
+	return cs.AppendShare(nil)
+}
+
+// AppendShare is Share appending A to dst.
+func (cs *ClientSession) AppendShare(dst []byte) ([]byte, error) {
+	if cs.state != StateStart {
+		return nil, errInvalidState
+	}
+
+	X, err := cs.client.shareElement(cs.ws, cs.x)
+	if err != nil {
+		cs.state = StateFailed
+		return nil, err
+	}
+
+	cs.ws.x = suite.AppendElement(cs.ws.x[:0], X)
+	cs.state = StateSentShare
+	return append(dst, cs.ws.x...), nil
+}
+
+// Confirm processes the server's B and confirmation cB, and returns the client's confirmation cA.
+func (cs *ClientSession) Confirm(m *ServerMaterial, incomingConfirmation []byte) ([]byte, error) {
+	if cs.state != StateSentShare {
+		return nil, errInvalidState
+	}
+
+	cs.ws.y = append(cs.ws.y[:0], m.B...)
+	secret, err := cs.client.complete(context.Background(), cs.ws, cs.x, cs.ws.x, cs.ws.y)
+	if err != nil {
+		cs.state = StateFailed
+		return nil, err
+	}
+
+	if err := secret.Verify(incomingConfirmation); err != nil {
+		secret.Destroy()
+		cs.state = StateFailed
+		return nil, err
+	}
+
+	cs.secret = secret
+	cs.state = StateDone
+	return secret.Confirmation(), nil
+}
+
+// SharedSecret returns Ke once the session is done.
+func (cs *ClientSession) SharedSecret() ([]byte, error) {
+	if cs.state != StateDone {
+		return nil, errInvalidState
+	}
+	return cs.secret.Bytes(), nil
+}
+
+// Destroy overwrites x and the session's keys, and moves the session to StateFailed.
+func (cs *ClientSession) Destroy() {
+	cs.wipe()
+	cs.state = StateFailed
+}
+
+// wipe overwrites x and the session's keys.
+func (cs *ClientSession) wipe() {
+	cs.x.Zero()
+	if cs.secret != nil {
+		cs.secret.Destroy()
+		cs.secret = nil
+	}
+}
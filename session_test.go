@@ -0,0 +1,255 @@
+package spake2plus
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestSessions(t *testing.T, testSuite Suite, password []byte) (*ClientSession, *ServerSession) {
+	db := NewMapLookup()
+	suite := testSuite(mhfScrypt)
+	clientIdentity := []byte("client")
+	serverIdentity := []byte("server")
+	salt := []byte("NaCl")
+
+	server, err := NewServer(suite, db, serverIdentity)
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+
+	client, err := NewClient(suite, clientIdentity, serverIdentity, []byte("password"), salt)
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	v, err := client.Verifier()
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	db.Add(clientIdentity, v)
+
+	if password != nil {
+		client, err = NewClient(suite, clientIdentity, serverIdentity, password, salt)
+		if !assert.NoError(t, err) {
+			return nil, nil
+		}
+	}
+
+	cs, err := client.NewSession()
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	ss, err := server.NewSession()
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	return cs, ss
+}
+
+func TestSessions(t *testing.T) {
+	for _, s := range testSuites {
+		cs, ss := newTestSessions(t, s, nil)
+		if cs == nil {
+			continue
+		}
+
+		A, err := cs.Share()
+		assert.NoError(t, err)
+		assert.Equal(t, StateSentShare, cs.State())
+
+		m, err := ss.Handshake([]byte("client"), A)
+		assert.NoError(t, err)
+		assert.Equal(t, StateSentShare, ss.State())
+
+		cB, err := ss.Confirmation()
+		assert.NoError(t, err)
+		assert.Equal(t, StateSentConfirm, ss.State())
+
+		cA, err := cs.Confirm(m, cB)
+		assert.NoError(t, err)
+		assert.Equal(t, StateDone, cs.State())
+
+		assert.NoError(t, ss.Verify(cA))
+		assert.Equal(t, StateDone, ss.State())
+
+		keyA, err := cs.SharedSecret()
+		assert.NoError(t, err)
+		keyB, err := ss.SharedSecret()
+		assert.NoError(t, err)
+		assert.Equal(t, keyA, keyB)
+
+		// single use
+		_, err = cs.Share()
+		assert.Error(t, err)
+		_, err = ss.Handshake([]byte("client"), A)
+		assert.Error(t, err)
+		assert.Error(t, ss.Verify(cA))
+		assert.Equal(t, StateDone, ss.State())
+	}
+}
+
+func TestSessionOutOfOrder(t *testing.T) {
+	cs, ss := newTestSessions(t, Ed25519Sha256HkdfHmac, nil)
+
+	_, err := cs.Confirm(&ServerMaterial{}, nil)
+	assert.Error(t, err)
+	_, err = cs.SharedSecret()
+	assert.Error(t, err)
+	assert.Equal(t, StateStart, cs.State())
+
+	_, err = ss.Confirmation()
+	assert.Error(t, err)
+	assert.Error(t, ss.Verify(nil))
+	_, err = ss.SharedSecret()
+	assert.Error(t, err)
+	assert.Equal(t, StateStart, ss.State())
+}
+
+func TestSessionFailedVerifyIsTerminal(t *testing.T) {
+	cs, ss := newTestSessions(t, P256Sha256HkdfHmac, []byte("a_wrong_password"))
+
+	A, err := cs.Share()
+	assert.NoError(t, err)
+	m, err := ss.Handshake([]byte("client"), A)
+	assert.NoError(t, err)
+	cB, err := ss.Confirmation()
+	assert.NoError(t, err)
+
+	// the client rejects the server's confirmation and never releases its own
+	_, err = cs.Confirm(m, cB)
+	assert.Error(t, err)
+	assert.Equal(t, StateFailed, cs.State())
+
+	assert.Error(t, ss.Verify([]byte("guess")))
+	assert.Equal(t, StateFailed, ss.State())
+	assert.Nil(t, ss.secret.keySecret, "the failed session's keys are destroyed")
+	assert.Error(t, ss.Verify([]byte("another guess")))
+	_, err = ss.SharedSecret()
+	assert.Error(t, err)
+}
+
+func TestSessionDestroy(t *testing.T) {
+	for _, s := range testSuites {
+		cs, ss := newTestSessions(t, s, nil)
+		if cs == nil {
+			continue
+		}
+
+		A, err := cs.Share()
+		assert.NoError(t, err)
+		m, err := ss.Handshake([]byte("client"), A)
+		assert.NoError(t, err)
+		cB, err := ss.Confirmation()
+		assert.NoError(t, err)
+		cA, err := cs.Confirm(m, cB)
+		assert.NoError(t, err)
+		assert.NoError(t, ss.Verify(cA))
+
+		key, err := cs.SharedSecret()
+		assert.NoError(t, err)
+		secret := cs.secret
+
+		cs.Destroy()
+		ss.Destroy()
+
+		assert.Equal(t, make([]byte, len(key)), key)
+		assert.Equal(t, make([]byte, len(cs.x.Bytes())), cs.x.Bytes())
+		assert.Equal(t, make([]byte, len(ss.y.Bytes())), ss.y.Bytes())
+		assert.Equal(t, StateFailed, cs.State())
+		_, err = cs.SharedSecret()
+		assert.Error(t, err)
+		assert.Nil(t, secret.Confirmation())
+		assert.Error(t, secret.Verify(cB))
+
+		client := cs.client
+		w0, w1 := client.verifierW0, client.verifierW1
+		client.Destroy()
+		assert.Equal(t, make([]byte, len(w0)), w0)
+		assert.Equal(t, make([]byte, len(w1)), w1)
+	}
+}
+
+func newResettableSessions(t *testing.T, testSuite Suite) (*ClientSession, *ServerSession) {
+	db := NewMapLookup()
+	server, err := NewServer(testSuite(mhfScrypt), db, []byte("server"), WithVerifierCache(1))
+	assert.NoError(t, err)
+	client, err := NewClient(testSuite(mhfScrypt), []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	assert.NoError(t, err)
+	v, err := client.Verifier()
+	assert.NoError(t, err)
+	db.Add([]byte("client"), v)
+
+	cs, err := client.NewSession()
+	assert.NoError(t, err)
+	ss, err := server.NewSession()
+	assert.NoError(t, err)
+	return cs, ss
+}
+
+// runResetHandshake runs a whole handshake through the Append variants, reusing A and B.
+func runResetHandshake(t *testing.T, cs *ClientSession, ss *ServerSession, A, B []byte) ([]byte, []byte) {
+	if !assert.NoError(t, cs.Reset()) || !assert.NoError(t, ss.Reset()) {
+		return A, B
+	}
+	A, err := cs.AppendShare(A[:0])
+	assert.NoError(t, err)
+	B, err = ss.AppendHandshake(B[:0], []byte("client"), A)
+	assert.NoError(t, err)
+	cB, err := ss.Confirmation()
+	assert.NoError(t, err)
+	cA, err := cs.Confirm(&ServerMaterial{B: B}, cB)
+	assert.NoError(t, err)
+	assert.NoError(t, ss.Verify(cA))
+	return A, B
+}
+
+func TestSessionReset(t *testing.T) {
+	for _, s := range testSuites {
+		cs, ss := newResettableSessions(t, s)
+
+		var A, B, prev []byte
+		for i := 0; i < 3; i++ {
+			A, B = runResetHandshake(t, cs, ss, A, B)
+			clientKey, err := cs.SharedSecret()
+			assert.NoError(t, err)
+			serverKey, err := ss.SharedSecret()
+			assert.NoError(t, err)
+			assert.Equal(t, clientKey, serverKey)
+			assert.NotEqual(t, prev, clientKey)
+			prev = append([]byte(nil), clientKey...)
+		}
+
+		// a failed handshake can be reset too
+		assert.NoError(t, cs.Reset())
+		assert.NoError(t, ss.Reset())
+		_, err := ss.AppendHandshake(nil, []byte("client"), []byte("garbage"))
+		assert.Error(t, err)
+		assert.Equal(t, StateFailed, ss.State())
+		A, B = runResetHandshake(t, cs, ss, A, B)
+		assert.Equal(t, StateDone, ss.State())
+
+		// and so can a destroyed one
+		ss.Destroy()
+		assert.Equal(t, StateFailed, ss.State())
+		assert.NoError(t, ss.Reset())
+		assert.Equal(t, StateStart, ss.State())
+	}
+}
+
+// raceEnabled is set by race_test.go; the race detector makes more values escape.
+var raceEnabled bool
+
+// A whole Ed25519 handshake through reset sessions has a small, fixed number of allocations:
+// mostly the fresh scalars, the digests and the SharedSecrets.
+func TestSessionAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations differ under the race detector")
+	}
+	cs, ss := newResettableSessions(t, Ed25519Sha256HkdfHmac)
+	var A, B []byte
+	A, B = runResetHandshake(t, cs, ss, A, B)
+
+	allocs := testing.AllocsPerRun(20, func() {
+		A, B = runResetHandshake(t, cs, ss, A, B)
+	})
+	assert.LessOrEqual(t, allocs, 22.0)
+}
@@ -1,23 +1,34 @@
 package spake2plus
 
 import (
-	"errors"
 	"github.com/jtejido/spake2plus/internal/suite"
 	"github.com/jtejido/spake2plus/internal/suite/ed25519"
 	"github.com/jtejido/spake2plus/internal/suite/ed448"
 	"github.com/jtejido/spake2plus/internal/suite/elliptic"
-	"golang.org/x/crypto/argon2"
-	"golang.org/x/crypto/scrypt"
 )
 
 func Ed25519Sha256HkdfHmac(mhf suite.MHF) suite.CipherSuite {
 	return ed25519.NewEd25519Sha256HkdfHmac(mhf)
 }
 
+// Edwards25519Sha256HkdfHmac is Ed25519Sha256HkdfHmac with Z and V multiplied by the cofactor
+// h = 8, as in RFC 9383, rather than by 2^251. The two don't interoperate.
+func Edwards25519Sha256HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return ed25519.NewEdwards25519Sha256HkdfHmac(mhf)
+}
+
 func Ed448Sha512HkdfHmac(mhf suite.MHF) suite.CipherSuite {
 	return ed448.NewEd448Sha512HkdfHmac(mhf)
 }
 
+func Ristretto255Sha512HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return ed25519.NewRistretto255Sha512HkdfHmac(mhf)
+}
+
+func Decaf448Sha512HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return ed448.NewDecaf448Sha512HkdfHmac(mhf)
+}
+
 func P256Sha256HkdfHmac(mhf suite.MHF) suite.CipherSuite {
 	return elliptic.NewP256Sha256HkdfHmac(mhf)
 }
@@ -38,6 +49,42 @@ func P521Sha512HkdfHmac(mhf suite.MHF) suite.CipherSuite {
 	return elliptic.NewP521Sha512HkdfHmac(mhf)
 }
 
+func P256Sha3HkdfKmac(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewP256Sha3HkdfKmac(mhf)
+}
+
+func Ed448Shake256Kmac(mhf suite.MHF) suite.CipherSuite {
+	return ed448.NewEd448Shake256Kmac(mhf)
+}
+
+func Ristretto255Blake2bHkdfBlake2b(mhf suite.MHF) suite.CipherSuite {
+	return ed25519.NewRistretto255Blake2bHkdfBlake2b(mhf)
+}
+
+func P256Sha256HkdfCmacAes128(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewP256Sha256HkdfCmacAes128(mhf)
+}
+
+func P521Sha512HkdfCmacAes256(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewP521Sha512HkdfCmacAes256(mhf)
+}
+
+func Secp256k1Sha256HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewSecp256k1Sha256HkdfHmac(mhf)
+}
+
+func BrainpoolP256r1Sha256HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewBrainpoolP256r1Sha256HkdfHmac(mhf)
+}
+
+func BrainpoolP384r1Sha512HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewBrainpoolP384r1Sha512HkdfHmac(mhf)
+}
+
+func BrainpoolP512r1Sha512HkdfHmac(mhf suite.MHF) suite.CipherSuite {
+	return elliptic.NewBrainpoolP512r1Sha512HkdfHmac(mhf)
+}
+
 // Confirmations provides a easy interface for confirmation verification, for state load.
 type Confirmations struct {
 	confirmation       []byte
@@ -58,20 +105,7 @@ func (c Confirmations) Bytes() []byte {
 // Verify verifies an incoming confirmation message.
 func (c Confirmations) Verify(incomingConfirmation []byte) error {
 	if !c.suite.MacEqual(incomingConfirmation, c.remoteConfirmation) {
-		return errors.New("Verification Failed")
+		return suite.NewError(suite.ConfirmationMismatch, "Verification Failed")
 	}
 	return nil
 }
-
-// MHFs
-func Scrypt(N, r, p int) suite.MHF {
-	return func(password, salt []byte, len int) ([]byte, error) {
-		return scrypt.Key(password, salt, N, r, p, len)
-	}
-}
-
-func Argon2(time, memory uint32, threads uint8) suite.MHF {
-	return func(password, salt []byte, len int) ([]byte, error) {
-		return argon2.Key(password, salt, time, memory, threads, uint32(len)), nil
-	}
-}
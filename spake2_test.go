@@ -16,8 +16,20 @@ var testSuites = []Suite{
 	P256Sha512HkdfHmac,
 	P384Sha512HkdfHmac,
 	P521Sha512HkdfHmac,
+	Secp256k1Sha256HkdfHmac,
+	BrainpoolP256r1Sha256HkdfHmac,
+	BrainpoolP384r1Sha512HkdfHmac,
+	BrainpoolP512r1Sha512HkdfHmac,
 	Ed25519Sha256HkdfHmac,
+	Edwards25519Sha256HkdfHmac,
 	Ed448Sha512HkdfHmac,
+	Ristretto255Sha512HkdfHmac,
+	Decaf448Sha512HkdfHmac,
+	P256Sha3HkdfKmac,
+	Ed448Shake256Kmac,
+	Ristretto255Blake2bHkdfBlake2b,
+	P256Sha256HkdfCmacAes128,
+	P521Sha512HkdfCmacAes256,
 }
 
 func testSPAKE2PlusScrypt(t *testing.T, testSuite Suite, mhf suite.MHF) {
@@ -331,3 +343,30 @@ func BenchmarkSPAKE2PlusP384Sha512Scrypt(b *testing.B) {
 func BenchmarkSPAKE2PlusP521Sha512Scrypt(b *testing.B) {
 	benchSPAKE2PlusScrypt(b, P521Sha512HkdfHmac, mhfScrypt)
 }
+
+func TestSuiteIDsAreDistinct(t *testing.T) {
+	seen := map[string]bool{}
+	for _, s := range testSuites {
+		id := s(mhfScrypt).ID()
+		assert.False(t, seen[id], id)
+		seen[id] = true
+	}
+}
+
+func TestMultiScalarMult(t *testing.T) {
+	for _, s := range testSuites {
+		g := s(mhfScrypt).Group()
+		a, _ := g.RandomScalar(nil)
+		b, _ := g.RandomScalar(nil)
+		c, _ := g.RandomScalar(nil)
+		P, _ := g.RandomElement(nil)
+
+		got := g.MultiScalarMult([]suite.Scalar{a, b, c}, []suite.Element{nil, g.M(), P})
+		want := g.Element().ScalarMult(a, nil)
+		want.Add(want, g.Element().ScalarMult(b, g.M()))
+		want.Add(want, g.Element().ScalarMult(c, P))
+		assert.Equal(t, want.Bytes(), got.Bytes(), g.String())
+
+		assert.Equal(t, g.Element().Identity().Bytes(), g.MultiScalarMult(nil, nil).Bytes(), g.String())
+	}
+}
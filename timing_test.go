@@ -0,0 +1,295 @@
+//go:build dudect
+
+package spake2plus
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"github.com/jtejido/spake2plus/internal/suite"
+	"math"
+	mrand "math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Timing leak tests after dudect (Reparaz, Balasch and Verbauwhede, "Dude, is my code constant
+// time?", 2017). Each experiment times an operation on inputs of two classes, one fixed secret and
+// random secrets, interleaved at random, and compares the two distributions with Welch's t-test:
+// on all the measurements, and on those below a series of percentiles, which crops the long tail
+// that interrupts and the scheduler add. A |t| above 4.5 is evidence of a leak. One below it means
+// no leak showed at this number of measurements, which is not a proof that there is none.
+//
+// The tests only build with -tags dudect, take minutes, and want a quiet machine:
+//
+//	go test -tags dudect -run Timing -v -timeout 0 . [-dudect.n 100000] [-dudect.report timing.txt] [-dudect.accept-known]
+
+var (
+	dudectN           = flag.Int("dudect.n", 10000, "measurements per timing experiment")
+	dudectReport      = flag.String("dudect.report", "", "file to append the timing report to")
+	dudectAcceptKnown = flag.Bool("dudect.accept-known", false, "report the leaks of knownLeaks without failing")
+)
+
+const (
+	dudectThreshold = 4.5
+	dudectCrops     = 20
+)
+
+// knownLeaks lists the experiments that leak in a dependency, with the reason. They fail like any
+// other leak, unless -dudect.accept-known is set.
+var knownLeaks = map[string]string{
+	"Ed448 ScalarMult(k, B)":    "circl's goldilocks ScalarBaseMult recodes k with math/big (mlsbset.Encode)",
+	"decaf448 ScalarMult(k, B)": "circl's goldilocks ScalarBaseMult recodes k with math/big (mlsbset.Encode)",
+}
+
+// welch accumulates the mean and variance of both classes online, with Welford's method.
+type welch struct {
+	n, mean, m2 [2]float64
+}
+
+func (w *welch) push(class int, x float64) {
+	w.n[class]++
+	d := x - w.mean[class]
+	w.mean[class] += d / w.n[class]
+	w.m2[class] += d * (x - w.mean[class])
+}
+
+func (w *welch) t() float64 {
+	if w.n[0] < 2 || w.n[1] < 2 {
+		return 0
+	}
+	v0, v1 := w.m2[0]/(w.n[0]-1), w.m2[1]/(w.n[1]-1)
+	den := math.Sqrt(v0/w.n[0] + v1/w.n[1])
+	if den == 0 {
+		return 0
+	}
+	return (w.mean[0] - w.mean[1]) / den
+}
+
+// timingResult is the outcome of one experiment: the mean time of each class, and the t of the
+// crop where |t| is largest.
+type timingResult struct {
+	name          string
+	n             int
+	fixed, random time.Duration
+	t             float64
+	crop          float64 // the percentile the crop kept, or 1 for all the measurements
+}
+
+// timingClasses assigns each of n inputs to the fixed class or not, at random.
+func timingClasses(n int) []bool {
+	r := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+	fixed := make([]bool, n)
+	for i := range fixed {
+		fixed[i] = r.Intn(2) == 0
+	}
+	return fixed
+}
+
+// measureTiming times op(i) for every input i, where fixed[i] gives its class. op must have its
+// input ready, so that only the operation is timed, and inputs of the fixed class should be copies
+// rather than one value, lest the cache tell them apart.
+func measureTiming(name string, fixed []bool, op func(i int)) timingResult {
+	n := len(fixed)
+	for i := 0; i < n/10+1; i++ {
+		op(i % n)
+	}
+	times := make([]float64, n)
+	for i := range times {
+		start := time.Now()
+		op(i)
+		times[i] = float64(time.Since(start))
+	}
+
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+	crops := []float64{1}
+	for k := 0; k < dudectCrops; k++ {
+		crops = append(crops, 1-math.Pow(0.5, 10*float64(k+1)/dudectCrops))
+	}
+	tests := make([]welch, len(crops))
+	for i, x := range times {
+		class := 1
+		if fixed[i] {
+			class = 0
+		}
+		for j, p := range crops {
+			if x <= sorted[int(p*float64(n-1))] {
+				tests[j].push(class, x)
+			}
+		}
+	}
+
+	r := timingResult{
+		name:   name,
+		n:      n,
+		fixed:  time.Duration(tests[0].mean[0]),
+		random: time.Duration(tests[0].mean[1]),
+	}
+	for j := range tests {
+		if t := tests[j].t(); math.Abs(t) >= math.Abs(r.t) {
+			r.t, r.crop = t, crops[j]
+		}
+	}
+	return r
+}
+
+// reportTiming logs r, appends it to the report, and fails t on a leak, unless it's a known one and
+// -dudect.accept-known is set.
+func reportTiming(t *testing.T, r timingResult) {
+	verdict := "no leak found"
+	if math.Abs(r.t) > dudectThreshold {
+		verdict = "LEAK"
+		if reason, ok := knownLeaks[r.name]; ok {
+			verdict = "LEAK (known: " + reason + ")"
+			if *dudectAcceptKnown {
+				verdict = "leak (known, accepted: " + reason + ")"
+			}
+		}
+	}
+	line := fmt.Sprintf("%-50s n=%-7d fixed=%-12v random=%-12v t=%8.2f crop=%.3f  %s", r.name, r.n, r.fixed, r.random, r.t, r.crop, verdict)
+	t.Log(line)
+	if *dudectReport != "" {
+		f, err := os.OpenFile(*dudectReport, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		fmt.Fprintln(f, line)
+	}
+	if strings.HasPrefix(verdict, "LEAK") {
+		t.Errorf("%s: |t| = %.2f > %v", r.name, math.Abs(r.t), dudectThreshold)
+	}
+}
+
+// timingGroups returns one suite per group of testSuites.
+func timingGroups() []suite.CipherSuite {
+	var suites []suite.CipherSuite
+	seen := make(map[string]bool)
+	for _, s := range testSuites {
+		cs := s(mhfScrypt)
+		if name := cs.Group().String(); !seen[name] {
+			seen[name] = true
+			suites = append(suites, cs)
+		}
+	}
+	return suites
+}
+
+// newTimingServer returns a server without a verifier cache, with "client" registered, and an A
+// from that client.
+func newTimingServer(t *testing.T, cs suite.CipherSuite) (*Server, []byte) {
+	db := NewMapLookup()
+	server, err := NewServer(cs, db, []byte("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(cs, []byte("client"), []byte("server"), []byte("password"), []byte("NaCl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := client.Verifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Add([]byte("client"), v)
+	A, err := client.EphemeralPublic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, A
+}
+
+// TestTimingScalarMult compares a fixed scalar, one, with random scalars, as the base and as the
+// multiplier of M. There's a subtest per group, e.g. -run TimingScalarMult/Ed25519.
+func TestTimingScalarMult(t *testing.T) {
+	for _, cs := range timingGroups() {
+		g := cs.Group()
+		t.Run(g.String(), func(t *testing.T) {
+			one := make([]byte, g.ScalarLen())
+			one[len(one)-1] = 1
+
+			fixed := timingClasses(*dudectN)
+			scalars := make([]suite.Scalar, len(fixed))
+			for i := range scalars {
+				var err error
+				if fixed[i] {
+					scalars[i] = g.Scalar()
+					err = scalars[i].FromBytes(one)
+				} else {
+					scalars[i], err = g.RandomScalar(rand.Reader)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			dst, M := g.Element(), g.M()
+			reportTiming(t, measureTiming(g.String()+" ScalarMult(k, B)", fixed, func(i int) {
+				dst.ScalarMult(scalars[i], nil)
+			}))
+			reportTiming(t, measureTiming(g.String()+" ScalarMult(k, M)", fixed, func(i int) {
+				dst.ScalarMult(scalars[i], M)
+			}))
+		})
+	}
+}
+
+// TestTimingUserMiss compares the handshake of a registered identity with those of unknown ones,
+// without a verifier cache. With one, the time tells whether an identity was tried recently, and
+// nothing more.
+func TestTimingUserMiss(t *testing.T) {
+	for _, cs := range timingGroups() {
+		name := cs.Group().String()
+		t.Run(name, func(t *testing.T) {
+			server, A := newTimingServer(t, cs)
+			fixed := timingClasses(*dudectN)
+			identities := make([][]byte, len(fixed))
+			for i := range identities {
+				identities[i] = []byte("client") // a copy each, like the random ones
+				if !fixed[i] {
+					identities[i] = make([]byte, 6)
+					rand.Read(identities[i])
+				}
+			}
+			reportTiming(t, measureTiming(name+" Handshake, registered vs unknown", fixed, func(i int) {
+				server.Handshake(identities[i], A)
+			}))
+		})
+	}
+}
+
+// TestTimingVerify compares a confirmation that only differs from the right one in its last byte
+// with random ones.
+func TestTimingVerify(t *testing.T) {
+	for _, s := range testSuites {
+		cs := s(mhfScrypt)
+		t.Run(cs.ID(), func(t *testing.T) {
+			server, A := newTimingServer(t, cs)
+			_, secret, err := server.Handshake([]byte("client"), A)
+			if err != nil {
+				t.Fatal(err)
+			}
+			secret.generateConfirmations()
+			want := secret.remoteConfirmation
+			almost := append([]byte(nil), want...)
+			almost[len(almost)-1] ^= 1
+
+			fixed := timingClasses(*dudectN)
+			confirmations := make([][]byte, len(fixed))
+			for i := range confirmations {
+				confirmations[i] = append([]byte(nil), almost...)
+				if !fixed[i] {
+					confirmations[i] = make([]byte, len(want))
+					rand.Read(confirmations[i])
+				}
+			}
+			reportTiming(t, measureTiming(cs.ID()+" Verify", fixed, func(i int) {
+				secret.Verify(confirmations[i])
+			}))
+		})
+	}
+}
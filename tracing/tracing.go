@@ -0,0 +1,63 @@
+// Package tracing turns the events of a spake2plus Client or Server into OpenTelemetry spans.
+package tracing
+
+import (
+	"context"
+	"github.com/jtejido/spake2plus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Span and attribute names.
+const (
+	HandshakeSpan    = "spake2plus.handshake"
+	ConfirmationSpan = "spake2plus.confirmation"
+
+	SideKey   = attribute.Key("spake2plus.side")
+	SuiteKey  = attribute.Key("spake2plus.suite")
+	ReasonKey = attribute.Key("spake2plus.reason")
+)
+
+// Observer records a HandshakeSpan for every handshake, covering the processing of the peer's
+// share, and a ConfirmationSpan for every confirmation, from the start of its handshake to the
+// Verify. Spans carry the side, the suite and the reason of a failure, never an identity, and not
+// whether a server found the user. A span is a child of the span in its event's Context, so a
+// handshake run with e.g. Server.HandshakeContext joins the trace of its request.
+type Observer struct {
+	spake2plus.NopObserver
+	tracer trace.Tracer
+}
+
+func NewObserver(tracer trace.Tracer) *Observer {
+	return &Observer{tracer: tracer}
+}
+
+func (o *Observer) HandshakeFinished(e spake2plus.HandshakeEvent) {
+	o.record(e.Context, HandshakeSpan, e.Side, e.Suite, e.Start, e.Start.Add(e.Duration), e.Failed, e.Reason)
+}
+
+func (o *Observer) Confirmed(e spake2plus.ConfirmationEvent) {
+	o.record(e.Context, ConfirmationSpan, e.Side, e.Suite, e.Start, e.Start.Add(e.Elapsed), e.Failed, e.Reason)
+}
+
+func (o *Observer) record(ctx context.Context, name string, side spake2plus.Side, suite string, start, end time.Time, failed bool, reason spake2plus.Reason) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	kind := trace.SpanKindClient
+	if side == spake2plus.ServerSide {
+		kind = trace.SpanKindServer
+	}
+	_, span := o.tracer.Start(ctx, name,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(SideKey.String(side.String()), SuiteKey.String(suite)),
+	)
+	if failed {
+		span.SetAttributes(ReasonKey.String(reason.String()))
+		span.SetStatus(codes.Error, reason.String())
+	}
+	span.End(trace.WithTimestamp(end))
+}
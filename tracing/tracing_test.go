@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"github.com/jtejido/spake2plus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestObserver(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	o := NewObserver(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test"))
+
+	cs := spake2plus.Ed25519Sha256HkdfHmac(spake2plus.Scrypt(16, 1, 1))
+	server, err := spake2plus.NewServer(cs, spake2plus.NewMapLookup(), []byte("server"), spake2plus.WithObserver(o))
+	assert.NoError(t, err)
+	client, err := spake2plus.NewClient(cs, []byte("mallory"), []byte("server"), []byte("guess"), []byte("NaCl"))
+	assert.NoError(t, err)
+	A, err := client.EphemeralPublic()
+	assert.NoError(t, err)
+	_, sB, err := server.Handshake([]byte("mallory"), A)
+	assert.NoError(t, err)
+	assert.Error(t, sB.Verify(make([]byte, 32)))
+
+	ended := spans.Ended()
+	if !assert.Len(t, ended, 2) {
+		return
+	}
+	h, c := ended[0], ended[1]
+	assert.Equal(t, HandshakeSpan, h.Name())
+	assert.Equal(t, trace.SpanKindServer, h.SpanKind())
+	assert.Equal(t, codes.Unset, h.Status().Code)
+	assert.ElementsMatch(t, h.Attributes(), []attribute.KeyValue{SideKey.String("server"), SuiteKey.String(cs.ID())})
+	assert.True(t, h.EndTime().After(h.StartTime()))
+
+	assert.Equal(t, ConfirmationSpan, c.Name())
+	assert.Equal(t, codes.Error, c.Status().Code)
+	assert.Contains(t, c.Attributes(), ReasonKey.String("confirmation mismatch"))
+	assert.Equal(t, h.StartTime(), c.StartTime())
+	for _, kv := range append(h.Attributes(), c.Attributes()...) {
+		assert.NotContains(t, kv.Value.Emit(), "mallory")
+	}
+}
+
+func TestObserverContext(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test")
+	o := NewObserver(tracer)
+
+	cs := spake2plus.Ed25519Sha256HkdfHmac(spake2plus.Scrypt(16, 1, 1))
+	server, err := spake2plus.NewServer(cs, spake2plus.NewMapLookup(), []byte("server"), spake2plus.WithObserver(o))
+	assert.NoError(t, err)
+	client, err := spake2plus.NewClient(cs, []byte("mallory"), []byte("server"), []byte("guess"), []byte("NaCl"), spake2plus.WithObserver(o))
+	assert.NoError(t, err)
+
+	serverCtx, request := tracer.Start(context.Background(), "request")
+	clientCtx, call := tracer.Start(context.Background(), "call")
+	A, err := client.EphemeralPublic()
+	assert.NoError(t, err)
+	m, sB, err := server.HandshakeFromContext(serverCtx, []byte("10.0.0.1"), []byte("mallory"), A)
+	assert.NoError(t, err)
+	sA, err := client.CompleteHandshakeContext(clientCtx, m)
+	assert.NoError(t, err)
+	assert.Error(t, sA.Verify(sB.Confirmation()))
+	confirmCtx, confirm := tracer.Start(context.Background(), "confirm")
+	assert.Error(t, sB.VerifyContext(confirmCtx, make([]byte, 32)))
+	request.End()
+	call.End()
+	confirm.End()
+
+	parents := map[string][]trace.SpanID{}
+	for _, s := range spans.Ended() {
+		if s.Name() == HandshakeSpan || s.Name() == ConfirmationSpan {
+			parents[s.Name()] = append(parents[s.Name()], s.Parent().SpanID())
+		}
+	}
+	// the server's handshake, the client's handshake and confirmation, then the server's confirmation
+	assert.Equal(t, []trace.SpanID{request.SpanContext().SpanID(), call.SpanContext().SpanID()}, parents[HandshakeSpan])
+	assert.Equal(t, []trace.SpanID{call.SpanContext().SpanID(), confirm.SpanContext().SpanID()}, parents[ConfirmationSpan])
+}
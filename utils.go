@@ -1,41 +1,53 @@
 package spake2plus
 
 import (
-	"bytes"
+	"context"
 	"encoding/binary"
 	"github.com/jtejido/spake2plus/internal/suite"
 )
 
 func concat(bytesArray ...[]byte) []byte {
-	result := []byte{}
+	n := 0
+	for _, bytes := range bytesArray {
+		n += 8 + len(bytes)
+	}
+	result := make([]byte, 0, n)
 	for _, bytes := range bytesArray {
 		if len(bytes) > 0 {
-			bytesLen := make([]byte, 8)
-			binary.LittleEndian.PutUint64(bytesLen, uint64(len(bytes)))
-			result = append(result, bytesLen...)
-			result = append(result, bytes...)
+			result = appendLenAndContent(result, bytes)
 		}
 	}
 	return result
 }
 
+// wipe overwrites b with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// padScalarBytes left-pads scBytes with zeros to padLen. It returns scBytes itself when there's
+// nothing to pad, so the result must not be modified.
 func padScalarBytes(scBytes []byte, padLen int) []byte {
-	if len(scBytes) > padLen {
+	if len(scBytes) >= padLen {
 		return scBytes
 	}
-	return append(bytes.Repeat([]byte{0}, padLen-len(scBytes)), scBytes...)
+	padded := make([]byte, padLen)
+	copy(padded[padLen-len(scBytes):], scBytes)
+	return padded
 }
 
-func appendLenAndContent(b *bytes.Buffer, input []byte) {
-	binary.Write(b, binary.LittleEndian, uint64(len(input)))
-	b.Write(input)
+func appendLenAndContent(dst, input []byte) []byte {
+	dst = binary.LittleEndian.AppendUint64(dst, uint64(len(input)))
+	return append(dst, input...)
 }
 
-func computeW0W1(s suite.CipherSuite, clientIdentity, serverIdentity, password, salt []byte) ([]byte, []byte, error) {
-	wBytes, err := s.Mhf(
-		concat(password, clientIdentity, serverIdentity),
-		salt,
-	)
+func computeW0W1(ctx context.Context, s suite.CipherSuite, clientIdentity, serverIdentity, password, salt []byte) ([]byte, []byte, error) {
+	input := concat(password, clientIdentity, serverIdentity)
+	defer wipe(input)
+
+	wBytes, err := s.MhfContext(ctx, input, salt)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -44,9 +56,10 @@ func computeW0W1(s suite.CipherSuite, clientIdentity, serverIdentity, password,
 	return w0, w1, nil
 }
 
+var confirmationKeysInfo = []byte("ConfirmationKeys")
+
 func confirmationMACs(ka []byte, s suite.CipherSuite) ([]byte, []byte) {
-	info := []byte("ConfirmationKeys")
-	Kc := s.DeriveKey(nil, ka, info)
+	Kc := s.DeriveKey(nil, ka, confirmationKeysInfo)
 	keyLength := len(Kc)
 	return Kc[:keyLength/2], Kc[keyLength/2:]
 }
@@ -81,37 +94,124 @@ func confirmationMACs(ka []byte, s suite.CipherSuite) ([]byte, []byte) {
 //    A and B output Ke as the shared secret from the protocol.  Ka and its
 //    derived keys (KcA and KcB) are not used for anything except key
 //    confirmation.
-func generateSharedSecrets(s suite.CipherSuite, idA, idB, X, Y, Z, V, w0 []byte) (Ke, Ka, kcA, kcB []byte) {
-	// TT = len(Context) || Context ||     // we dont have this yet, ideally we would have all the required stuff in here, suite ID, h2c ID, etc
+func generateSharedSecrets(s suite.CipherSuite, transcript []byte) (Ke, Ka, kcA, kcB []byte) {
+	transcriptHash := s.HashDigest(transcript)
+	// the transcript carries w0, Z and V
+	wipe(transcript)
+	blockSize := len(transcriptHash)
+
+	Ke, Ka = transcriptHash[:blockSize/2], transcriptHash[blockSize/2:]
+	kcA, kcB = confirmationMACs(Ka, s)
+	return
+}
+
+// appendTranscript appends TT to dst, growing it at most once.
+func appendTranscript(dst []byte, s suite.CipherSuite, idA, idB, X, Y, Z, V, w0 []byte) []byte {
+	// TT = len(Context) || Context      // only for suites with a context, see CipherSuite.Context
 	//   || len(A) || A || len(B) || B
 	//   || len(M) || M || len(N) || N
 	//   || len(X) || X || len(Y) || Y
 	//   || len(Z) || Z || len(V) || V
 	//   || len(w0) || w0
-	transcript := new(bytes.Buffer)
-	if len(idA) != 0 {
-		appendLenAndContent(transcript, idA)
+	M, N := s.EncodedMN()
+	fields := [...][]byte{s.Context(), idA, idB, M, N, X, Y, Z, V, w0}
+
+	n := 0
+	for _, f := range fields {
+		n += 8 + len(f)
 	}
-	if len(idB) != 0 {
-		appendLenAndContent(transcript, idB)
+	if cap(dst)-len(dst) < n {
+		dst = append(make([]byte, 0, len(dst)+n), dst...)
 	}
-	appendLenAndContent(transcript, s.Group().M().Bytes())
-	appendLenAndContent(transcript, s.Group().N().Bytes())
-	appendLenAndContent(transcript, X)
-	appendLenAndContent(transcript, Y)
-	appendLenAndContent(transcript, Z)
-	appendLenAndContent(transcript, V)
-	appendLenAndContent(transcript, w0)
-
-	transcriptBytes := transcript.Bytes()
-	transcriptHash := s.HashDigest(transcriptBytes)
-	blockSize := len(transcriptHash)
 
-	Ke, Ka = transcriptHash[:blockSize/2], transcriptHash[blockSize/2:]
-	kcA, kcB = confirmationMACs(Ka, s)
-	return
+	for i, f := range fields {
+		// the context and the identities are left out when empty
+		if i < 3 && len(f) == 0 {
+			continue
+		}
+		dst = appendLenAndContent(dst, f)
+	}
+	return dst
 }
 
-func isElementSmall(s suite.CipherSuite, elem suite.Element) bool {
-	return s.Group().ClearCofactor(elem).Equal(s.Group().Element().Identity())
+// workspace holds the elements and buffers of one handshake, so that sessions can reuse them
+// from one handshake to the next. Z and V end up in whichever scratch elements the group's
+// ClearCofactorTo picks.
+type workspace struct {
+	g                                suite.Group
+	incoming, tmp, scratch, identity suite.Element
+	rawZ, rawV, Z, V                 suite.Element
+	w0, w1                           suite.Scalar // only used by the client, see scalars
+	x, y, z, v, transcript, pad      []byte
+}
+
+func newWorkspace(g suite.Group) *workspace {
+	return &workspace{
+		g:        g,
+		incoming: g.Element(),
+		tmp:      g.Element(),
+		scratch:  g.Element(),
+		identity: g.Element().Identity(),
+		rawZ:     g.Element(),
+		rawV:     g.Element(),
+	}
+}
+
+// scalars returns the client's w0 and w1 scalars, allocated on first use.
+func (ws *workspace) scalars() (w0, w1 suite.Scalar) {
+	if ws.w0 == nil {
+		ws.w0, ws.w1 = ws.g.Scalar(), ws.g.Scalar()
+	}
+	return ws.w0, ws.w1
+}
+
+// setScalar sets sc from b, padded as padScalarBytes would but in the workspace's buffer.
+func (ws *workspace) setScalar(sc suite.Scalar, b []byte) error {
+	n := ws.g.ScalarLen()
+	if len(b) >= n {
+		return sc.FromBytes(b)
+	}
+	if cap(ws.pad) < n {
+		ws.pad = make([]byte, n)
+	}
+	ws.pad = ws.pad[:n]
+	defer wipe(ws.pad)
+	wipe(ws.pad[:n-len(b)])
+	copy(ws.pad[n-len(b):], b)
+	return sc.FromBytes(ws.pad)
+}
+
+// clearCofactors sets Z and V from rawZ and rawV, overwriting tmp and scratch.
+func (ws *workspace) clearCofactors() {
+	ws.Z = suite.ClearCofactorTo(ws.g, ws.tmp, ws.rawZ)
+	ws.V = suite.ClearCofactorTo(ws.g, ws.scratch, ws.rawV)
+}
+
+// encodeZV encodes Z and V into the workspace's buffers.
+func (ws *workspace) encodeZV() ([]byte, []byte) {
+	ws.z = suite.AppendElement(ws.z[:0], ws.Z)
+	ws.v = suite.AppendElement(ws.v[:0], ws.V)
+	return ws.z, ws.v
+}
+
+// sharedSecret derives the keys from the transcript, and wipes the encoded Z and V along with it.
+// The SharedSecret keeps X and Y.
+func (ws *workspace) sharedSecret(s suite.CipherSuite, idA, idB, X, Y, Z, V, w0 []byte, client bool) *SharedSecret {
+	ws.transcript = appendTranscript(ws.transcript[:0], s, idA, idB, X, Y, Z, V, w0)
+	wipe(Z)
+	wipe(V)
+
+	Ke, Ka, kcA, kcB := generateSharedSecrets(s, ws.transcript)
+	if client {
+		return newSharedSecret(Ke, Ka, X, Y, kcA, kcB, s)
+	}
+	return newSharedSecret(Ke, Ka, Y, X, kcB, kcA, s)
+}
+
+// isElementSmall reports whether elem is of small order. It may overwrite scratch.
+func (ws *workspace) isElementSmall(elem suite.Element) bool {
+	if c, ok := ws.g.(suite.SmallOrderChecker); ok {
+		return c.IsSmallOrder(elem)
+	}
+	return suite.ClearCofactorTo(ws.g, ws.scratch, elem).Equal(ws.identity)
 }
@@ -9,6 +9,8 @@ type Verifier struct {
 	// identity with a verifier. To be stored on a non-volatile DB.
 	I        []byte
 	Verifier VerifierPair
+	// descriptor of the MHF that w0 and w1 came from, see ParseMHF.
+	MHF string
 }
 
 type VerifierPair struct {
@@ -20,7 +22,8 @@ type VerifierPair struct {
 // the Verifier against the Identity in non-volatile storage.
 // An SRP client will supply Identity and its public key - whereupon,
 // an SRP server will use the Identity as a key to lookup
-// the rest of the encoded verifier data.
+// the rest of the encoded verifier data. The MHF descriptor, if any, follows
+// after another ':'.
 func (v *Verifier) Encode() (string, string) {
 	var b bytes.Buffer
 
@@ -29,6 +32,10 @@ func (v *Verifier) Encode() (string, string) {
 	b.WriteByte(':')
 	b.WriteString(hex.EncodeToString(v.Verifier.V1))
 	b.WriteString(hex.EncodeToString(v.Verifier.V2))
+	if v.MHF != "" {
+		b.WriteByte(':')
+		b.WriteString(v.MHF)
+	}
 	return ih, b.String()
 }
 